@@ -7,10 +7,12 @@ import (
 	"time"
 
 	"poule/configuration"
+	"poule/gh"
 	"poule/operations"
 	"poule/operations/catalog"
 	"poule/operations/settings"
 	"poule/runner"
+	"poule/runner/state"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/ehazlett/simplelog"
@@ -22,6 +24,7 @@ import (
 var batchCommand = cli.Command{
 	Name:   "batch",
 	Usage:  "Run groups of commands described in files",
+	Flags:  reportFlags,
 	Action: doBatchCommand,
 	Before: func(c *cli.Context) error {
 		// set a simpler logrus formatter for better cli experience
@@ -31,15 +34,34 @@ var batchCommand = cli.Command{
 }
 
 func doBatchCommand(c *cli.Context) {
+	output, err := reportOutputFromFlags(c)
+	if err != nil {
+		fmt.Printf("FATAL: %v\n", err)
+		os.Exit(1)
+	}
+
+	// A single report covers all batch files.
+	runReport := output.makeReport()
 	for _, arg := range c.Args() {
-		if err := executeBatchFile(c, arg); err != nil {
+		err := executeBatchFile(c, arg, func(r *runner.OperationRunner, _ operationConfiguration) error {
+			r.Report = runReport
+			return r.HandleStock()
+		})
+		if err != nil {
 			fmt.Printf("FATAL: Executing batch file %q: %v\n", arg, err)
+			output.write(runReport)
 			os.Exit(1)
 		}
 	}
+	if err := output.write(runReport); err != nil {
+		fmt.Printf("FATAL: Writing report: %v\n", err)
+		os.Exit(1)
+	}
 }
 
-func executeBatchFile(c *cli.Context, file string) error {
+// executeBatchFile creates a runner for each operation described in the batch file, and passes it to
+// the run function. Errors returned by the run function are logged but don't interrupt the batch.
+func executeBatchFile(c *cli.Context, file string, run func(*runner.OperationRunner, operationConfiguration) error) error {
 	b, err := ioutil.ReadFile(file)
 	if err != nil {
 		return err
@@ -58,29 +80,50 @@ func executeBatchFile(c *cli.Context, file string) error {
 	// specialized flags defined in the configuration file.
 	config := configuration.FromGlobalFlags(c)
 	batchConfig.applyConfig(config)
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	if err := gh.ResolveCredentials(config); err != nil {
+		return err
+	}
 
-	// Execute each command described as part of the YAML file.
+	// Verify all operations before processing any repository.
 	for _, operationConfig := range batchConfig.Operations {
-		logrus.Debugf("processing operation: %s", operationConfig.Type)
-		descriptor, ok := catalog.ByNameIndex[operationConfig.Type]
-		if !ok {
+		if _, ok := catalog.ByNameIndex[operationConfig.Type]; !ok {
 			return errors.Errorf("unknown operation %q in file %q", operationConfig.Type, file)
 		}
-		itemFilters, err := settings.ParseConfigurationFilters(operationConfig.Filters)
-		if err != nil {
-			return err
-		}
-		op, err := descriptor.OperationFromConfig(operationConfig.Settings)
-		if err != nil {
-			return err
-		}
-		opRunner := runner.NewOperationRunner(config, op)
-		opRunner.GlobalFilters = itemFilters
-		if err := opRunner.HandleStock(); err != nil {
-			logrus.Error(err)
-		}
 	}
-	return nil
+
+	// Open the state store shared by all operations of the batch.
+	store, err := state.Open(config.StateFile)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	// Execute each command described as part of the YAML file on each repository.
+	return forEachRepository(config, func(config *configuration.Config) error {
+		for _, operationConfig := range batchConfig.Operations {
+			logrus.Debugf("processing operation: %s", operationConfig.Type)
+			descriptor := catalog.ByNameIndex[operationConfig.Type]
+			itemFilters, err := settings.ParseConfigurationFilters(operationConfig.Filters)
+			if err != nil {
+				return err
+			}
+			op, err := descriptor.OperationFromConfig(operationConfig.Settings)
+			if err != nil {
+				return err
+			}
+			opRunner := runner.NewOperationRunner(config, op)
+			opRunner.GlobalFilters = itemFilters
+			opRunner.OperationName = operationConfig.Type
+			opRunner.State = store
+			if err := run(opRunner, operationConfig); err != nil {
+				logrus.Error(err)
+			}
+		}
+		return nil
+	})
 }
 
 // we need a special type to allow yaml to decode from a duration string
@@ -102,20 +145,44 @@ type batchConfiguration struct {
 
 type operationConfiguration struct {
 	Type     string                   `yaml:"type"`
-	Filters  map[string]interface{}   `yaml:"filters"`
+	Filters  interface{}              `yaml:"filters"`
 	Settings operations.Configuration `yaml:"settings"`
 }
 
 func (b *batchConfiguration) applyConfig(c *configuration.Config) {
+	if c.AppID == 0 {
+		c.AppID = b.AppID
+	}
+	if c.AppInstallationID == 0 {
+		c.AppInstallationID = b.AppInstallationID
+	}
+	if c.AppPrivateKeyFile == "" {
+		c.AppPrivateKeyFile = b.AppPrivateKeyFile
+	}
+	if c.BaseURL == "" {
+		c.BaseURL = b.BaseURL
+	}
+	if c.UploadURL == "" {
+		c.UploadURL = b.UploadURL
+	}
+	if c.Concurrency == 0 {
+		c.Concurrency = b.Concurrency
+	}
 	if c.RunDelay == 0 {
 		c.RunDelay = b.RunDelay
 	}
+	if !c.DisableSearch && b.DisableSearch {
+		c.DisableSearch = b.DisableSearch
+	}
 	if !c.DryRun && b.DryRun {
 		c.DryRun = b.DryRun
 	}
 	if c.Repository == "" {
 		c.Repository = b.Repository
 	}
+	if c.StateFile == "" {
+		c.StateFile = b.StateFile
+	}
 	if c.Token == "" {
 		c.Token = b.Token
 	}
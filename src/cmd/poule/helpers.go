@@ -2,28 +2,86 @@ package main
 
 import (
 	"poule/configuration"
+	"poule/gh"
 	"poule/operations/catalog"
 	"poule/operations/settings"
 	"poule/runner"
+	"poule/runner/state"
 
+	"github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
 	"github.com/urfave/cli"
 )
 
 func executeSingleOperation(c *cli.Context, descriptor catalog.OperationDescriptor) error {
+	output, err := reportOutputFromFlags(c)
+	if err != nil {
+		return err
+	}
+
+	// A single report covers all repositories.
+	runReport := output.makeReport()
+	err = runSingleOperation(c, descriptor, func(r *runner.OperationRunner) error {
+		r.Report = runReport
+		return r.HandleStock()
+	})
+	if reportErr := output.write(runReport); reportErr != nil && err == nil {
+		err = reportErr
+	}
+	return err
+}
+
+// runSingleOperation creates a runner for the operation described on the command line for each of
+// the configured repositories, and passes it to the run function. Errors are aggregated across
+// repositories.
+func runSingleOperation(c *cli.Context, descriptor catalog.OperationDescriptor, run func(*runner.OperationRunner) error) error {
 	config := configuration.FromGlobalFlags(c)
 	if err := config.Validate(); err != nil {
 		return err
 	}
+	if err := gh.ResolveCredentials(config); err != nil {
+		return err
+	}
 	f, err := settings.ParseCliFilters(c)
 	if err != nil {
 		return err
 	}
-	op, err := descriptor.OperationFromCli(c)
+
+	store, err := state.Open(config.StateFile)
 	if err != nil {
 		return err
 	}
+	defer store.Close()
+
+	return forEachRepository(config, func(config *configuration.Config) error {
+		// Operations may cache repository specific data: each repository gets its own instance.
+		op, err := descriptor.OperationFromCli(c)
+		if err != nil {
+			return err
+		}
+		runner := runner.NewOperationRunner(config, op)
+		runner.GlobalFilters = f
+		runner.OperationName = descriptor.CommandLineDescription().Name
+		runner.State = store
+		return run(runner)
+	})
+}
 
-	runner := runner.NewOperationRunner(config, op)
-	runner.GlobalFilters = f
-	return runner.HandleStock()
+// forEachRepository expands the repository selectors of the configuration, and calls the run
+// function with a copy of the configuration for each repository. Errors for individual repositories
+// don't interrupt the processing of the others: they are aggregated and returned at the end.
+func forEachRepository(config *configuration.Config, run func(*configuration.Config) error) error {
+	if config.IsSingleRepository() {
+		return run(config)
+	}
+	repositories, err := runner.ExpandRepositories(gh.MakeClient(config), config.RepositorySelectors())
+	if err != nil {
+		return err
+	}
+	var errs runner.Errors
+	for _, repository := range repositories {
+		logrus.Debugf("processing repository: %s", repository)
+		errs = errs.Append(errors.Wrapf(run(config.ForRepository(repository)), "repository %q", repository))
+	}
+	return errs.ErrorOrNil()
 }
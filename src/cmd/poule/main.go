@@ -8,7 +8,6 @@ import (
 	"time"
 
 	"poule/operations/catalog"
-	"poule/operations/settings"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/urfave/cli"
@@ -31,7 +30,10 @@ func main() {
 
 	// Register the top-level 'batch' and 'serve' commands.
 	app.Commands = []cli.Command{
+		applyCommand,
 		batchCommand,
+		planCommand,
+		replayCommand,
 		serveCommand,
 		validateCommand,
 	}
@@ -43,17 +45,55 @@ func main() {
 	}
 
 	app.Flags = []cli.Flag{
+		cli.IntFlag{
+			Name:   "app-id",
+			Usage:  "GitHub App ID",
+			EnvVar: "POULE_GITHUB_APP_ID",
+		},
+		cli.IntFlag{
+			Name:   "app-installation-id",
+			Usage:  "GitHub App installation ID (discovered from the repository when unspecified)",
+			EnvVar: "POULE_GITHUB_APP_INSTALLATION_ID",
+		},
+		cli.StringFlag{
+			Name:   "app-private-key-file",
+			Usage:  "GitHub App private key file",
+			EnvVar: "POULE_GITHUB_APP_PRIVATE_KEY_FILE",
+		},
+		cli.StringFlag{
+			Name:   "base-url",
+			Usage:  "GitHub API base URL (for GitHub Enterprise)",
+			EnvVar: "POULE_GITHUB_BASE_URL",
+		},
+		cli.IntFlag{
+			Name:  "concurrency",
+			Usage: "number of items processed concurrently",
+		},
 		cli.BoolFlag{
 			Name:  "debug, D",
 			Usage: "enable debug logging",
 		},
+		cli.BoolFlag{
+			Name:  "disable-search",
+			Usage: "list and filter all items locally instead of using the GitHub search API",
+		},
 		cli.BoolFlag{
 			Name:  "dry-run",
 			Usage: "simulate operations",
 		},
 		cli.StringFlag{
 			Name:  "repository",
-			Usage: "GitHub repository",
+			Usage: "GitHub repository, or comma separated list of repositories, patterns (owner/*), and organizations (org:owner)",
+		},
+		cli.StringFlag{
+			Name:   "state-file",
+			Usage:  "file recording the history of applied operations",
+			EnvVar: "POULE_STATE_FILE",
+		},
+		cli.StringFlag{
+			Name:   "upload-url",
+			Usage:  "GitHub uploads API base URL (for GitHub Enterprise)",
+			EnvVar: "POULE_GITHUB_UPLOAD_URL",
 		},
 		cli.StringFlag{
 			Name:   "token",
@@ -75,7 +115,7 @@ func makeCommand(descriptor catalog.OperationDescriptor) cli.Command {
 	clidesc := descriptor.CommandLineDescription()
 	return cli.Command{
 		Category:  "Operations",
-		Flags:     append(clidesc.Flags, settings.FilteringFlag),
+		Flags:     append(catalog.OperationFlags(descriptor), reportFlags...),
 		Name:      clidesc.Name,
 		Usage:     clidesc.Description,
 		ArgsUsage: clidesc.ArgsUsage,
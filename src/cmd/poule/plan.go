@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"poule/configuration"
+	"poule/gh"
+	"poule/operations"
+	"poule/operations/catalog"
+	"poule/runner"
+	"poule/runner/plan"
+	"poule/runner/state"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+const defaultPlanFile = "poule-plan.json"
+
+var planCommand = cli.Command{
+	Name:      "plan",
+	Usage:     "Save the items an operation or batch file would apply to, for later review and application",
+	ArgsUsage: "<operation> [operation flags] | batch <file>...",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "output, o",
+			Value: defaultPlanFile,
+			Usage: "plan file to write",
+		},
+	},
+	Subcommands: makePlanSubcommands(),
+}
+
+var applyCommand = cli.Command{
+	Name:      "apply",
+	Usage:     "Apply a plan previously saved by the plan command",
+	ArgsUsage: "<plan file>",
+	Flags:     reportFlags,
+	Action: func(c *cli.Context) {
+		if err := doApplyCommand(c); err != nil {
+			fmt.Printf("FATAL: Applying plan: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func makePlanSubcommands() []cli.Command {
+	commands := []cli.Command{
+		{
+			Name:      "batch",
+			Usage:     "Plan groups of commands described in files",
+			ArgsUsage: "<file>...",
+			Action: func(c *cli.Context) {
+				if err := doPlanBatchCommand(c); err != nil {
+					fmt.Printf("FATAL: Planning batch: %v\n", err)
+					os.Exit(1)
+				}
+			},
+		},
+	}
+	for i := range catalog.Index {
+		descriptor := catalog.Index[i]
+		clidesc := descriptor.CommandLineDescription()
+		commands = append(commands, cli.Command{
+			Category:  "Operations",
+			Name:      clidesc.Name,
+			Usage:     clidesc.Description,
+			ArgsUsage: clidesc.ArgsUsage,
+
+			// Arguments are parsed by the action so that they can be saved as part of the plan.
+			SkipFlagParsing: true,
+			Action: func(c *cli.Context) {
+				if err := doPlanOperationCommand(c, descriptor); err != nil {
+					fmt.Printf("FATAL: Planning operation: %v\n", err)
+					os.Exit(1)
+				}
+			},
+		})
+	}
+	return commands
+}
+
+func doPlanOperationCommand(c *cli.Context, descriptor catalog.OperationDescriptor) error {
+	args := []string(c.Args())
+	opContext, err := catalog.ParseOperationArguments(c, descriptor, args)
+	if err != nil {
+		return err
+	}
+
+	p := plan.New()
+	err = runSingleOperation(opContext, descriptor, func(r *runner.OperationRunner) error {
+		r.Plan = p.AddCommandLineOperation(r.Config.Repository, r.OperationName, args)
+		return r.HandleStock()
+	})
+	if err != nil {
+		return err
+	}
+	return writePlan(p, c.Parent().String("output"))
+}
+
+func doPlanBatchCommand(c *cli.Context) error {
+	// Failures of individual operations don't interrupt the batch, but an incomplete plan is never
+	// written.
+	var errs runner.Errors
+	p := plan.New()
+	for _, arg := range c.Args() {
+		err := executeBatchFile(c, arg, func(r *runner.OperationRunner, operationConfig operationConfiguration) error {
+			r.Plan = p.AddOperation(r.Config.Repository, operationConfig.Type, operationConfig.Settings)
+			err := r.HandleStock()
+			errs = errs.Append(errors.Wrapf(err, "planning operation %q on repository %q", operationConfig.Type, r.Config.Repository))
+			return err
+		})
+		if err != nil {
+			return errors.Wrapf(err, "executing batch file %q", arg)
+		}
+	}
+	if err := errs.ErrorOrNil(); err != nil {
+		return errors.Wrap(err, "not saving incomplete plan")
+	}
+	return writePlan(p, c.Parent().String("output"))
+}
+
+func writePlan(p *plan.Plan, path string) error {
+	if err := p.Write(path); err != nil {
+		return err
+	}
+	logrus.Infof("saved plan for %d item(s) to %q", p.ItemCount(), path)
+	return nil
+}
+
+func doApplyCommand(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("expected exactly one plan file")
+	}
+	output, err := reportOutputFromFlags(c)
+	if err != nil {
+		return err
+	}
+	p, err := plan.Read(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	globalConfig := configuration.FromGlobalFlags(c)
+	store, err := state.Open(globalConfig.StateFile)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	var errs runner.Errors
+	runReport := output.makeReport()
+	for _, planned := range p.Operations {
+		op, err := operationFromPlan(c, planned)
+		if err != nil {
+			return err
+		}
+
+		// The plan is always applied to the repository it was computed for.
+		config := *globalConfig
+		config.Repository = planned.Repository
+		if err := config.Validate(); err != nil {
+			return err
+		}
+		if err := gh.ResolveCredentials(&config); err != nil {
+			return err
+		}
+
+		opRunner := runner.NewOperationRunner(&config, op)
+		opRunner.OperationName = planned.Type
+		opRunner.Report = runReport
+		opRunner.State = store
+		errs = errs.Append(opRunner.ApplyPlan(planned))
+	}
+	if err := output.write(runReport); err != nil {
+		errs = errs.Append(err)
+	}
+	return errs.ErrorOrNil()
+}
+
+// operationFromPlan recreates the operation of a plan, either from its command-line arguments or
+// from its configuration settings.
+func operationFromPlan(c *cli.Context, planned *plan.Operation) (operations.Operation, error) {
+	descriptor, ok := catalog.ByNameIndex[planned.Type]
+	if !ok {
+		return nil, errors.Errorf("unknown operation %q in plan", planned.Type)
+	}
+	if !planned.CommandLine {
+		return descriptor.OperationFromConfig(planned.Settings)
+	}
+	opContext, err := catalog.ParseOperationArguments(c, descriptor, planned.Arguments)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid arguments for operation %q in plan", planned.Type)
+	}
+	return descriptor.OperationFromCli(opContext)
+}
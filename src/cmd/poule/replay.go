@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"poule/configuration"
+	"poule/runner"
+	"poule/server"
+	"poule/server/deadletter"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+var replayCommand = cli.Command{
+	Name:      "replay",
+	Usage:     "Process failed events from the dead-letter directory, or captured webhook payloads",
+	ArgsUsage: "<file or directory>...",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "config, c",
+			Value: "poule-server.yml",
+			Usage: "Poule server configuration",
+		},
+		cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "simulate operations",
+		},
+		cli.StringFlag{
+			Name:  "event",
+			Usage: "GitHub event type of captured webhook payloads which don't specify it",
+		},
+		cli.BoolFlag{
+			Name:  "remove",
+			Usage: "remove files which were successfully processed",
+		},
+	},
+	Action: func(c *cli.Context) {
+		if err := doReplayCommand(c); err != nil {
+			fmt.Printf("FATAL: Replaying events: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func doReplayCommand(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return errors.New("expected at least one file or directory")
+	}
+	serveConfig, err := validateServerConfig(c.String("config"))
+	if err != nil {
+		return err
+	}
+	overrides := configuration.FromGlobalFlags(c)
+	overrides.DryRun = overrides.DryRun || c.Bool("dry-run")
+	overrideConfig(&serveConfig.Config, overrides)
+
+	// Gather the files to replay, expanding directories in order of their content.
+	var paths []string
+	for _, arg := range c.Args() {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			paths = append(paths, arg)
+			continue
+		}
+		dirPaths, err := deadletter.List(arg)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, dirPaths...)
+	}
+
+	s, err := server.NewServer(serveConfig)
+	if err != nil {
+		return err
+	}
+	if err := s.FetchRepositoriesConfigs(); err != nil {
+		return err
+	}
+
+	// Events are processed in order, and failures don't interrupt the replay.
+	var errs runner.Errors
+	for _, path := range paths {
+		entry, err := deadletter.Read(path)
+		if err != nil {
+			errs = errs.Append(err)
+			continue
+		}
+		if entry.Event == "" {
+			entry.Event = c.String("event")
+		}
+		if entry.Event == "" {
+			errs = errs.Append(errors.Errorf("unknown event type for %q: use --event", path))
+			continue
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"delivery": entry.Delivery,
+			"event":    entry.Event,
+			"file":     path,
+		}).Info("replaying event")
+		if err := s.HandleMessage(entry.Event, entry.Body); err != nil {
+			errs = errs.Append(errors.Wrapf(err, "failed to replay %q", path))
+			continue
+		}
+		if c.Bool("remove") && !serveConfig.DryRun {
+			if err := os.Remove(path); err != nil {
+				errs = errs.Append(err)
+			}
+		}
+	}
+	return errs.ErrorOrNil()
+}
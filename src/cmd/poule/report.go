@@ -0,0 +1,54 @@
+package main
+
+import (
+	"poule/runner/report"
+
+	"github.com/urfave/cli"
+)
+
+// reportFlags are the flags common to all commands producing a run report.
+var reportFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "report",
+		Usage: "write a report of the outcome for each item to the specified file (\"-\" for stdout)",
+	},
+	cli.StringFlag{
+		Name:  "report-format",
+		Usage: "report format (json, csv, or markdown), inferred from the report file extension by default",
+	},
+}
+
+// reportOutput describes where and how to write the run report requested on the command line.
+type reportOutput struct {
+	Path   string
+	Format report.Format
+}
+
+// reportOutputFromFlags returns the requested report output, or nil if no report was requested.
+func reportOutputFromFlags(c *cli.Context) (*reportOutput, error) {
+	path := c.String("report")
+	if path == "" {
+		return nil, nil
+	}
+	format, err := report.ParseFormat(c.String("report-format"), path)
+	if err != nil {
+		return nil, err
+	}
+	return &reportOutput{Path: path, Format: format}, nil
+}
+
+// makeReport returns a new report if an output was requested, and nil otherwise.
+func (o *reportOutput) makeReport() *report.Report {
+	if o == nil {
+		return nil
+	}
+	return report.New()
+}
+
+// write writes the report to the requested output, if any.
+func (o *reportOutput) write(r *report.Report) error {
+	if o == nil || r == nil {
+		return nil
+	}
+	return r.WriteFile(o.Path, o.Format)
+}
@@ -23,12 +23,21 @@ var serveCommand = cli.Command{
 }
 
 func doServeCommand(c *cli.Context) {
-	serveConfig, err := validateServerConfig(c.String("config"))
+	// Command line flags override the configuration file, including when it is reloaded.
+	configFile := c.String("config")
+	overrides := configuration.FromGlobalFlags(c)
+	loadConfig := func() (*configuration.Server, error) {
+		serveConfig, err := validateServerConfig(configFile)
+		if err != nil {
+			return nil, err
+		}
+		overrideConfig(&serveConfig.Config, overrides)
+		return serveConfig, nil
+	}
+	serveConfig, err := loadConfig()
 	if err != nil {
 		log.Fatal(err)
 	}
-	overrides := configuration.FromGlobalFlags(c)
-	overrideConfig(&serveConfig.Config, overrides)
 
 	// Create the server.
 	s, err := server.NewServer(serveConfig)
@@ -41,14 +50,41 @@ func doServeCommand(c *cli.Context) {
 		log.Fatal(err)
 	}
 
+	// Reload the configuration when the file changes or on SIGHUP.
+	s.WatchConfiguration(configFile, loadConfig)
+
 	// Start the long-running job.
 	s.Run()
 }
 
 func overrideConfig(config, overrides *configuration.Config) {
+	if overrides.AppID != 0 {
+		config.AppID = overrides.AppID
+	}
+	if overrides.AppInstallationID != 0 {
+		config.AppInstallationID = overrides.AppInstallationID
+	}
+	if overrides.AppPrivateKeyFile != "" {
+		config.AppPrivateKeyFile = overrides.AppPrivateKeyFile
+	}
+	if overrides.BaseURL != "" {
+		config.BaseURL = overrides.BaseURL
+	}
+	if overrides.UploadURL != "" {
+		config.UploadURL = overrides.UploadURL
+	}
+	if overrides.Concurrency != 0 {
+		config.Concurrency = overrides.Concurrency
+	}
+	if !config.DisableSearch && overrides.DisableSearch {
+		config.DisableSearch = overrides.DisableSearch
+	}
 	if !config.DryRun && overrides.DryRun {
 		config.DryRun = overrides.DryRun
 	}
+	if overrides.StateFile != "" {
+		config.StateFile = overrides.StateFile
+	}
 	if overrides.Token != "" {
 		config.Token = overrides.Token
 	}
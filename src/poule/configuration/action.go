@@ -2,6 +2,7 @@ package configuration
 
 import (
 	"fmt"
+	"strings"
 
 	cron "gopkg.in/robfig/cron.v2"
 )
@@ -19,6 +20,19 @@ type Action struct {
 
 	// Operations to apply to all repositories when any trigger is met.
 	Operations []OperationConfiguration `yaml:"operations"`
+
+	// AllowedSenders, AllowedTeams, and MinPermission restrict the users whose events trigger the
+	// action: the sender of the event must either be listed in AllowedSenders, be a member of any of
+	// the AllowedTeams (as "org/team"), or have at least the MinPermission level on the repository.
+	// Any sender triggers the action when none of them is specified.
+	AllowedSenders []string `yaml:"allowed_senders"`
+	AllowedTeams   []string `yaml:"allowed_teams"`
+	MinPermission  string   `yaml:"min_permission"`
+}
+
+// IsRestricted returns whether the action can only be triggered by some users.
+func (a Action) IsRestricted() bool {
+	return len(a.AllowedSenders) != 0 || len(a.AllowedTeams) != 0 || a.MinPermission != ""
 }
 
 // Validate verifies the validity of the action definition.
@@ -31,6 +45,14 @@ func (a Action) Validate(opValidator OperationValidator) error {
 			return fmt.Errorf("Invalid schedule specification %q", a.Schedule)
 		}
 	}
+	for _, team := range a.AllowedTeams {
+		if s := strings.Split(team, "/"); len(s) != 2 || s[0] == "" || s[1] == "" {
+			return fmt.Errorf("Invalid allowed team %q (expected \"org/team\")", team)
+		}
+	}
+	if a.MinPermission != "" && (a.MinPermission == "none" || !StringSlice(PermissionLevels).Contains(a.MinPermission)) {
+		return fmt.Errorf("Invalid min_permission %q (expected one of %s)", a.MinPermission, strings.Join(PermissionLevels[1:], ", "))
+	}
 	for _, opConfig := range a.Operations {
 		if err := opValidator.Validate(&opConfig); err != nil {
 			return err
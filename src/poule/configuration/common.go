@@ -2,6 +2,8 @@ package configuration
 
 // GitHubEvents is the collection of valid GitHub events which can be used as triggers.
 var GitHubEvents = []string{
+	"check_run",
+	"check_suite",
 	"commit_comment",
 	"create",
 	"delete",
@@ -13,10 +15,12 @@ var GitHubEvents = []string{
 	"integration_installation_repositories",
 	"issue_comment",
 	"issues",
+	"label",
 	"member",
 	"membership",
 	"page_build",
 	"public",
+	"pull_request_review",
 	"pull_request_review_comment",
 	"pull_request",
 	"push",
@@ -27,6 +31,33 @@ var GitHubEvents = []string{
 	"watch",
 }
 
+// PermissionLevels are the permission levels of a user on a GitHub repository, from lowest to
+// highest.
+var PermissionLevels = []string{
+	"none",
+	"read",
+	"triage",
+	"write",
+	"maintain",
+	"admin",
+}
+
+// RolePermission returns the permission level of a user on a repository given the permission
+// reported by GitHub and the name of the user's role, which is only more precise when it is a
+// permission level (i.e., not a custom role).
+func RolePermission(permission, role string) string {
+	if StringSlice(PermissionLevels).Contains(role) {
+		return role
+	}
+	return permission
+}
+
+// HasPermission returns whether the permission level is at least the required one. Unknown levels
+// are considered lower than any known one.
+func HasPermission(level, required string) bool {
+	return StringSlice(PermissionLevels).Index(level) >= StringSlice(PermissionLevels).Index(required)
+}
+
 // StringSlice is a slice of strings.
 type StringSlice []string
 
@@ -39,3 +70,13 @@ func (s StringSlice) Contains(item string) bool {
 	}
 	return false
 }
+
+// Index returns the position of the first occurence of an item in the StringSlice, or -1.
+func (s StringSlice) Index(item string) int {
+	for i, v := range s {
+		if v == item {
+			return i
+		}
+	}
+	return -1
+}
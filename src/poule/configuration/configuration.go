@@ -1,6 +1,8 @@
 package configuration
 
 import (
+	"net/url"
+	"path"
 	"strings"
 	"time"
 
@@ -10,17 +12,44 @@ import (
 
 // Config is the main configuration object for poule.
 type Config struct {
-	RunDelay   time.Duration `yaml:"delay"`
-	DryRun     bool          `yaml:"dry_run"`
-	Repository string        `yaml:"repository"`
-	Token      string        `yaml:"token"`
-	TokenFile  string        `yaml:"token_file"`
+	Concurrency int           `yaml:"concurrency"`
+	RunDelay    time.Duration `yaml:"delay"`
+	DryRun      bool          `yaml:"dry_run"`
+	StateFile   string        `yaml:"state_file"`
+	Token       string        `yaml:"token"`
+	TokenFile   string        `yaml:"token_file"`
+
+	// Repository is either the full name of a GitHub repository (e.g., "moby/moby"), or a comma
+	// separated list of repository selectors: full names, glob patterns matched against the
+	// repositories of an organization (e.g., "moby/*"), or all repositories of an organization
+	// (e.g., "org:moby"). Selectors are expanded into individual repositories before execution.
+	Repository string `yaml:"repository"`
+
+	// BaseURL and UploadURL are the GitHub API endpoints, which only need to be specified for
+	// GitHub Enterprise (e.g., "https://github.example.com/api/v3/").
+	BaseURL   string `yaml:"base_url"`
+	UploadURL string `yaml:"upload_url"`
+
+	// AppID, AppPrivateKeyFile, and AppInstallationID configure authentication as a GitHub App,
+	// as an alternative to Token and TokenFile. When AppInstallationID is unspecified, the
+	// installation is discovered from the repository.
+	AppID             int    `yaml:"app_id"`
+	AppPrivateKeyFile string `yaml:"app_private_key_file"`
+	AppInstallationID int    `yaml:"app_installation_id"`
+
+	// RateLimitReserve is the number of GitHub API requests that processing the entire stock of
+	// items must leave available, for example to keep handling webhooks in server mode.
+	RateLimitReserve int `yaml:"rate_limit_reserve"`
+
+	// DisableSearch disables the translation of global filters into GitHub search queries when
+	// processing the entire stock of items, in which case all items are listed and filtered locally.
+	DisableSearch bool `yaml:"disable_search"`
 }
 
 // OperationConfiguration describes an operation.
 type OperationConfiguration struct {
 	Type     string                 `yaml:"type"`
-	Filters  map[string]interface{} `yaml:"filters"`
+	Filters  interface{}            `yaml:"filters"`
 	Settings map[string]interface{} `yaml:"settings"`
 }
 
@@ -33,11 +62,104 @@ func (c *Config) SplitRepository() (string, string) {
 	return username, repository
 }
 
+// RepositorySelectors returns the list of repository selectors of the configuration.
+func (c *Config) RepositorySelectors() []string {
+	selectors := []string{}
+	for _, selector := range strings.Split(c.Repository, ",") {
+		if selector = strings.TrimSpace(selector); selector != "" {
+			selectors = append(selectors, selector)
+		}
+	}
+	return selectors
+}
+
+// IsSingleRepository returns whether the configuration designates a single repository by name.
+func (c *Config) IsSingleRepository() bool {
+	selectors := c.RepositorySelectors()
+	return len(selectors) == 1 && !IsRepositoryPattern(selectors[0])
+}
+
+// IsRepositoryPattern returns whether the repository selector designates multiple repositories.
+func IsRepositoryPattern(selector string) bool {
+	return strings.HasPrefix(selector, OrganizationSelectorPrefix) || strings.ContainsAny(selector, "*?[")
+}
+
+// ForRepository returns a copy of the configuration for the specified repository.
+func (c *Config) ForRepository(repository string) *Config {
+	config := *c
+	config.Repository = repository
+	return &config
+}
+
+// UsesGitHubApp returns whether the configuration authenticates as a GitHub App.
+func (c *Config) UsesGitHubApp() bool {
+	return c.AppID != 0
+}
+
 // Validate verifies the validity of the configuration object.
 func (c *Config) Validate() error {
-	if _, _, err := getRepository(c.Repository); err != nil {
+	if err := c.ValidateRepositories(); err != nil {
 		return err
 	}
+	if err := c.ValidateEndpoints(); err != nil {
+		return err
+	}
+	return c.ValidateCredentials()
+}
+
+// ValidateRepositories verifies the validity of the repository selectors.
+func (c *Config) ValidateRepositories() error {
+	selectors := c.RepositorySelectors()
+	if len(selectors) == 0 {
+		return errors.Errorf("invalid repository %q", c.Repository)
+	}
+	for _, selector := range selectors {
+		if strings.HasPrefix(selector, OrganizationSelectorPrefix) {
+			if org := strings.TrimPrefix(selector, OrganizationSelectorPrefix); org == "" || strings.Contains(org, "/") {
+				return errors.Errorf("invalid organization selector %q", selector)
+			}
+			continue
+		}
+		owner, name, err := getRepository(selector)
+		if err != nil {
+			return err
+		}
+		if strings.ContainsAny(owner, "*?[") {
+			return errors.Errorf("invalid repository %q: patterns are only supported for repository names", selector)
+		}
+		if _, err := path.Match(name, ""); err != nil {
+			return errors.Errorf("invalid repository pattern %q", selector)
+		}
+	}
+
+	// Discovering the installation of a GitHub App requires a single repository.
+	if c.UsesGitHubApp() && c.AppInstallationID == 0 && !c.IsSingleRepository() {
+		return errors.Errorf("GitHub App authentication for multiple repositories requires an installation ID")
+	}
+	return nil
+}
+
+// ValidateEndpoints verifies the validity of the GitHub API endpoints.
+func (c *Config) ValidateEndpoints() error {
+	for key, value := range map[string]string{"base_url": c.BaseURL, "upload_url": c.UploadURL} {
+		if value == "" {
+			continue
+		}
+		if u, err := url.Parse(value); err != nil || !u.IsAbs() {
+			return errors.Errorf("invalid %s %q", key, value)
+		}
+	}
+	return nil
+}
+
+// ValidateCredentials verifies the validity of the authentication settings.
+func (c *Config) ValidateCredentials() error {
+	if c.UsesGitHubApp() && c.AppPrivateKeyFile == "" {
+		return errors.Errorf("GitHub App authentication requires a private key file")
+	}
+	if !c.UsesGitHubApp() && (c.AppPrivateKeyFile != "" || c.AppInstallationID != 0) {
+		return errors.Errorf("GitHub App authentication requires an app ID")
+	}
 	return nil
 }
 
@@ -49,17 +171,25 @@ func (c *Config) Delay() time.Duration {
 // FromGlobalFlags creates a configuration object from command line flags.
 func FromGlobalFlags(c *cli.Context) *Config {
 	config := &Config{
-		DryRun:     c.GlobalBool("dry-run"),
-		Repository: c.GlobalString("repository"),
-		Token:      c.GlobalString("token"),
-		TokenFile:  c.GlobalString("token-file"),
+		AppID:             c.GlobalInt("app-id"),
+		AppInstallationID: c.GlobalInt("app-installation-id"),
+		AppPrivateKeyFile: c.GlobalString("app-private-key-file"),
+		BaseURL:           c.GlobalString("base-url"),
+		UploadURL:         c.GlobalString("upload-url"),
+		Concurrency:       c.GlobalInt("concurrency"),
+		DisableSearch:     c.GlobalBool("disable-search"),
+		DryRun:            c.GlobalBool("dry-run"),
+		Repository:        c.GlobalString("repository"),
+		StateFile:         c.GlobalString("state-file"),
+		Token:             c.GlobalString("token"),
+		TokenFile:         c.GlobalString("token-file"),
 	}
 	return config
 }
 
 func getRepository(repository string) (string, string, error) {
 	s := strings.SplitN(repository, "/", 2)
-	if len(s) != 2 {
+	if len(s) != 2 || s[0] == "" || s[1] == "" {
 		return "", "", errors.Errorf("invalid repository %q", repository)
 	}
 	return s[0], s[1], nil
@@ -0,0 +1,23 @@
+package configuration
+
+import "testing"
+
+func TestValidateEndpoints(t *testing.T) {
+	for _, tc := range []struct {
+		baseURL   string
+		uploadURL string
+		valid     bool
+	}{
+		{"", "", true},
+		{"https://github.example.com/api/v3", "", true},
+		{"https://github.example.com/api/v3/", "https://github.example.com/api/uploads", true},
+		{"github.example.com/api/v3", "", false},
+		{"https://github.example.com/%zz", "", false},
+		{"", "/api/uploads", false},
+	} {
+		config := Config{BaseURL: tc.baseURL, UploadURL: tc.uploadURL}
+		if err := config.ValidateEndpoints(); (err == nil) != tc.valid {
+			t.Fatalf("Expected endpoints %q and %q to be valid=%t, got error %v", tc.baseURL, tc.uploadURL, tc.valid, err)
+		}
+	}
+}
@@ -14,4 +14,8 @@ const (
 
 	// PouleConfigurationFile is the name of the special file at the root of the repository.
 	PouleConfigurationFile = "poule.yml"
+
+	// OrganizationSelectorPrefix prefixes repository selectors designating all repositories of an
+	// organization (e.g., "org:moby").
+	OrganizationSelectorPrefix = "org:"
 )
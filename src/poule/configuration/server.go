@@ -1,5 +1,11 @@
 package configuration
 
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
 // Server is the configuration object for the server mode.
 type Server struct {
 	Config      `yaml:",inline"`
@@ -8,6 +14,29 @@ type Server struct {
 	LookupdAddr string `yaml:"nsq_lookupd"`
 	Channel     string `yaml:"nsq_channel"`
 
+	// HTTPWorkers, HTTPQueueSize, and HTTPRetries configure the processing of webhooks received
+	// over HTTP: the number of events processed concurrently, the maximum number of events waiting
+	// to be processed, and the number of times processing of an event is retried on failure.
+	HTTPWorkers   int `yaml:"http_workers"`
+	HTTPQueueSize int `yaml:"http_queue_size"`
+	HTTPRetries   int `yaml:"http_retries"`
+
+	// NSQMaxAttempts is the number of times processing of an event received from NSQ is attempted
+	// before giving up on it.
+	NSQMaxAttempts int `yaml:"nsq_max_attempts"`
+
+	// DeadLetterDir is the directory where events which failed to be processed are persisted, so
+	// that they can be replayed later. Failed events are only logged when unspecified.
+	DeadLetterDir string `yaml:"dead_letter_dir"`
+
+	// Commands lists the operations which can be run on an issue or a pull request by commenting
+	// "/poule <operation> [arguments...]". Commands are disabled when empty.
+	Commands []string `yaml:"commands"`
+
+	// CommandsPermission is the minimum permission level on the repository required to run
+	// commands (e.g., "write").
+	CommandsPermission string `yaml:"commands_permission"`
+
 	// Repositories maps GitHub repositories full names their corresponding
 	// NSQ topic.
 	Repositories map[string]string `yaml:"repositories"`
@@ -19,6 +48,25 @@ type Server struct {
 // Validate verifies the validity of the configuration.
 func (s Server) Validate(opValidator OperationValidator) []error {
 	var errs []error
+	if err := s.Config.ValidateEndpoints(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := s.Config.ValidateCredentials(); err != nil {
+		errs = append(errs, err)
+	}
+	for key, value := range map[string]int{
+		"http_workers":     s.HTTPWorkers,
+		"http_queue_size":  s.HTTPQueueSize,
+		"http_retries":     s.HTTPRetries,
+		"nsq_max_attempts": s.NSQMaxAttempts,
+	} {
+		if value < 0 {
+			errs = append(errs, errors.Errorf("invalid %s %d", key, value))
+		}
+	}
+	if s.CommandsPermission != "" && (s.CommandsPermission == "none" || !StringSlice(PermissionLevels).Contains(s.CommandsPermission)) {
+		errs = append(errs, errors.Errorf("invalid commands_permission %q (expected one of %s)", s.CommandsPermission, strings.Join(PermissionLevels[1:], ", ")))
+	}
 	for _, action := range s.CommonActions {
 		if err := action.Validate(opValidator); err != nil {
 			errs = append(errs, err)
@@ -0,0 +1,349 @@
+package gh
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"poule/configuration"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// defaultAPIURL is the URL of the public GitHub API.
+	defaultAPIURL = "https://api.github.com/"
+
+	// appPreviewMediaType is required to access the GitHub Apps API.
+	appPreviewMediaType = "application/vnd.github.machine-man-preview+json"
+
+	// appJWTLifetime is the validity of the JSON Web Tokens used to authenticate as a GitHub App.
+	// GitHub refuses tokens valid for more than 10 minutes.
+	appJWTLifetime = 9 * time.Minute
+
+	// appTokenExpiryMargin is how long before its actual expiration an installation token gets
+	// renewed.
+	appTokenExpiryMargin = time.Minute
+
+	// appRequestTimeout bounds the requests made to discover installations and create tokens.
+	appRequestTimeout = 30 * time.Second
+
+	// appDiscoveryRetryDelay is how long a failure to discover an installation is remembered.
+	appDiscoveryRetryDelay = time.Minute
+)
+
+var (
+	appCredentialsLock sync.Mutex
+
+	// appKeys caches the parsed private keys by file, along with the state of the file when it was
+	// read so that a rotated key is picked up.
+	appKeys = map[string]appKey{}
+
+	// appInstallations caches the installations resolved for repositories, and the errors which
+	// occurred resolving them.
+	appInstallations = map[string]appInstallation{}
+
+	// appTokenSources caches the token source of each installation, along with the private key it
+	// signs its requests with.
+	appTokenSources = map[string]appCredentials{}
+)
+
+// appKey is a parsed private key, and the state of its file when it was read.
+type appKey struct {
+	key     *rsa.PrivateKey
+	modTime time.Time
+	size    int64
+}
+
+// appCredentials is the token source of an installation, and the private key it uses.
+type appCredentials struct {
+	key    *rsa.PrivateKey
+	source oauth2.TokenSource
+}
+
+// appInstallation is the outcome of resolving the GitHub App installation for a repository.
+type appInstallation struct {
+	id      int
+	err     error
+	expires time.Time
+}
+
+// appTokenSource provides installation tokens for a GitHub App.
+type appTokenSource struct {
+	apiURL         string
+	appID          int
+	key            *rsa.PrivateKey
+	client         *http.Client
+	installationID int
+}
+
+// ResolveCredentials verifies that the credentials of the configuration can be used. When
+// authenticating as a GitHub App, the private key is parsed and the installation is resolved once:
+// subsequent clients for the same repository reuse both the installation and its token source.
+func ResolveCredentials(c *configuration.Config) error {
+	if !c.UsesGitHubApp() {
+		return nil
+	}
+	_, _, err := appCredentialsFor(c)
+	return err
+}
+
+// ResetAppCredentials drops the cached GitHub App private keys, installations, and token sources,
+// so that they are read and resolved again by the next clients (e.g., when the configuration is
+// reloaded after the App was reinstalled).
+func ResetAppCredentials() {
+	appCredentialsLock.Lock()
+	defer appCredentialsLock.Unlock()
+	appKeys = map[string]appKey{}
+	appInstallations = map[string]appInstallation{}
+	appTokenSources = map[string]appCredentials{}
+}
+
+// appCredentialsFor returns the key uniquely identifying the installation token used for a given
+// configuration, and the token source shared by all clients for that installation. Repositories
+// sharing an installation share its token and its rate limit budget, so the key is based on the
+// installation, which is discovered from the repository when it isn't configured.
+func appCredentialsFor(c *configuration.Config) (string, oauth2.TokenSource, error) {
+	installationID, err := appInstallationID(c)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// The token source is replaced when the private key was rotated.
+	privateKey, err := appPrivateKey(c.AppPrivateKeyFile)
+	if err != nil {
+		return "", nil, err
+	}
+	key := appInstallationKey(c, installationID)
+	appCredentialsLock.Lock()
+	credentials, ok := appTokenSources[key]
+	appCredentialsLock.Unlock()
+	if ok && credentials.key == privateKey {
+		return key, credentials.source, nil
+	}
+	source := newAppTokenSource(c, privateKey, installationID)
+
+	// Keep the token source of any concurrent caller, so that the installation has a single one.
+	appCredentialsLock.Lock()
+	defer appCredentialsLock.Unlock()
+	if credentials, ok := appTokenSources[key]; ok && credentials.key == privateKey {
+		return key, credentials.source, nil
+	}
+	appTokenSources[key] = appCredentials{key: privateKey, source: oauth2.ReuseTokenSource(nil, source)}
+	return key, appTokenSources[key].source, nil
+}
+
+func appInstallationKey(c *configuration.Config, installationID int) string {
+	return fmt.Sprintf("app:%s:%d:%d", APIURL(c), c.AppID, installationID)
+}
+
+// appInstallationID returns the ID of the GitHub App installation used for the configuration.
+// Failures to discover the installation are cached for appDiscoveryRetryDelay, so that clients
+// created in the meantime don't retry the discovery.
+func appInstallationID(c *configuration.Config) (int, error) {
+	if c.AppInstallationID != 0 {
+		return c.AppInstallationID, nil
+	}
+
+	repositoryKey := fmt.Sprintf("%s:%d:%s", APIURL(c), c.AppID, strings.ToLower(c.Repository))
+	appCredentialsLock.Lock()
+	installation, ok := appInstallations[repositoryKey]
+	appCredentialsLock.Unlock()
+	if ok && (installation.err == nil || time.Now().Before(installation.expires)) {
+		return installation.id, installation.err
+	}
+
+	privateKey, err := appPrivateKey(c.AppPrivateKeyFile)
+	if err != nil {
+		return 0, err
+	}
+	ts := newAppTokenSource(c, privateKey, 0)
+	owner, repository := c.SplitRepository()
+	installation = appInstallation{expires: time.Now().Add(appDiscoveryRetryDelay)}
+	installation.id, installation.err = ts.discoverInstallation(owner, repository)
+	appCredentialsLock.Lock()
+	appInstallations[repositoryKey] = installation
+	appCredentialsLock.Unlock()
+	return installation.id, installation.err
+}
+
+func newAppTokenSource(c *configuration.Config, key *rsa.PrivateKey, installationID int) *appTokenSource {
+	return &appTokenSource{
+		apiURL:         APIURL(c),
+		appID:          c.AppID,
+		key:            key,
+		client:         &http.Client{Timeout: appRequestTimeout},
+		installationID: installationID,
+	}
+}
+
+// appPrivateKey returns the private key read from the specified file, which is only parsed again
+// when the file is modified.
+func appPrivateKey(file string) (*rsa.PrivateKey, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read GitHub App private key")
+	}
+	appCredentialsLock.Lock()
+	defer appCredentialsLock.Unlock()
+	if cached, ok := appKeys[file]; ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.key, nil
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read GitHub App private key")
+	}
+	key, err := parsePrivateKey(b)
+	if err != nil {
+		return nil, err
+	}
+	appKeys[file] = appKey{key: key, modTime: info.ModTime(), size: info.Size()}
+	return key, nil
+}
+
+// forgetAppInstallation drops the token source of an installation and the repositories resolved
+// to it, so that the installation is resolved again by the next clients.
+func forgetAppInstallation(apiURL string, appID, installationID int) {
+	appCredentialsLock.Lock()
+	defer appCredentialsLock.Unlock()
+	delete(appTokenSources, fmt.Sprintf("app:%s:%d:%d", apiURL, appID, installationID))
+	prefix := fmt.Sprintf("%s:%d:", apiURL, appID)
+	for repositoryKey, installation := range appInstallations {
+		if installation.id == installationID && strings.HasPrefix(repositoryKey, prefix) {
+			delete(appInstallations, repositoryKey)
+		}
+	}
+}
+
+// discoverInstallation returns the ID of the installation of the GitHub App for the repository.
+func (s *appTokenSource) discoverInstallation(owner, repository string) (int, error) {
+	jwt, err := s.makeJWT(time.Now())
+	if err != nil {
+		return 0, err
+	}
+	var installation struct {
+		ID int `json:"id"`
+	}
+	url := fmt.Sprintf("%srepos/%s/%s/installation", s.apiURL, owner, repository)
+	if err := s.do("GET", url, jwt, &installation); err != nil {
+		return 0, errors.Wrapf(err, "failed to find GitHub App installation for repository \"%s/%s\"", owner, repository)
+	}
+	return installation.ID, nil
+}
+
+// Token exchanges a freshly minted JSON Web Token for an installation token.
+func (s *appTokenSource) Token() (*oauth2.Token, error) {
+	jwt, err := s.makeJWT(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	var token struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	url := fmt.Sprintf("%sinstallations/%d/access_tokens", s.apiURL, s.installationID)
+	if err := s.do("POST", url, jwt, &token); err != nil {
+		// The installation was removed, or the App credentials changed: the installation is
+		// resolved again by the next clients.
+		if err, ok := err.(*appStatusError); ok && (err.code == http.StatusUnauthorized || err.code == http.StatusNotFound) {
+			forgetAppInstallation(s.apiURL, s.appID, s.installationID)
+		}
+		return nil, errors.Wrapf(err, "failed to create token for GitHub App installation %d", s.installationID)
+	}
+	return &oauth2.Token{
+		AccessToken: token.Token,
+		TokenType:   "token",
+		Expiry:      token.ExpiresAt.Add(-appTokenExpiryMargin),
+	}, nil
+}
+
+func (s *appTokenSource) do(method, url, jwt string, v interface{}) error {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", appPreviewMediaType)
+	req.Header.Set("Authorization", "Bearer "+jwt)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return &appStatusError{
+			code:    resp.StatusCode,
+			message: fmt.Sprintf("%s %s: %s %s", method, url, resp.Status, strings.TrimSpace(string(body))),
+		}
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// appStatusError is returned for unsuccessful responses of the GitHub Apps API.
+type appStatusError struct {
+	code    int
+	message string
+}
+
+func (e *appStatusError) Error() string {
+	return e.message
+}
+
+// makeJWT returns a JSON Web Token authenticating as the GitHub App.
+func (s *appTokenSource) makeJWT(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]int64{
+		// Backdate the token to allow for clock drift.
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": int64(s.appID),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(payload))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", errors.Wrap(err, "failed to sign GitHub App token")
+	}
+	return payload + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func parsePrivateKey(b []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("invalid GitHub App private key: no PEM data found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid GitHub App private key")
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("invalid GitHub App private key: not an RSA key")
+	}
+	return rsaKey, nil
+}
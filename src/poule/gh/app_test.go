@@ -0,0 +1,251 @@
+package gh
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"poule/configuration"
+)
+
+func TestAppTokenSource(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Verify the JSON Web Token signature.
+		parts := strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), ".")
+		if len(parts) != 3 {
+			t.Fatalf("Malformed authorization header %q", r.Header.Get("Authorization"))
+		}
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			t.Fatalf("Invalid JWT signature: %v", err)
+		}
+		var claims map[string]int64
+		b, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		if err := json.Unmarshal(b, &claims); err != nil || claims["iss"] != 1234 {
+			t.Fatalf("Invalid JWT claims %s", b)
+		}
+
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/repos/icecrime/poule/installation":
+			fmt.Fprint(w, `{"id": 42}`)
+		case r.Method == "POST" && r.URL.Path == "/installations/42/access_tokens":
+			fmt.Fprintf(w, `{"token": "v1.token", "expires_at": %q}`, expiresAt.Format(time.RFC3339))
+		default:
+			t.Fatalf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	ts := &appTokenSource{
+		apiURL: server.URL + "/",
+		appID:  1234,
+		key:    key,
+		client: &http.Client{},
+	}
+	installationID, err := ts.discoverInstallation("icecrime", "poule")
+	if err != nil {
+		t.Fatalf("discoverInstallation returned unexpected error %v", err)
+	}
+	if installationID != 42 {
+		t.Fatalf("Expected discovered installation 42, got %d", installationID)
+	}
+
+	ts.installationID = installationID
+	token, err := ts.Token()
+	if err != nil {
+		t.Fatalf("Token returned unexpected error %v", err)
+	}
+	if token.AccessToken != "v1.token" {
+		t.Fatalf("Unexpected access token %q", token.AccessToken)
+	}
+	if expected := expiresAt.Add(-appTokenExpiryMargin); !token.Expiry.Equal(expected) {
+		t.Fatalf("Expected token expiry %v, got %v", expected, token.Expiry)
+	}
+}
+
+func TestAppCredentialsKeySharedByInstallation(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile, err := ioutil.TempFile("", "poule-app-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(keyFile.Name())
+	pem.Encode(keyFile, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	keyFile.Close()
+
+	// Both repositories belong to the same installation, which is only discovered once for each.
+	discoveries := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		discoveries[r.URL.Path]++
+		fmt.Fprint(w, `{"id": 42}`)
+	}))
+	defer server.Close()
+
+	var keys []string
+	for _, repository := range []string{"icecrime/poule", "icecrime/other", "icecrime/poule"} {
+		c := &configuration.Config{
+			AppID:             1234,
+			AppPrivateKeyFile: keyFile.Name(),
+			BaseURL:           server.URL,
+			Repository:        repository,
+		}
+		if err := ResolveCredentials(c); err != nil {
+			t.Fatalf("ResolveCredentials returned unexpected error %v", err)
+		}
+		k, ts, err := appCredentialsFor(c)
+		if err != nil {
+			t.Fatalf("appCredentialsFor returned unexpected error %v", err)
+		}
+		if _, source, _ := appCredentialsFor(c); source != ts {
+			t.Fatalf("Expected clients of the same installation to share the token source")
+		}
+		keys = append(keys, k)
+	}
+	if keys[0] != keys[1] || keys[1] != keys[2] {
+		t.Fatalf("Expected repositories of the same installation to share credentials, got %v", keys)
+	}
+	for path, count := range discoveries {
+		if count != 1 {
+			t.Fatalf("Expected a single discovery for %q, got %d", path, count)
+		}
+	}
+}
+
+func TestAppInstallationFailureCached(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile, err := ioutil.TempFile("", "poule-app-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(keyFile.Name())
+	pem.Encode(keyFile, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	keyFile.Close()
+
+	// The App isn't installed on the repository: the failure is reported when resolving the
+	// credentials, and clients created afterwards don't retry the discovery.
+	discoveries := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		discoveries++
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	c := &configuration.Config{
+		AppID:             1234,
+		AppPrivateKeyFile: keyFile.Name(),
+		BaseURL:           server.URL,
+		Repository:        "icecrime/missing",
+	}
+	if err := ResolveCredentials(c); err == nil {
+		t.Fatalf("Expected ResolveCredentials to fail for a repository without installation")
+	}
+	MakeClient(c)
+	if discoveries != 1 {
+		t.Fatalf("Expected a single discovery, got %d", discoveries)
+	}
+}
+
+func writeAppKey(t *testing.T, file string) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := ioutil.WriteFile(file, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAppCredentialsRenewal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "poule-app-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile := dir + "/key.pem"
+	writeAppKey(t, keyFile)
+
+	// The App is reinstalled after the first token was created.
+	installationID := 42
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET":
+			fmt.Fprintf(w, `{"id": %d}`, installationID)
+		case r.URL.Path == fmt.Sprintf("/installations/%d/access_tokens", installationID):
+			fmt.Fprintf(w, `{"token": "v1.token", "expires_at": %q}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := &configuration.Config{
+		AppID:             1234,
+		AppPrivateKeyFile: keyFile,
+		BaseURL:           server.URL,
+		Repository:        "icecrime/poule",
+	}
+	_, ts, err := appCredentialsFor(c)
+	if err != nil {
+		t.Fatalf("appCredentialsFor returned unexpected error %v", err)
+	}
+	if _, err := ts.Token(); err != nil {
+		t.Fatalf("Token returned unexpected error %v", err)
+	}
+
+	// A rotated private key results in a new token source.
+	writeAppKey(t, keyFile)
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(keyFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+	_, rotated, err := appCredentialsFor(c)
+	if err != nil {
+		t.Fatalf("appCredentialsFor returned unexpected error %v", err)
+	}
+	if rotated == ts {
+		t.Fatalf("Expected a new token source after rotating the private key")
+	}
+
+	// The removed installation is forgotten once creating a token fails.
+	installationID = 43
+	if _, err := rotated.Token(); err == nil {
+		t.Fatalf("Expected Token to fail for a removed installation")
+	}
+	key, ts, err := appCredentialsFor(c)
+	if err != nil {
+		t.Fatalf("appCredentialsFor returned unexpected error %v", err)
+	}
+	if !strings.HasSuffix(key, ":43") {
+		t.Fatalf("Expected the new installation to be resolved, got credentials %q", key)
+	}
+	if _, err := ts.Token(); err != nil {
+		t.Fatalf("Token returned unexpected error %v", err)
+	}
+}
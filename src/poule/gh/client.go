@@ -1,7 +1,11 @@
 package gh
 
 import (
+	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
 
 	"poule/configuration"
 
@@ -19,14 +23,24 @@ func (d DefaultClient) Issues() IssuesService {
 	return d.Client.Issues
 }
 
+// Organizations returns the organization service instance.
+func (d DefaultClient) Organizations() OrganizationsService {
+	return d.Client.Organizations
+}
+
 // PullRequests returns the pull request service instance.
 func (d DefaultClient) PullRequests() PullRequestsService {
-	return d.Client.PullRequests
+	return pullRequestsService{d.Client.PullRequests, d.Client}
+}
+
+// Reactions returns the reactions service instance.
+func (d DefaultClient) Reactions() ReactionsService {
+	return d.Client.Reactions
 }
 
 // Repositories returns the repository service instance.
 func (d DefaultClient) Repositories() RepositoriesService {
-	return d.Client.Repositories
+	return repositoriesService{d.Client.Repositories, d.Client}
 }
 
 // Search returns the search service instance.
@@ -49,9 +63,77 @@ func GetToken(c *configuration.Config) string {
 	return ""
 }
 
-// MakeClient returns a new client instance for the specified configuration.
+// MakeClient returns a new client instance for the specified configuration. All clients sharing
+// the same credentials also share the same RateLimiter, and all clients share the same ETagCache.
+// GitHub App credentials are expected to be resolved beforehand using ResolveCredentials: failures
+// to do so are reported on the first API call.
 func MakeClient(c *configuration.Config) Client {
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: GetToken(c)})
-	tc := oauth2.NewClient(oauth2.NoContext, ts)
-	return DefaultClient{github.NewClient(tc)}
+	key, source := clientCredentials(c)
+	tc := &http.Client{
+		Transport: RateLimiterFor(key).Transport(&oauth2.Transport{
+			Source: source,
+			Base:   defaultETagCache.Transport(nil, key),
+		}),
+	}
+	client := github.NewClient(tc)
+	if c.BaseURL != "" {
+		client.BaseURL, _ = url.Parse(withTrailingSlash(c.BaseURL))
+	}
+	if c.UploadURL != "" {
+		client.UploadURL, _ = url.Parse(withTrailingSlash(c.UploadURL))
+	}
+	return DefaultClient{client}
+}
+
+// APIURL returns the base URL of the GitHub API for the specified configuration.
+func APIURL(c *configuration.Config) string {
+	if c.BaseURL != "" {
+		return withTrailingSlash(c.BaseURL)
+	}
+	return defaultAPIURL
+}
+
+func withTrailingSlash(s string) string {
+	if strings.HasSuffix(s, "/") {
+		return s
+	}
+	return s + "/"
+}
+
+// RateLimiterForConfig returns the RateLimiter shared by all clients using the same credentials as
+// the specified configuration.
+func RateLimiterForConfig(c *configuration.Config) *RateLimiter {
+	return RateLimiterFor(credentialsKey(c))
+}
+
+// credentialsKey identifies the credentials used for the specified configuration.
+func credentialsKey(c *configuration.Config) string {
+	key, _ := clientCredentials(c)
+	return key
+}
+
+// clientCredentials returns the key identifying the credentials used for the specified
+// configuration, and their token source. Installation tokens expire and are renewed, so GitHub App
+// credentials are identified by their installation.
+func clientCredentials(c *configuration.Config) (string, oauth2.TokenSource) {
+	if !c.UsesGitHubApp() {
+		token := GetToken(c)
+		return token, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	}
+	// When the installation can't be determined, API calls fail anyway as no token can be created.
+	key, ts, err := appCredentialsFor(c)
+	if err != nil {
+		return fmt.Sprintf("app:%s:%d:%s", APIURL(c), c.AppID, strings.ToLower(c.Repository)), errorTokenSource{err}
+	}
+	return key, ts
+}
+
+// errorTokenSource is a token source which always fails: it allows to report credentials errors
+// on the first API call rather than when creating the client.
+type errorTokenSource struct {
+	err error
+}
+
+func (e errorTokenSource) Token() (*oauth2.Token, error) {
+	return nil, e.err
 }
@@ -0,0 +1,44 @@
+package gh
+
+import (
+	"net/http"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+// GetFileContent retrieves the content of a file in a repository through the Contents API, at the
+// specified reference (an empty reference designates the repository's default branch). It returns
+// a nil content and no error when the file doesn't exist.
+func GetFileContent(client Client, owner, repo, path, ref string) ([]byte, error) {
+	var opt *github.RepositoryContentGetOptions
+	if ref != "" {
+		opt = &github.RepositoryContentGetOptions{Ref: ref}
+	}
+	file, _, resp, err := client.Repositories().GetContents(owner, repo, path, opt)
+	switch {
+	case err != nil && resp != nil && resp.Response != nil && resp.StatusCode == http.StatusNotFound:
+		return nil, nil
+	case err != nil:
+		return nil, errors.Wrapf(err, "failed to retrieve %q from repository \"%s/%s\"", path, owner, repo)
+	case file == nil:
+		return nil, errors.Errorf("%q in repository \"%s/%s\" is not a file", path, owner, repo)
+	}
+	content, err := file.GetContent()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decode %q from repository \"%s/%s\"", path, owner, repo)
+	}
+	return []byte(content), nil
+}
+
+// GetDefaultBranch returns the name of the default branch of a repository.
+func GetDefaultBranch(client Client, owner, repo string) (string, error) {
+	repository, _, err := client.Repositories().Get(owner, repo)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to retrieve repository \"%s/%s\"", owner, repo)
+	}
+	if repository.DefaultBranch == nil || *repository.DefaultBranch == "" {
+		return "", errors.Errorf("repository \"%s/%s\" has no default branch", owner, repo)
+	}
+	return *repository.DefaultBranch, nil
+}
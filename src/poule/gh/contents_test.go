@@ -0,0 +1,102 @@
+package gh
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"poule/configuration"
+)
+
+// makeTestServerClient returns a client for a GitHub Enterprise instance served by the handler.
+// The base URL purposely lacks the trailing slash the GitHub client requires.
+func makeTestServerClient(handler http.HandlerFunc) (Client, func()) {
+	server := httptest.NewServer(http.StripPrefix("/api/v3", handler))
+	client := MakeClient(&configuration.Config{
+		BaseURL: server.URL + "/api/v3",
+		Token:   "contents-test-token",
+	})
+	return client, server.Close
+}
+
+func TestGetFileContent(t *testing.T) {
+	client, closeServer := makeTestServerClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/icecrime/poule/contents/poule.yml":
+			if ref := r.URL.Query().Get("ref"); ref != "abc" {
+				t.Fatalf("Expected ref %q, got %q", "abc", ref)
+			}
+			fmt.Fprintf(w, `{"type": "file", "encoding": "base64", "content": %q}`, base64.StdEncoding.EncodeToString([]byte("- triggers: {}")))
+		case "/repos/icecrime/poule/contents/docs":
+			fmt.Fprint(w, `[{"type": "file", "name": "index.rst"}]`)
+		case "/repos/icecrime/poule/contents/forbidden.yml":
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message": "Forbidden"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+		}
+	})
+	defer closeServer()
+
+	content, err := GetFileContent(client, "icecrime", "poule", "poule.yml", "abc")
+	if err != nil || string(content) != "- triggers: {}" {
+		t.Fatalf("Unexpected content %q (error %v)", content, err)
+	}
+
+	// Missing files aren't an error.
+	if content, err := GetFileContent(client, "icecrime", "poule", "missing.yml", ""); err != nil || content != nil {
+		t.Fatalf("Expected nil content for missing file, got %q (error %v)", content, err)
+	}
+
+	for path, expected := range map[string]string{
+		"docs":          "is not a file",
+		"forbidden.yml": "failed to retrieve",
+	} {
+		if _, err := GetFileContent(client, "icecrime", "poule", path, ""); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("Expected error containing %q for %q, got %v", expected, path, err)
+		}
+	}
+}
+
+func TestGetDefaultBranch(t *testing.T) {
+	client, closeServer := makeTestServerClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/icecrime/poule":
+			fmt.Fprint(w, `{"full_name": "icecrime/poule", "default_branch": "main"}`)
+		case "/repos/icecrime/empty":
+			fmt.Fprint(w, `{"full_name": "icecrime/empty"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+		}
+	})
+	defer closeServer()
+
+	if branch, err := GetDefaultBranch(client, "icecrime", "poule"); err != nil || branch != "main" {
+		t.Fatalf("Expected default branch %q, got %q (error %v)", "main", branch, err)
+	}
+	for repo, expected := range map[string]string{
+		"empty":   "has no default branch",
+		"missing": "failed to retrieve",
+	} {
+		if _, err := GetDefaultBranch(client, "icecrime", repo); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("Expected error containing %q for %q, got %v", expected, repo, err)
+		}
+	}
+}
+
+func TestAPIURL(t *testing.T) {
+	for baseURL, expected := range map[string]string{
+		"":                                   defaultAPIURL,
+		"https://github.example.com/api/v3":  "https://github.example.com/api/v3/",
+		"https://github.example.com/api/v3/": "https://github.example.com/api/v3/",
+	} {
+		if actual := APIURL(&configuration.Config{BaseURL: baseURL}); actual != expected {
+			t.Fatalf("Expected API URL %q for base URL %q, got %q", expected, baseURL, actual)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package gh
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	headerETag        = "ETag"
+	headerIfNoneMatch = "If-None-Match"
+)
+
+// defaultETagCacheSize is the maximum number of responses kept by the default ETagCache.
+const defaultETagCacheSize = 1000
+
+var (
+	// repositoryPathRegexp matches the path of a single repository resource.
+	repositoryPathRegexp = regexp.MustCompile(`/repos/[^/]+/[^/]+/?$`)
+
+	// commitRefRegexp matches a commit SHA.
+	commitRefRegexp = regexp.MustCompile(`^[0-9a-fA-F]{40}$`)
+)
+
+// defaultETagCache is shared by all clients returned by MakeClient.
+var defaultETagCache = NewETagCache(IsCacheableRequest, defaultETagCacheSize)
+
+// ETagCache keeps the latest response to cacheable GET requests, and revalidates them using
+// conditional requests. Conditional requests answered with "304 Not Modified" don't count against
+// the GitHub API rate limit. The least recently used responses are evicted once the cache is full.
+type ETagCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	size    int
+
+	// cacheable returns whether responses to a request should be cached.
+	cacheable func(*http.Request) bool
+}
+
+type etagEntry struct {
+	key    string
+	etag   string
+	header http.Header
+	body   []byte
+}
+
+// NewETagCache returns a new ETagCache caching at most size responses to requests accepted by the
+// cacheable function.
+func NewETagCache(cacheable func(*http.Request) bool, size int) *ETagCache {
+	return &ETagCache{
+		entries:   map[string]*list.Element{},
+		lru:       list.New(),
+		size:      size,
+		cacheable: cacheable,
+	}
+}
+
+// IsCacheableRequest returns whether a request is for a resource which rarely changes, namely
+// repository metadata and repository contents. Contents pinned to a commit never change, but are
+// rarely requested twice and are therefore not cached.
+func IsCacheableRequest(req *http.Request) bool {
+	if commitRefRegexp.MatchString(req.URL.Query().Get("ref")) {
+		return false
+	}
+	return strings.Contains(req.URL.Path, "/contents/") || repositoryPathRegexp.MatchString(req.URL.Path)
+}
+
+// Transport returns an http.RoundTripper which serves cached responses on top of the specified base
+// transport. Cached responses are only shared between transports of the same scope, which must
+// identify the credentials of the requests.
+func (c *ETagCache) Transport(base http.RoundTripper, scope string) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &etagTransport{base: base, cache: c, scope: scope}
+}
+
+// Len returns the number of cached responses.
+func (c *ETagCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+func (c *ETagCache) get(key string) *etagEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(element)
+		return element.Value.(*etagEntry)
+	}
+	return nil
+}
+
+func (c *ETagCache) set(entry *etagEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[entry.key]; ok {
+		element.Value = entry
+		c.lru.MoveToFront(element)
+		return
+	}
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*etagEntry).key)
+	}
+}
+
+type etagTransport struct {
+	base  http.RoundTripper
+	cache *ETagCache
+	scope string
+}
+
+func (t *etagTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != "GET" || !t.cache.cacheable(req) {
+		return t.base.RoundTrip(req)
+	}
+
+	// Never share cached responses across credentials or media types.
+	digest := sha256.Sum256([]byte(t.scope + "\n" + req.Header.Get("Accept") + "\n" + req.URL.String()))
+	key := hex.EncodeToString(digest[:])
+
+	entry := t.cache.get(key)
+	if entry != nil {
+		// The original request must not be modified by a RoundTripper.
+		req = req.Clone(req.Context())
+		req.Header.Set(headerIfNoneMatch, entry.etag)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && entry != nil:
+		resp.Body.Close()
+		header := cloneHeader(entry.header)
+		for _, h := range []string{headerRateLimit, headerRateRemaining, headerRateReset} {
+			if v := resp.Header.Get(h); v != "" {
+				header.Set(h, v)
+			}
+		}
+		resp.StatusCode = http.StatusOK
+		resp.Status = "200 OK"
+		resp.Header = header
+		resp.Body = ioutil.NopCloser(bytes.NewReader(entry.body))
+		resp.ContentLength = int64(len(entry.body))
+	case resp.StatusCode == http.StatusOK && resp.Header.Get(headerETag) != "":
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		t.cache.set(&etagEntry{
+			key:    key,
+			etag:   resp.Header.Get(headerETag),
+			header: cloneHeader(resp.Header),
+			body:   body,
+		})
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	return resp, nil
+}
+
+func cloneHeader(h http.Header) http.Header {
+	c := make(http.Header, len(h))
+	for k, v := range h {
+		c[k] = append([]string(nil), v...)
+	}
+	return c
+}
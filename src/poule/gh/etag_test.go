@@ -0,0 +1,82 @@
+package gh
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestETagCache(t *testing.T) {
+	calls, notModified := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		etag := fmt.Sprintf(`"%s"`, r.Header.Get("Authorization"))
+		if r.Header.Get(headerIfNoneMatch) == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set(headerETag, etag)
+		fmt.Fprint(w, "content for "+r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	cache := NewETagCache(IsCacheableRequest, 2)
+	clients := map[string]*http.Client{
+		"a": {Transport: cache.Transport(nil, "a")},
+		"b": {Transport: cache.Transport(nil, "b")},
+	}
+	get := func(path, authorization string) string {
+		req, _ := http.NewRequest("GET", server.URL+path, nil)
+		req.Header.Set("Authorization", authorization)
+		resp, err := clients[authorization].Do(req)
+		if err != nil {
+			t.Fatalf("Get returned unexpected error %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		b, _ := ioutil.ReadAll(resp.Body)
+		return string(b)
+	}
+
+	for i := 0; i < 3; i++ {
+		if body := get("/repos/icecrime/poule/contents/poule.yml", "a"); body != "content for a" {
+			t.Fatalf("Unexpected body %q", body)
+		}
+	}
+	if notModified != 2 {
+		t.Fatalf("Expected 2 revalidated requests, got %d", notModified)
+	}
+
+	// Cached responses are never shared across scopes.
+	if body := get("/repos/icecrime/poule/contents/poule.yml", "b"); body != "content for b" {
+		t.Fatalf("Unexpected body %q", body)
+	}
+
+	// Non cacheable requests, including contents pinned to a commit, are passed through unmodified.
+	for _, path := range []string{
+		"/repos/icecrime/poule/issues",
+		"/repos/icecrime/poule/contents/poule.yml?ref=0123456789abcdef0123456789abcdef01234567",
+	} {
+		get(path, "a")
+		get(path, "a")
+	}
+	if calls != 8 || notModified != 2 {
+		t.Fatalf("Unexpected calls (%d) and revalidations (%d)", calls, notModified)
+	}
+
+	// The least recently used response is evicted once the cache is full.
+	get("/repos/icecrime/poule", "a")
+	if cache.Len() != 2 {
+		t.Fatalf("Expected 2 cached responses, got %d", cache.Len())
+	}
+	get("/repos/icecrime/poule/contents/poule.yml", "b")
+	get("/repos/icecrime/poule/contents/poule.yml", "a")
+	if calls != 11 || notModified != 3 {
+		t.Fatalf("Unexpected calls (%d) and revalidations (%d) after eviction", calls, notModified)
+	}
+}
@@ -6,7 +6,9 @@ import "github.com/google/go-github/github"
 // be able to mock it in tests.
 type Client interface {
 	Issues() IssuesService
+	Organizations() OrganizationsService
 	PullRequests() PullRequestsService
+	Reactions() ReactionsService
 	Repositories() RepositoriesService
 	Search() SearchService
 }
@@ -33,21 +35,60 @@ type IssuesService interface {
 	ListMilestones(owner string, repo string, opt *github.MilestoneListOptions) ([]*github.Milestone, *github.Response, error)
 }
 
+// OrganizationsService is the interface to the GitHub organization service.
+//go:generate mockery -name=OrganizationsService -output ../test/mocks
+type OrganizationsService interface {
+	// Members API.
+	IsMember(org, user string) (bool, *github.Response, error)
+
+	// Teams API.
+	IsTeamMember(team int, user string) (bool, *github.Response, error)
+	ListTeams(org string, opt *github.ListOptions) ([]*github.Team, *github.Response, error)
+}
+
 // PullRequestsService is the interface to the GitHub pull request service.
 //go:generate mockery -name=PullRequestsService -output ../test/mocks
 type PullRequestsService interface {
 	// Pull requests API.
 	Get(owner string, repo string, number int) (*github.PullRequest, *github.Response, error)
+	IsDraft(owner string, repo string, number int) (bool, *github.Response, error)
 	List(owner string, repo string, opt *github.PullRequestListOptions) ([]*github.PullRequest, *github.Response, error)
 	ListFiles(owner string, repo string, number int, opt *github.ListOptions) ([]*github.CommitFile, *github.Response, error)
 
+	// Review requests API.
+	ListReviewers(owner string, repo string, number int) (*Reviewers, *github.Response, error)
+	RequestReviewers(owner string, repo string, number int, reviewers ReviewersRequest) (*github.PullRequest, *github.Response, error)
+
+	// Reviews API.
+	ListReviews(owner string, repo string, number int) ([]*github.PullRequestReview, *github.Response, error)
+
 	// Commits API.
 	ListCommits(owner string, repo string, number int, opt *github.ListOptions) ([]*github.RepositoryCommit, *github.Response, error)
 }
 
+// ReactionsService is the interface to the GitHub reactions service.
+//go:generate mockery -name=ReactionsService -output ../test/mocks
+type ReactionsService interface {
+	CreateIssueCommentReaction(owner, repo string, id int, content string) (*github.Reaction, *github.Response, error)
+}
+
 // RepositoriesService is the interface to the GitHub repositories service.
 //go:generate mockery -name=RepositoriesService -output ../test/mocks
 type RepositoriesService interface {
+	// Repositories API.
+	Get(owner, repo string) (*github.Repository, *github.Response, error)
+	List(user string, opt *github.ListOptions) ([]*Repository, *github.Response, error)
+	ListByOrg(org string, opt *github.ListOptions) ([]*Repository, *github.Response, error)
+
+	// Collaborators API.
+	GetPermissionLevel(owner, repo, user string) (*RepositoryPermissionLevel, *github.Response, error)
+
+	// Contents API.
+	GetContents(owner, repo, path string, opt *github.RepositoryContentGetOptions) (*github.RepositoryContent, []*github.RepositoryContent, *github.Response, error)
+
+	// Commits API.
+	ListPullRequestsWithCommit(owner, repo, sha string, opt *github.ListOptions) ([]*github.PullRequest, *github.Response, error)
+
 	// Statuses API.
 	CreateStatus(owner, repo, ref string, sts *github.RepoStatus) (*github.RepoStatus, *github.Response, error)
 	ListStatuses(owner, repo, ref string, opt *github.ListOptions) ([]*github.RepoStatus, *github.Response, error)
@@ -1,6 +1,9 @@
 package gh
 
 import (
+	"strings"
+	"time"
+
 	"github.com/google/go-github/github"
 	"github.com/pkg/errors"
 )
@@ -8,9 +11,22 @@ import (
 // Item is a union type that can encapsulate either a github.Issue or a
 // github.PullRequest. This allows to have a single Operation interface and let
 // the implementation handle according to its capabilities.
+//
+// The Issue field of a pull request item may hold the related issue once retrieved, and accessors
+// therefore always give precedence to the PullRequest field.
 type Item struct {
 	Issue       *github.Issue
 	PullRequest *github.PullRequest
+
+	// files caches the list of files modified by a pull request once retrieved.
+	files []*github.CommitFile
+
+	// draft caches whether a pull request is a draft once retrieved.
+	draft *bool
+
+	// partial is true when the pull request was built from its related issue (e.g., a search
+	// result), and lacks attributes such as its branches until completed.
+	partial bool
 }
 
 // MakeIssueItem create an Item wrapper around a GitHub issue.
@@ -27,6 +43,31 @@ func MakePullRequestItem(pullRequest *github.PullRequest) Item {
 	}
 }
 
+// MakePartialPullRequestItem creates an Item wrapper around the issue related to a pull request,
+// such as a search result, without retrieving the pull request. Only attributes shared with the
+// issue are available until CompletePullRequest is called. The issue must hold its repository.
+func MakePartialPullRequestItem(issue *github.Issue) Item {
+	return Item{
+		Issue: issue,
+		PullRequest: &github.PullRequest{
+			Number:    issue.Number,
+			State:     issue.State,
+			Title:     issue.Title,
+			Body:      issue.Body,
+			CreatedAt: issue.CreatedAt,
+			UpdatedAt: issue.UpdatedAt,
+			ClosedAt:  issue.ClosedAt,
+			User:      issue.User,
+			Comments:  issue.Comments,
+			HTMLURL:   issue.HTMLURL,
+			Assignee:  issue.Assignee,
+			Assignees: issue.Assignees,
+			Milestone: issue.Milestone,
+		},
+		partial: true,
+	}
+}
+
 // IsNil returns true when the item is not initialized.
 func (i Item) IsNil() bool {
 	return i.Issue == nil && i.PullRequest == nil
@@ -46,13 +87,19 @@ func (i Item) IsPullRequest() bool {
 	return i.PullRequest != nil
 }
 
+// IsPartial returns whether the item is a pull request lacking the attributes which are not shared
+// with its related issue.
+func (i Item) IsPartial() bool {
+	return i.partial
+}
+
 // Assignee returns the assignee of the item.
 func (i Item) Assignee() *github.User {
 	switch {
-	case i.Issue != nil:
-		return i.Issue.Assignee
 	case i.PullRequest != nil:
 		return i.PullRequest.Assignee
+	case i.Issue != nil:
+		return i.Issue.Assignee
 	default:
 		panic("uninitialized item")
 	}
@@ -61,10 +108,10 @@ func (i Item) Assignee() *github.User {
 // Assignees returns the assignees of the item.
 func (i Item) Assignees() []*github.User {
 	switch {
-	case i.Issue != nil:
-		return i.Issue.Assignees
 	case i.PullRequest != nil:
 		return i.PullRequest.Assignees
+	case i.Issue != nil:
+		return i.Issue.Assignees
 	default:
 		panic("uninitialized item")
 	}
@@ -73,10 +120,22 @@ func (i Item) Assignees() []*github.User {
 // Body returns the text body of the item.
 func (i Item) Body() string {
 	switch {
+	case i.PullRequest != nil:
+		return *i.PullRequest.Body
 	case i.Issue != nil:
 		return *i.Issue.Body
+	default:
+		panic("uninitialized item")
+	}
+}
+
+// Milestone returns the milestone of the item.
+func (i *Item) Milestone() *github.Milestone {
+	switch {
 	case i.PullRequest != nil:
-		return *i.PullRequest.Body
+		return i.PullRequest.Milestone
+	case i.Issue != nil:
+		return i.Issue.Milestone
 	default:
 		panic("uninitialized item")
 	}
@@ -85,10 +144,10 @@ func (i Item) Body() string {
 // Number returns the number of the item.
 func (i *Item) Number() int {
 	switch {
-	case i.Issue != nil:
-		return *i.Issue.Number
 	case i.PullRequest != nil:
 		return *i.PullRequest.Number
+	case i.Issue != nil:
+		return *i.Issue.Number
 	default:
 		panic("uninitialized item")
 	}
@@ -98,22 +157,39 @@ func (i *Item) Number() int {
 // pull request, this is the destination repository.
 func (i *Item) Repository() string {
 	switch {
+	case i.PullRequest != nil && !i.partial:
+		return *i.PullRequest.Base.Repo.FullName
 	case i.Issue != nil:
 		return *i.Issue.Repository.FullName
+	default:
+		panic("uninitialized item")
+	}
+}
+
+// State returns the state of the item, which is either "open" or "closed".
+func (i *Item) State() string {
+	var state *string
+	switch {
 	case i.PullRequest != nil:
-		return *i.PullRequest.Base.Repo.FullName
+		state = i.PullRequest.State
+	case i.Issue != nil:
+		state = i.Issue.State
 	default:
 		panic("uninitialized item")
 	}
+	if state == nil {
+		return ""
+	}
+	return *state
 }
 
 // Title returns the title of the item.
 func (i *Item) Title() string {
 	switch {
-	case i.Issue != nil:
-		return *i.Issue.Title
 	case i.PullRequest != nil:
 		return *i.PullRequest.Title
+	case i.Issue != nil:
+		return *i.Issue.Title
 	default:
 		panic("uninitialized item")
 	}
@@ -122,22 +198,39 @@ func (i *Item) Title() string {
 // Type returns a string representation of the GitHub item type.
 func (i *Item) Type() string {
 	switch {
+	case i.PullRequest != nil:
+		return "pull_request"
 	case i.Issue != nil:
 		return "issue"
+	default:
+		panic("uninitialized item")
+	}
+}
+
+// UpdatedAt returns the time at which the item was last updated.
+func (i *Item) UpdatedAt() time.Time {
+	var updatedAt *time.Time
+	switch {
 	case i.PullRequest != nil:
-		return "pull_request"
+		updatedAt = i.PullRequest.UpdatedAt
+	case i.Issue != nil:
+		updatedAt = i.Issue.UpdatedAt
 	default:
 		panic("uninitialized item")
 	}
+	if updatedAt == nil {
+		return time.Time{}
+	}
+	return *updatedAt
 }
 
 // User returns the user of the item.
 func (i *Item) User() *github.User {
 	switch {
-	case i.Issue != nil:
-		return i.Issue.User
 	case i.PullRequest != nil:
 		return i.PullRequest.User
+	case i.Issue != nil:
+		return i.Issue.User
 	default:
 		panic("uninitialized item")
 	}
@@ -163,3 +256,86 @@ func (i *Item) GetRelatedIssue(client Client) (*github.Issue, error) {
 	i.Issue = issue
 	return i.Issue, nil
 }
+
+// CompletePullRequest retrieves the pull request of a partial item. It does nothing for other
+// items.
+func (i *Item) CompletePullRequest(client Client) error {
+	if !i.partial {
+		return nil
+	}
+	return i.RefreshPullRequest(client)
+}
+
+// RefreshPullRequest retrieves the complete pull request object of the item, which holds attributes
+// not returned by listings (e.g., mergeability and diff statistics). This function will fail when
+// called on a GitHub issue.
+func (i *Item) RefreshPullRequest(client Client) error {
+	if !i.IsPullRequest() {
+		return errors.Errorf("RefreshPullRequest called on an issue")
+	}
+	owner, repo := splitRepository(i.Repository())
+	pr, _, err := client.PullRequests().Get(owner, repo, i.Number())
+	if err != nil {
+		return errors.Wrapf(err, "failed to retrieve pull request #%d", i.Number())
+	}
+	i.PullRequest, i.partial = pr, false
+	return nil
+}
+
+// IsDraft retrieves and returns whether a pull request is a draft. Draft status isn't part of the
+// pull request objects of the API version in use: it is retrieved once and kept in the item. This
+// function will fail when called on a GitHub issue.
+func (i *Item) IsDraft(client Client) (bool, error) {
+	if i.draft != nil {
+		return *i.draft, nil
+	} else if !i.IsPullRequest() {
+		return false, errors.Errorf("IsDraft called on an issue")
+	}
+	owner, repo := splitRepository(i.Repository())
+	isDraft, _, err := client.PullRequests().IsDraft(owner, repo, i.Number())
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to retrieve draft status of pull request #%d", i.Number())
+	}
+	i.draft = &isDraft
+	return isDraft, nil
+}
+
+// GetFiles retrieves and returns the list of files modified by a pull request. The list is kept in
+// the item, so that it is only retrieved once for all filters and for the operation. This function
+// will fail when called on a GitHub issue.
+func (i *Item) GetFiles(client Client) ([]*github.CommitFile, error) {
+	if i.files != nil {
+		return i.files, nil
+	} else if !i.IsPullRequest() {
+		return nil, errors.Errorf("GetFiles called on an issue")
+	} else if err := i.CompletePullRequest(client); err != nil {
+		return nil, err
+	}
+
+	files := []*github.CommitFile{}
+	for page := 1; page != 0; {
+		pageFiles, resp, err := client.PullRequests().ListFiles(
+			*i.PullRequest.Base.Repo.Owner.Login,
+			*i.PullRequest.Base.Repo.Name,
+			*i.PullRequest.Number,
+			&github.ListOptions{Page: page, PerPage: 100},
+		)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, pageFiles...)
+		page = 0
+		if resp != nil {
+			page = resp.NextPage
+		}
+	}
+	i.files = files
+	return i.files, nil
+}
+
+func splitRepository(fullName string) (string, string) {
+	if i := strings.Index(fullName, "/"); i != -1 {
+		return fullName[:i], fullName[i+1:]
+	}
+	return fullName, ""
+}
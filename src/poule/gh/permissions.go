@@ -0,0 +1,155 @@
+package gh
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+// defaultPermissionCacheTTL is the duration for which permission lookups are cached.
+const defaultPermissionCacheTTL = 5 * time.Minute
+
+// PermissionCache memoizes the permission levels of users on repositories and their membership of
+// organizations and teams, which are looked up for every event triggering a restricted action and
+// by filters on users.
+type PermissionCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]permissionCacheEntry
+	swept   time.Time
+
+	// now returns the current time, overridable for testing purposes.
+	now func() time.Time
+}
+
+type permissionCacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// NewPermissionCache returns a new PermissionCache keeping lookups for the specified duration, or
+// for a default duration when zero.
+func NewPermissionCache(ttl time.Duration) *PermissionCache {
+	if ttl == 0 {
+		ttl = defaultPermissionCacheTTL
+	}
+	return &PermissionCache{
+		ttl:     ttl,
+		entries: map[string]permissionCacheEntry{},
+		now:     time.Now,
+	}
+}
+
+// PermissionLevel returns the permission level of the user on the repository (e.g., "write"), and
+// the name of the user's role which may be more precise (e.g., "maintain") or empty.
+func (c *PermissionCache) PermissionLevel(client Client, owner, repo, user string) (string, string, error) {
+	v, err := c.Lookup(fmt.Sprintf("permission:%s/%s:%s", strings.ToLower(owner), strings.ToLower(repo), strings.ToLower(user)), func() (interface{}, error) {
+		level, _, err := client.Repositories().GetPermissionLevel(owner, repo, user)
+		if err != nil {
+			return nil, err
+		}
+		result := [2]string{"none", ""}
+		if level != nil && level.Permission != nil {
+			result[0] = *level.Permission
+		}
+		if level != nil && level.RoleName != nil {
+			result[1] = *level.RoleName
+		}
+		return result, nil
+	})
+	if err != nil {
+		return "", "", errors.Wrapf(err, "failed to retrieve permission of %q on \"%s/%s\"", user, owner, repo)
+	}
+	level := v.([2]string)
+	return level[0], level[1], nil
+}
+
+// IsMember returns whether the user is a member of the organization.
+func (c *PermissionCache) IsMember(client Client, org, user string) (bool, error) {
+	v, err := c.Lookup(fmt.Sprintf("member:%s:%s", strings.ToLower(org), strings.ToLower(user)), func() (interface{}, error) {
+		isMember, _, err := client.Organizations().IsMember(org, user)
+		return isMember, err
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to check membership of %q in organization %q", user, org)
+	}
+	return v.(bool), nil
+}
+
+// IsTeamMember returns whether the user is a member of the team designated by its slug in the
+// organization.
+func (c *PermissionCache) IsTeamMember(client Client, org, team, user string) (bool, error) {
+	id, err := c.Lookup(fmt.Sprintf("team:%s/%s", strings.ToLower(org), strings.ToLower(team)), func() (interface{}, error) {
+		return findTeamID(client, org, team)
+	})
+	if err != nil {
+		return false, err
+	}
+	v, err := c.Lookup(fmt.Sprintf("team-member:%d:%s", id, strings.ToLower(user)), func() (interface{}, error) {
+		isMember, _, err := client.Organizations().IsTeamMember(id.(int), user)
+		return isMember, err
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to check membership of %q in team \"%s/%s\"", user, org, team)
+	}
+	return v.(bool), nil
+}
+
+// Lookup returns the cached value for the specified key unless expired, or calls fn and caches its
+// result. Errors aren't cached. Expired entries are removed on lookup, and all of them at most once
+// per cache duration so that the entries of users who are never looked up again don't accumulate.
+func (c *PermissionCache) Lookup(key string, fn func() (interface{}, error)) (interface{}, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok && !c.now().Before(entry.expires) {
+		delete(c.entries, key)
+		ok = false
+	}
+	c.mu.Unlock()
+	if ok {
+		return entry.value, nil
+	}
+	v, err := fn()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if now.Sub(c.swept) >= c.ttl {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		c.swept = now
+	}
+	c.entries[key] = permissionCacheEntry{value: v, expires: now.Add(c.ttl)}
+	return v, nil
+}
+
+// findTeamID returns the identifier of the team with the specified slug in an organization.
+func findTeamID(client Client, org, slug string) (int, error) {
+	options := &github.ListOptions{PerPage: 100}
+	for options.Page = 1; options.Page != 0; {
+		teams, resp, err := client.Organizations().ListTeams(org, options)
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to list teams of organization %q", org)
+		}
+		for _, team := range teams {
+			if team.Slug != nil && team.ID != nil && strings.EqualFold(*team.Slug, slug) {
+				return *team.ID, nil
+			}
+		}
+		if resp == nil {
+			break
+		}
+		options.Page = resp.NextPage
+	}
+	return 0, errors.Errorf("unknown team \"%s/%s\"", org, slug)
+}
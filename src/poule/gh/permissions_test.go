@@ -0,0 +1,41 @@
+package gh
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPermissionCacheExpiry(t *testing.T) {
+	now := time.Now()
+	cache := NewPermissionCache(time.Minute)
+	cache.now = func() time.Time { return now }
+
+	calls := 0
+	lookup := func(key string) {
+		if _, err := cache.Lookup(key, func() (interface{}, error) {
+			calls++
+			return true, nil
+		}); err != nil {
+			t.Fatalf("Lookup returned unexpected error %v", err)
+		}
+	}
+
+	// Values are cached until they expire.
+	lookup("first")
+	lookup("first")
+	if calls != 1 {
+		t.Fatalf("Expected a single call before expiry, got %d", calls)
+	}
+	now = now.Add(time.Minute)
+	lookup("first")
+	if calls != 2 {
+		t.Fatalf("Expected expired value to be looked up again, got %d calls", calls)
+	}
+
+	// Expired entries are removed, even when their key is never looked up again.
+	now = now.Add(time.Minute)
+	lookup("second")
+	if len(cache.entries) != 1 {
+		t.Fatalf("Expected expired entries to be removed, got %v", cache.entries)
+	}
+}
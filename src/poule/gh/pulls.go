@@ -0,0 +1,76 @@
+package gh
+
+import (
+	"fmt"
+
+	"github.com/google/go-github/github"
+)
+
+// mediaTypeDraftPreview is required by GitHub Enterprise versions on which draft pull requests
+// are still a preview feature.
+const mediaTypeDraftPreview = "application/vnd.github.shadow-cat-preview+json"
+
+// Reviewers is the set of users and teams requested to review a pull request.
+type Reviewers struct {
+	Users []*github.User `json:"users,omitempty"`
+	Teams []*github.Team `json:"teams,omitempty"`
+}
+
+// ReviewersRequest designates the users (by login) and teams (by slug) to request a review from.
+type ReviewersRequest struct {
+	Reviewers     []string `json:"reviewers,omitempty"`
+	TeamReviewers []string `json:"team_reviewers,omitempty"`
+}
+
+// pullRequestsService extends the go-github pull request service with the features it lacks.
+type pullRequestsService struct {
+	*github.PullRequestsService
+	client *github.Client
+}
+
+// IsDraft returns whether the specified pull request is a draft.
+func (s pullRequestsService) IsDraft(owner string, repo string, number int) (bool, *github.Response, error) {
+	req, err := s.client.NewRequest("GET", fmt.Sprintf("repos/%v/%v/pulls/%d", owner, repo, number), nil)
+	if err != nil {
+		return false, nil, err
+	}
+	req.Header.Set("Accept", mediaTypeDraftPreview)
+
+	var pr struct {
+		Draft *bool `json:"draft"`
+	}
+	resp, err := s.client.Do(req, &pr)
+	if err != nil {
+		return false, resp, err
+	}
+	return pr.Draft != nil && *pr.Draft, resp, nil
+}
+
+// ListReviewers returns the users and teams which were requested to review the specified pull
+// request and haven't submitted a review yet.
+func (s pullRequestsService) ListReviewers(owner string, repo string, number int) (*Reviewers, *github.Response, error) {
+	req, err := s.client.NewRequest("GET", fmt.Sprintf("repos/%v/%v/pulls/%d/requested_reviewers", owner, repo, number), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	reviewers := &Reviewers{}
+	resp, err := s.client.Do(req, reviewers)
+	if err != nil {
+		return nil, resp, err
+	}
+	return reviewers, resp, nil
+}
+
+// RequestReviewers requests reviews on the specified pull request from users and teams.
+func (s pullRequestsService) RequestReviewers(owner string, repo string, number int, reviewers ReviewersRequest) (*github.PullRequest, *github.Response, error) {
+	req, err := s.client.NewRequest("POST", fmt.Sprintf("repos/%v/%v/pulls/%d/requested_reviewers", owner, repo, number), &reviewers)
+	if err != nil {
+		return nil, nil, err
+	}
+	pr := &github.PullRequest{}
+	resp, err := s.client.Do(req, pr)
+	if err != nil {
+		return nil, resp, err
+	}
+	return pr, resp, nil
+}
@@ -0,0 +1,266 @@
+package gh
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+const (
+	headerRateLimit     = "X-RateLimit-Limit"
+	headerRateRemaining = "X-RateLimit-Remaining"
+	headerRateReset     = "X-RateLimit-Reset"
+	headerRateResource  = "X-RateLimit-Resource"
+	headerRetryAfter    = "Retry-After"
+
+	// defaultMaxRetries is the number of times a request is retried on secondary rate limits and
+	// server errors.
+	defaultMaxRetries = 5
+
+	// initialBackoff and maxBackoff bound the exponential backoff between retries.
+	initialBackoff = time.Second
+	maxBackoff     = time.Minute
+
+	// coreResource and searchResource are the rate limited resources of the GitHub API which are
+	// tracked: the search API has a distinct and much lower limit than the core API.
+	coreResource   = "core"
+	searchResource = "search"
+)
+
+// Budget is the state of the GitHub API rate limit as reported by the latest API response.
+type Budget struct {
+	// Limit is the number of requests per hour (per minute for the search API) the client is
+	// currently limited to.
+	Limit int
+
+	// Remaining is the number of remaining requests the client can make until Reset.
+	Remaining int
+
+	// Reset is the time at which the current rate limit will reset.
+	Reset time.Time
+}
+
+// RateLimiter keeps track of the GitHub API rate limits for a given set of credentials, pauses
+// requests before the limit of their resource is exhausted, and retries requests which failed
+// because of secondary rate limits or server errors.
+type RateLimiter struct {
+	mu      sync.Mutex
+	budgets map[string]Budget
+
+	// MaxRetries is the number of times a request is retried before giving up.
+	MaxRetries int
+
+	// sleep is the function used to pause, overridable for testing purposes.
+	sleep func(time.Duration)
+}
+
+// NewRateLimiter returns a new RateLimiter instance.
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		budgets:    map[string]Budget{},
+		MaxRetries: defaultMaxRetries,
+		sleep:      time.Sleep,
+	}
+}
+
+var (
+	rateLimitersLock sync.Mutex
+	rateLimiters     = map[string]*RateLimiter{}
+)
+
+// RateLimiterFor returns the RateLimiter shared by all clients using the specified token.
+func RateLimiterFor(token string) *RateLimiter {
+	rateLimitersLock.Lock()
+	defer rateLimitersLock.Unlock()
+	if l, ok := rateLimiters[token]; ok {
+		return l
+	}
+	l := NewRateLimiter()
+	rateLimiters[token] = l
+	return l
+}
+
+// Budget returns the current rate limit budget of the core API, and whether it is known (i.e.,
+// whether any API response was received yet).
+func (l *RateLimiter) Budget() (Budget, bool) {
+	return l.resourceBudget(coreResource)
+}
+
+func (l *RateLimiter) resourceBudget(resource string) (Budget, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	budget, known := l.budgets[resource]
+	return budget, known
+}
+
+// Wait blocks until more than `reserve` requests remain in the budget of the core API, or until
+// the rate limit is reset. It returns immediately when the budget is unknown.
+func (l *RateLimiter) Wait(reserve int) {
+	l.wait(coreResource, reserve)
+}
+
+func (l *RateLimiter) wait(resource string, reserve int) {
+	budget, known := l.resourceBudget(resource)
+	if !known || budget.Remaining > reserve {
+		return
+	}
+	if d := time.Until(budget.Reset); d > 0 {
+		logrus.WithFields(logrus.Fields{
+			"remaining": budget.Remaining,
+			"reset":     budget.Reset.Format(time.RFC3339),
+			"resource":  resource,
+		}).Warnf("GitHub API rate limit almost exhausted: pausing for %s", d)
+		l.sleep(d)
+	}
+}
+
+// Transport returns an http.RoundTripper which applies the rate limiting policy on top of the
+// specified base transport.
+func (l *RateLimiter) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &rateLimitedTransport{base: base, limiter: l}
+}
+
+func (l *RateLimiter) update(resp *http.Response) {
+	// Each resource has its own budget, so that the much lower limit of the search API doesn't
+	// pause unrelated requests. Other resources aren't tracked.
+	resource := resp.Header.Get(headerRateResource)
+	if resource == "" {
+		resource = coreResource
+	}
+	if resource != coreResource && resource != searchResource {
+		return
+	}
+	remaining, err := strconv.Atoi(resp.Header.Get(headerRateRemaining))
+	if err != nil {
+		return
+	}
+	limit, _ := strconv.Atoi(resp.Header.Get(headerRateLimit))
+	reset, _ := strconv.ParseInt(resp.Header.Get(headerRateReset), 10, 64)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.budgets[resource] = Budget{
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     time.Unix(reset, 0),
+	}
+}
+
+// requestResource returns the rate limited resource a request is accounted against.
+func requestResource(req *http.Request) string {
+	if strings.HasPrefix(req.URL.Path, "/search/") || strings.Contains(req.URL.Path, "/api/v3/search/") {
+		return searchResource
+	}
+	return coreResource
+}
+
+type rateLimitedTransport struct {
+	base    http.RoundTripper
+	limiter *RateLimiter
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		// Pause before the budget is entirely exhausted.
+		t.limiter.wait(requestResource(req), 0)
+
+		// Requests bodies are consumed by each attempt and need to be rewound.
+		if attempt > 0 && req.Body != nil && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		t.limiter.update(resp)
+
+		delay, retry := t.retryDelay(req, resp, attempt)
+		if !retry || attempt >= t.limiter.MaxRetries || (req.Body != nil && req.GetBody == nil) {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		logrus.WithFields(logrus.Fields{
+			"attempt": attempt + 1,
+			"status":  resp.StatusCode,
+			"url":     req.URL.String(),
+		}).Warnf("GitHub API request failed: retrying in %s", delay)
+		t.limiter.sleep(delay)
+	}
+}
+
+// retryDelay returns whether the request which produced the specified response should be retried,
+// and how long to wait before doing so.
+//
+// Rate limited requests were rejected before being processed and are always retried. Server errors
+// on the other hand may happen after the request was processed, so only idempotent requests are
+// retried: retrying a POST could for example post the same comment twice.
+func (t *rateLimitedTransport) retryDelay(req *http.Request, resp *http.Response, attempt int) (time.Duration, bool) {
+	switch {
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return backoff(attempt), isIdempotent(req.Method)
+	case resp.StatusCode == http.StatusForbidden:
+		// Secondary rate limits come with a Retry-After header.
+		if retryAfter, err := strconv.Atoi(resp.Header.Get(headerRetryAfter)); err == nil {
+			return time.Duration(retryAfter) * time.Second, true
+		}
+		// Primary rate limit exhaustion: wait for the reset.
+		if resp.Header.Get(headerRateRemaining) == "0" {
+			reset, _ := strconv.ParseInt(resp.Header.Get(headerRateReset), 10, 64)
+			if d := time.Until(time.Unix(reset, 0)); d > 0 {
+				return d, true
+			}
+			return backoff(attempt), true
+		}
+		// Secondary rate limits may come without a Retry-After header, in which case the only
+		// indication lies in the response body.
+		if isAbuseResponse(resp) {
+			return backoff(attempt), true
+		}
+	}
+	return 0, false
+}
+
+// isIdempotent returns whether requests with the specified method can safely be sent twice.
+func isIdempotent(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isAbuseResponse returns whether the response body indicates that a secondary rate limit was
+// triggered. The body is restored so that it can be read again by the caller.
+func isAbuseResponse(resp *http.Response) bool {
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	message := strings.ToLower(string(body))
+	return strings.Contains(message, "abuse") || strings.Contains(message, "secondary rate limit")
+}
+
+func backoff(attempt int) time.Duration {
+	d := initialBackoff << uint(attempt)
+	if d > maxBackoff || d <= 0 {
+		return maxBackoff
+	}
+	return d
+}
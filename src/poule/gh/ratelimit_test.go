@@ -0,0 +1,159 @@
+package gh
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func makeTestRateLimiter(delays *[]time.Duration) *RateLimiter {
+	l := NewRateLimiter()
+	l.sleep = func(d time.Duration) {
+		*delays = append(*delays, d)
+	}
+	return l
+}
+
+func TestRateLimiterRetries(t *testing.T) {
+	responses := []func(w http.ResponseWriter){
+		func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusBadGateway)
+		},
+		func(w http.ResponseWriter) {
+			w.Header().Set(headerRetryAfter, "30")
+			w.WriteHeader(http.StatusForbidden)
+		},
+		func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message": "You have triggered an abuse detection mechanism."}`)
+		},
+		func(w http.ResponseWriter) {
+			w.Header().Set(headerRateLimit, "5000")
+			w.Header().Set(headerRateRemaining, "4999")
+			w.Header().Set(headerRateReset, strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+			w.WriteHeader(http.StatusOK)
+		},
+	}
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		responses[calls](w)
+		calls++
+	}))
+	defer server.Close()
+
+	var delays []time.Duration
+	l := makeTestRateLimiter(&delays)
+	client := &http.Client{Transport: l.Transport(nil)}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get returned unexpected error %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected final status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	expected := []time.Duration{initialBackoff, 30 * time.Second, 4 * initialBackoff}
+	if fmt.Sprint(delays) != fmt.Sprint(expected) {
+		t.Fatalf("Expected delays %v, got %v", expected, delays)
+	}
+	if budget, known := l.Budget(); !known || budget.Limit != 5000 || budget.Remaining != 4999 {
+		t.Fatalf("Unexpected budget %v", budget)
+	}
+}
+
+func TestRateLimiterRetriesNonIdempotentRequests(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set(headerRetryAfter, "30")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	// Rate limited POST requests are retried, but server errors are returned as is as the request
+	// may have been processed.
+	var delays []time.Duration
+	l := makeTestRateLimiter(&delays)
+	client := &http.Client{Transport: l.Transport(nil)}
+	resp, err := client.Post(server.URL, "application/json", strings.NewReader(`{"body": "Hello"}`))
+	if err != nil {
+		t.Fatalf("Post returned unexpected error %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadGateway || calls != 2 {
+		t.Fatalf("Expected status %d after 2 calls, got %d after %d calls", http.StatusBadGateway, resp.StatusCode, calls)
+	}
+	if expected := []time.Duration{30 * time.Second}; fmt.Sprint(delays) != fmt.Sprint(expected) {
+		t.Fatalf("Expected delays %v, got %v", expected, delays)
+	}
+}
+
+func TestRateLimiterPausesBeforeExhaustion(t *testing.T) {
+	reset := time.Now().Add(time.Hour)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headerRateLimit, "5000")
+		w.Header().Set(headerRateRemaining, "0")
+		w.Header().Set(headerRateReset, strconv.FormatInt(reset.Unix(), 10))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var delays []time.Duration
+	l := makeTestRateLimiter(&delays)
+	client := &http.Client{Transport: l.Transport(nil)}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Get returned unexpected error %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	// The first request is made with an unknown budget, the second must wait for the reset.
+	if len(delays) != 1 || delays[0] <= 0 || delays[0] > time.Hour {
+		t.Fatalf("Unexpected delays %v", delays)
+	}
+}
+
+func TestRateLimiterTracksSearchSeparately(t *testing.T) {
+	reset := time.Now().Add(time.Minute)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/search/") {
+			w.Header().Set(headerRateLimit, "30")
+			w.Header().Set(headerRateRemaining, "0")
+			w.Header().Set(headerRateReset, strconv.FormatInt(reset.Unix(), 10))
+			w.Header().Set(headerRateResource, "search")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var delays []time.Duration
+	l := makeTestRateLimiter(&delays)
+	client := &http.Client{Transport: l.Transport(nil)}
+	for _, path := range []string{"/search/issues", "/repos/icecrime/poule/issues", "/search/issues"} {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Get returned unexpected error %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	// The exhausted search budget only pauses search requests, and doesn't affect the core budget.
+	if _, known := l.Budget(); known {
+		t.Fatalf("Unexpected core budget update from search response")
+	}
+	if len(delays) != 1 || delays[0] <= 0 || delays[0] > time.Minute {
+		t.Fatalf("Expected a single pause before the second search request, got delays %v", delays)
+	}
+}
@@ -0,0 +1,94 @@
+package gh
+
+import (
+	"fmt"
+
+	"github.com/google/go-github/github"
+)
+
+// mediaTypeCommitPullsPreview is required to list the pull requests associated with a commit.
+const mediaTypeCommitPullsPreview = "application/vnd.github.groot-preview+json"
+
+// Repository is a GitHub repository, with the fields the go-github repository lacks.
+type Repository struct {
+	github.Repository
+	Archived *bool `json:"archived,omitempty"`
+}
+
+// repositoriesService extends the go-github repositories service with the features it lacks.
+type repositoriesService struct {
+	*github.RepositoriesService
+	client *github.Client
+}
+
+// List returns the repositories of the specified user.
+func (s repositoriesService) List(user string, opt *github.ListOptions) ([]*Repository, *github.Response, error) {
+	return s.listRepositories(fmt.Sprintf("users/%v/repos", user), opt)
+}
+
+// ListByOrg returns the repositories of the specified organization.
+func (s repositoriesService) ListByOrg(org string, opt *github.ListOptions) ([]*Repository, *github.Response, error) {
+	return s.listRepositories(fmt.Sprintf("orgs/%v/repos", org), opt)
+}
+
+func (s repositoriesService) listRepositories(u string, opt *github.ListOptions) ([]*Repository, *github.Response, error) {
+	if opt != nil {
+		u = fmt.Sprintf("%s?page=%d&per_page=%d", u, opt.Page, opt.PerPage)
+	}
+	req, err := s.client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var repos []*Repository
+	resp, err := s.client.Do(req, &repos)
+	if err != nil {
+		return nil, resp, err
+	}
+	return repos, resp, nil
+}
+
+// ListPullRequestsWithCommit returns the pull requests which contain the specified commit.
+func (s repositoriesService) ListPullRequestsWithCommit(owner, repo, sha string, opt *github.ListOptions) ([]*github.PullRequest, *github.Response, error) {
+	u := fmt.Sprintf("repos/%v/%v/commits/%v/pulls", owner, repo, sha)
+	if opt != nil {
+		u = fmt.Sprintf("%s?page=%d&per_page=%d", u, opt.Page, opt.PerPage)
+	}
+	req, err := s.client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", mediaTypeCommitPullsPreview)
+
+	var pulls []*github.PullRequest
+	resp, err := s.client.Do(req, &pulls)
+	if err != nil {
+		return nil, resp, err
+	}
+	return pulls, resp, nil
+}
+
+// RepositoryPermissionLevel is the permission level of a user on a repository, along with the name
+// of the user's role.
+type RepositoryPermissionLevel struct {
+	github.RepositoryPermissionLevel
+	RoleName *string `json:"role_name,omitempty"`
+}
+
+// GetPermissionLevel returns the permission level of a user on the repository. The permission
+// reported by GitHub maps the "triage" and "maintain" roles to "read" and "write" respectively:
+// the role name is returned as well for callers to rely on when they know of it.
+func (s repositoriesService) GetPermissionLevel(owner, repo, user string) (*RepositoryPermissionLevel, *github.Response, error) {
+	u := fmt.Sprintf("repos/%v/%v/collaborators/%v/permission", owner, repo, user)
+	req, err := s.client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	level := &RepositoryPermissionLevel{}
+	resp, err := s.client.Do(req, level)
+	if err != nil {
+		return nil, resp, err
+	}
+	return level, resp, nil
+}
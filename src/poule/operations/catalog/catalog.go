@@ -1,9 +1,12 @@
 package catalog
 
 import (
+	"flag"
+	"io/ioutil"
 	"sort"
 
 	"poule/operations"
+	"poule/operations/settings"
 
 	"github.com/urfave/cli"
 )
@@ -65,3 +68,28 @@ func registerOperation(descriptor OperationDescriptor) {
 	ByNameIndex[descriptor.CommandLineDescription().Name] = descriptor
 	sort.Sort(Index)
 }
+
+// OperationFlags returns the command-line flags of the specified operation, including the filtering
+// flag supported by every operation.
+func OperationFlags(descriptor OperationDescriptor) []cli.Flag {
+	return append(descriptor.CommandLineDescription().Flags, settings.FilteringFlag)
+}
+
+// ParseOperationArguments parses the command-line arguments of an operation, and returns the
+// corresponding context. The parent context may be nil when the arguments don't originate from the
+// command line (e.g., for commands in issue comments).
+func ParseOperationArguments(parent *cli.Context, descriptor OperationDescriptor, args []string) (*cli.Context, error) {
+	set := flag.NewFlagSet(descriptor.CommandLineDescription().Name, flag.ContinueOnError)
+	set.SetOutput(ioutil.Discard)
+	for _, f := range OperationFlags(descriptor) {
+		f.Apply(set)
+	}
+	if err := set.Parse(args); err != nil {
+		return nil, err
+	}
+	var app *cli.App
+	if parent != nil {
+		app = parent.App
+	}
+	return cli.NewContext(app, set, parent), nil
+}
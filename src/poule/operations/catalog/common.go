@@ -1,14 +1,53 @@
 package catalog
 
 import (
+	"fmt"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"poule/operations"
+	"poule/runner/state"
 
 	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
 )
 
+// hasAutomatedComment returns whether the pull request has an automated comment containing the
+// specified token. The state store is queried first, and comments are only listed unless it knows
+// there is none: a comment recorded as posted may have been deleted manually since.
+func hasAutomatedComment(c *operations.Context, pr *github.PullRequest, token string) (bool, error) {
+	if present, known, err := recordedAutomatedComment(c, pr, token); err != nil {
+		return false, err
+	} else if known && !present {
+		return false, nil
+	}
+	automatedComments, err := findAutomatedComments(c, pr, token)
+	if err != nil {
+		return false, err
+	}
+	return len(automatedComments) != 0, nil
+}
+
+// createAutomatedComment posts the automated comment containing the specified token, and records
+// it in the state store.
+func createAutomatedComment(c *operations.Context, pr *github.PullRequest, token, content string) error {
+	comment := &github.IssueComment{Body: &content}
+	if _, _, err := c.Client.Issues().CreateComment(c.Username, c.Repository, *pr.Number, comment); err != nil {
+		return err
+	}
+	return recordAutomatedComment(c, pr, token, true)
+}
+
 func deleteAutomatedComments(c *operations.Context, pr *github.PullRequest, substr string) error {
+	// Comments don't need to be listed when the state store knows there is none.
+	if present, known, err := recordedAutomatedComment(c, pr, substr); err != nil {
+		return err
+	} else if known && !present {
+		return nil
+	}
+
 	automatedComments, err := findAutomatedComments(c, pr, substr)
 	if err != nil {
 		return err
@@ -18,7 +57,7 @@ func deleteAutomatedComments(c *operations.Context, pr *github.PullRequest, subs
 			return err
 		}
 	}
-	return nil
+	return recordAutomatedComment(c, pr, substr, false)
 }
 
 func findAutomatedComments(c *operations.Context, pr *github.PullRequest, substr string) ([]*github.IssueComment, error) {
@@ -53,3 +92,117 @@ func findAutomatedComments(c *operations.Context, pr *github.PullRequest, substr
 	}
 	return automatedComments, nil
 }
+
+// recordedAutomatedComment returns whether the state store recorded the automated comment
+// containing the specified token as present, and whether it has any record of it at all.
+func recordedAutomatedComment(c *operations.Context, pr *github.PullRequest, token string) (present, known bool, err error) {
+	if c.State == nil {
+		return false, false, nil
+	}
+	record, err := state.Last(c.State, c.Username+"/"+c.Repository, *pr.Number, automatedCommentRecordName(token))
+	if err != nil || record == nil {
+		return false, false, err
+	}
+	return record.Digest == state.Digest(true), true, nil
+}
+
+// recordAutomatedComment records the presence or absence of the automated comment containing the
+// specified token.
+func recordAutomatedComment(c *operations.Context, pr *github.PullRequest, token string, present bool) error {
+	if c.State == nil {
+		return nil
+	}
+	return c.State.Add(state.Record{
+		Repository: c.Username + "/" + c.Repository,
+		Number:     *pr.Number,
+		Operation:  automatedCommentRecordName(token),
+		Digest:     state.Digest(present),
+		Time:       time.Now(),
+	})
+}
+
+// automatedCommentRecordName returns the name under which the automated comment containing the
+// specified token is recorded.
+func automatedCommentRecordName(token string) string {
+	return "comment:" + token
+}
+
+// loadCounter counts the items of a repository matching a per-user search qualifier (e.g.,
+// "is:open assignee:%s"), and accounts for the items the operation selects users for.
+type loadCounter struct {
+	qualifier string
+
+	sync.Mutex
+	loads map[string]int
+}
+
+func newLoadCounter(qualifier string) *loadCounter {
+	return &loadCounter{
+		qualifier: qualifier,
+		loads:     map[string]int{},
+	}
+}
+
+// pickLeastLoaded selects at most n candidates with the lowest load, and accounts for the new items
+// so that subsequent selections are balanced accordingly. Ties are resolved in the order of the
+// candidates.
+func (l *loadCounter) pickLeastLoaded(c *operations.Context, candidates []string, n int) ([]string, error) {
+	queries := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		query, err := l.load(c, candidate)
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, query)
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	indices := make([]int, len(candidates))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		return l.loads[queries[indices[i]]] < l.loads[queries[indices[j]]]
+	})
+	if len(indices) > n {
+		indices = indices[:n]
+	}
+	picked := make([]string, 0, len(indices))
+	for _, i := range indices {
+		l.loads[queries[i]]++
+		picked = append(picked, candidates[i])
+	}
+	return picked, nil
+}
+
+// load retrieves the load of the user in the repository unless already known, and returns the
+// query it is recorded under. The lock isn't held during the search, and the first result recorded
+// wins should concurrent lookups of the same user happen.
+func (l *loadCounter) load(c *operations.Context, user string) (string, error) {
+	query := fmt.Sprintf("repo:%s/%s "+l.qualifier, c.Username, c.Repository, user)
+	l.Lock()
+	_, ok := l.loads[query]
+	l.Unlock()
+	if ok {
+		return query, nil
+	}
+
+	result, _, err := c.Client.Search().Issues(query, &github.SearchOptions{
+		ListOptions: github.ListOptions{PerPage: 1},
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to count open items of %q", user)
+	}
+	load := 0
+	if result.Total != nil {
+		load = *result.Total
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	if _, ok := l.loads[query]; !ok {
+		l.loads[query] = load
+	}
+	return query, nil
+}
@@ -3,6 +3,7 @@ package catalog
 import (
 	"poule/configuration"
 	"poule/operations"
+	"poule/operations/settings"
 
 	"github.com/pkg/errors"
 )
@@ -26,6 +27,9 @@ type OperationValidator struct{}
 
 // Validate verifies the validity of the configuration object.
 func (o OperationValidator) Validate(operationConfig *configuration.OperationConfiguration) error {
+	if _, err := settings.ParseConfigurationFilters(operationConfig.Filters); err != nil {
+		return errors.Wrapf(err, "invalid filters for operation %q", operationConfig.Type)
+	}
 	_, err := OperationFromConfig(operationConfig)
 	return err
 }
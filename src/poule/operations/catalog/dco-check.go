@@ -85,7 +85,7 @@ func (o *dcoCheckOperation) applySigned(c *operations.Context, pr *github.PullRe
 	}
 
 	// Delete the automated DCO comment (if any).
-	if err := deleteAutomatedComments(c, pr, pouleValidationCommentToken); err != nil {
+	if err := deleteAutomatedComments(c, pr, dcoCommentToken); err != nil {
 		return err
 	}
 
@@ -105,16 +105,14 @@ func (o *dcoCheckOperation) applyUnsigned(c *operations.Context, pr *github.Pull
 	}
 
 	// Create the automated comment for that pull request, unless there is already one.
-	if automatedComments, err := findAutomatedComments(c, pr, dcoCommentToken); err != nil {
+	if exists, err := hasAutomatedComment(c, pr, dcoCommentToken); err != nil {
 		return err
-	} else if len(automatedComments) != 0 {
+	} else if exists {
 		return nil
 	}
 
 	// Create the automated comment.
-	content := formatDCOComment(c, pr)
-	comment := &github.IssueComment{Body: &content}
-	if _, _, err := c.Client.Issues().CreateComment(c.Username, c.Repository, *pr.Number, comment); err != nil {
+	if err := createAutomatedComment(c, pr, dcoCommentToken, formatDCOComment(c, pr)); err != nil {
 		return err
 	}
 
@@ -9,6 +9,7 @@ import (
 
 	"poule/gh"
 	"poule/operations"
+	"poule/runner/state"
 	"poule/test"
 
 	"github.com/google/go-github/github"
@@ -212,3 +213,56 @@ func TestDCOSuccessLabelMissing(t *testing.T) {
 
 	dcoTestStub(t, ctx, item)
 }
+
+func TestDCOFromState(t *testing.T) {
+	clt, ctx := makeContext()
+	item := test.NewPullRequestBuilder(test.IssueNumber).
+		BaseBranch(ctx.Username, ctx.Repository, "base", "0x123").
+		HeadBranch(ctx.Username, ctx.Repository, "head", "0x456").
+		Commits(1).
+		Item()
+
+	// The explanation comment was recorded but deleted manually since: it is posted again.
+	ctx.State = state.NewMemoryStore()
+	recordAutomatedComment(ctx, item.PullRequest, dcoCommentToken, true)
+	clt.MockIssues.
+		On("AddLabelsToIssue", ctx.Username, ctx.Repository, test.IssueNumber, []string{testDCOFailureLabel}).
+		Return([]*github.Label{}, nil, nil)
+	clt.MockIssues.
+		On("ListComments", ctx.Username, ctx.Repository, test.IssueNumber, mock.AnythingOfType("*github.IssueListCommentsOptions")).
+		Return([]*github.IssueComment{}, &github.Response{NextPage: 0}, nil).Once()
+	clt.MockIssues.
+		On("CreateComment", ctx.Username, ctx.Repository, test.IssueNumber, mock.AnythingOfType("*github.IssueComment")).
+		Return(nil, nil, nil).Once()
+	clt.MockRepositories.
+		On("CreateStatus", ctx.Username, ctx.Repository, "0x456", mock.AnythingOfType("*github.RepoStatus")).
+		Return(nil, nil, nil)
+	clt.MockPullRequests.
+		On("ListCommits", ctx.Username, ctx.Repository, test.IssueNumber, mock.AnythingOfType("*github.ListOptions")).
+		Return([]*github.RepositoryCommit{
+			{Commit: &github.Commit{Message: github.String("Commit message")}},
+		}, nil, nil).Once()
+	dcoTestStub(t, ctx, item)
+
+	// Once the comment is deleted, the store knows there is nothing left to delete.
+	clt.MockIssues.
+		On("RemoveLabelForIssue", ctx.Username, ctx.Repository, test.IssueNumber, testDCOFailureLabel).
+		Return(nil, nil)
+	clt.MockIssues.
+		On("ListComments", ctx.Username, ctx.Repository, test.IssueNumber, mock.AnythingOfType("*github.IssueListCommentsOptions")).
+		Return([]*github.IssueComment{
+			{ID: github.Int(test.CommentID), Body: github.String(dcoCommentToken)},
+		}, &github.Response{NextPage: 0}, nil).Once()
+	clt.MockIssues.
+		On("DeleteComment", ctx.Username, ctx.Repository, test.CommentID).
+		Return(nil, nil).Once()
+	clt.MockPullRequests.
+		On("ListCommits", ctx.Username, ctx.Repository, test.IssueNumber, mock.AnythingOfType("*github.ListOptions")).
+		Return([]*github.RepositoryCommit{
+			{Commit: &github.Commit{Message: github.String("Signed-off-by: Arnaud Porterie (icecrime) <arnaud.porterie@docker.com>")}},
+		}, nil, nil)
+	for i := 0; i < 2; i++ {
+		dcoTestStub(t, ctx, item)
+	}
+	test.AssertExpectations(clt, t)
+}
@@ -0,0 +1,375 @@
+package catalog
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"poule/gh"
+	"poule/operations"
+	"poule/operations/settings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/google/go-github/github"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+// codeOwnersLocations are the paths where GitHub looks for a CODEOWNERS file, in order.
+var codeOwnersLocations = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+func init() {
+	registerOperation(&ownersAssignDescriptor{})
+}
+
+type ownersAssignConfig struct {
+	Assign    bool     `mapstructure:"assign"`
+	Owners    []string `mapstructure:"owners"`
+	Reviewers int      `mapstructure:"reviewers"`
+}
+
+type ownersAssignDescriptor struct{}
+
+func (d *ownersAssignDescriptor) CommandLineDescription() CommandLineDescription {
+	return CommandLineDescription{
+		Name:        "owners-assign",
+		Description: "Request reviews on pull requests from the owners of the files they modify",
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "assign",
+				Usage: "also assign the pull request to the requested reviewers",
+			},
+			cli.StringSliceFlag{
+				Name:  "owner",
+				Usage: "ownership rule in CODEOWNERS format, overriding the repository's CODEOWNERS",
+			},
+			cli.IntFlag{
+				Name:  "reviewers",
+				Usage: "number of reviewers to request",
+				Value: 1,
+			},
+		},
+	}
+}
+
+func (d *ownersAssignDescriptor) OperationFromCli(c *cli.Context) (operations.Operation, error) {
+	ownersAssignConfig := &ownersAssignConfig{
+		Assign:    c.Bool("assign"),
+		Owners:    c.StringSlice("owner"),
+		Reviewers: c.Int("reviewers"),
+	}
+	return d.makeOperation(ownersAssignConfig)
+}
+
+func (d *ownersAssignDescriptor) OperationFromConfig(c operations.Configuration) (operations.Operation, error) {
+	ownersAssignConfig := &ownersAssignConfig{}
+	if err := mapstructure.Decode(c, &ownersAssignConfig); err != nil {
+		return nil, errors.Wrap(err, "decoding configuration")
+	}
+	return d.makeOperation(ownersAssignConfig)
+}
+
+func (d *ownersAssignDescriptor) makeOperation(config *ownersAssignConfig) (operations.Operation, error) {
+	operation := &ownersAssignOperation{
+		assign:     config.Assign,
+		reviewers:  config.Reviewers,
+		codeOwners: map[string][]ownersRule{},
+		loads:      newLoadCounter("is:pr is:open review-requested:%s"),
+	}
+	switch {
+	case operation.reviewers == 0:
+		operation.reviewers = 1
+	case operation.reviewers < 0:
+		return nil, errors.Errorf("invalid number of reviewers %d", config.Reviewers)
+	}
+	if len(config.Owners) > 0 {
+		rules, err := parseOwnersRules(config.Owners)
+		if err != nil {
+			return nil, err
+		}
+		operation.owners = rules
+	}
+	return operation, nil
+}
+
+// ownersRule associates owners ("@user" or "@org/team") to files matching a CODEOWNERS pattern.
+type ownersRule struct {
+	pattern string
+	regexps []*regexp.Regexp
+	owners  []string
+}
+
+func (r ownersRule) matches(filename string) bool {
+	for _, regex := range r.regexps {
+		if regex.MatchString(filename) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseOwnersRules parses lines in the CODEOWNERS format. Owners designated by email address can't
+// be requested a review from, and are ignored.
+func parseOwnersRules(lines []string) ([]ownersRule, error) {
+	rules := []ownersRule{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		regexps, err := compileOwnersPattern(fields[0])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid owners pattern %q", fields[0])
+		}
+		rule := ownersRule{pattern: fields[0], regexps: regexps}
+		for _, owner := range fields[1:] {
+			if strings.HasPrefix(owner, "#") {
+				break
+			}
+			if strings.HasPrefix(owner, "@") {
+				rule.owners = append(rule.owners, owner)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// compileOwnersPattern translates a CODEOWNERS pattern, which follows the gitignore rules: patterns
+// without a slash (other than a trailing one) match at any depth, and patterns matching a directory
+// also match all files under it.
+func compileOwnersPattern(pattern string) ([]*regexp.Regexp, error) {
+	glob := strings.TrimPrefix(pattern, "/")
+	if !strings.HasPrefix(pattern, "/") && !strings.Contains(strings.TrimSuffix(pattern, "/"), "/") {
+		glob = "**/" + glob
+	}
+	globs := []string{glob}
+	if !strings.HasSuffix(glob, "/") {
+		globs = append(globs, glob+"/")
+	}
+	regexps := []*regexp.Regexp{}
+	for _, g := range globs {
+		regex, err := settings.CompileGlob(g)
+		if err != nil {
+			return nil, err
+		}
+		regexps = append(regexps, regex)
+	}
+	return regexps, nil
+}
+
+// ownersOf returns the owners of a file, as designated by the last matching rule.
+func ownersOf(rules []ownersRule, filename string) []string {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].matches(filename) {
+			return rules[i].owners
+		}
+	}
+	return nil
+}
+
+type ownersAssignOperation struct {
+	assign    bool
+	owners    []ownersRule
+	reviewers int
+
+	// codeOwners caches the rules parsed from the repository's CODEOWNERS by reference.
+	sync.Mutex
+	codeOwners map[string][]ownersRule
+
+	// loads counts the open pull requests each user is requested to review.
+	loads *loadCounter
+}
+
+type ownersAssignUserData struct {
+	Reviewers     []string `json:"reviewers,omitempty"`
+	TeamReviewers []string `json:"team_reviewers,omitempty"`
+}
+
+func (o *ownersAssignOperation) Accepts() operations.AcceptedType {
+	return operations.PullRequests
+}
+
+func (o *ownersAssignOperation) Apply(c *operations.Context, item gh.Item, userData interface{}) error {
+	ud := userData.(ownersAssignUserData)
+	if _, _, err := c.Client.PullRequests().RequestReviewers(c.Username, c.Repository, item.Number(), gh.ReviewersRequest{
+		Reviewers:     ud.Reviewers,
+		TeamReviewers: ud.TeamReviewers,
+	}); err != nil {
+		return errors.Wrapf(err, "failed to request reviewers for pull request #%d", item.Number())
+	}
+	if o.assign && len(ud.Reviewers) > 0 {
+		if _, _, err := c.Client.Issues().AddAssignees(c.Username, c.Repository, item.Number(), ud.Reviewers); err != nil {
+			return errors.Wrapf(err, "failed to assign pull request #%d", item.Number())
+		}
+	}
+	return nil
+}
+
+func (o *ownersAssignOperation) Describe(c *operations.Context, item gh.Item, userData interface{}) string {
+	ud := userData.(ownersAssignUserData)
+	reviewers := append([]string{}, ud.Reviewers...)
+	for _, team := range ud.TeamReviewers {
+		reviewers = append(reviewers, c.Username+"/"+team)
+	}
+	if o.assign && len(ud.Reviewers) > 0 {
+		return fmt.Sprintf("requesting review from and assigning %s", strings.Join(reviewers, ", "))
+	}
+	return fmt.Sprintf("requesting review from %s", strings.Join(reviewers, ", "))
+}
+
+func (o *ownersAssignOperation) Filter(c *operations.Context, item gh.Item) (operations.FilterResult, interface{}, error) {
+	pr := item.PullRequest
+	if pr.State != nil && *pr.State != "open" {
+		return operations.Reject, nil, nil
+	}
+
+	// Leave pull requests which already have pending review requests alone.
+	requested, _, err := c.Client.PullRequests().ListReviewers(c.Username, c.Repository, item.Number())
+	if err != nil {
+		return operations.Reject, nil, errors.Wrapf(err, "failed to list reviewers of pull request #%d", item.Number())
+	}
+	if len(requested.Users) > 0 || len(requested.Teams) > 0 {
+		logrus.Debugf("rejecting pull request #%d with pending review requests", item.Number())
+		return operations.Reject, nil, nil
+	}
+
+	// Collect the owners of all modified files.
+	rules, err := o.rules(c, pr)
+	if err != nil {
+		return operations.Reject, nil, err
+	}
+	files, err := item.GetFiles(c.Client)
+	if err != nil {
+		return operations.Reject, nil, errors.Wrapf(err, "failed to list files of pull request #%d", item.Number())
+	}
+	users, teams := map[string]bool{}, map[string]bool{}
+	for _, file := range files {
+		for _, owner := range ownersOf(rules, *file.Filename) {
+			if org, team := splitOwner(owner); team == "" {
+				users[org] = true
+			} else if strings.EqualFold(org, c.Username) {
+				// Only teams of the organization owning the repository can be requested a review.
+				teams[team] = true
+			}
+		}
+	}
+	if len(users) == 0 && len(teams) == 0 {
+		return operations.Reject, nil, nil
+	}
+
+	// GitHub removes the pending request of reviewers once they submit their review: owners who
+	// already reviewed the pull request aren't requested again, and teams are only requested until
+	// a first review is submitted.
+	reviews, _, err := c.Client.PullRequests().ListReviews(c.Username, c.Repository, item.Number())
+	if err != nil {
+		return operations.Reject, nil, errors.Wrapf(err, "failed to list reviews of pull request #%d", item.Number())
+	}
+	author := ""
+	if user := item.User(); user != nil && user.Login != nil {
+		author = *user.Login
+	}
+	excluded := []string{author}
+	for _, review := range reviews {
+		if review.User == nil || review.User.Login == nil || strings.EqualFold(*review.User.Login, author) {
+			continue
+		}
+		excluded = append(excluded, *review.User.Login)
+		teams = map[string]bool{}
+	}
+	for user := range users {
+		for _, login := range excluded {
+			if strings.EqualFold(user, login) {
+				delete(users, user)
+			}
+		}
+	}
+
+	// Favor individual owners, and fall back to teams.
+	var userData ownersAssignUserData
+	if len(users) > 0 {
+		if userData.Reviewers, err = o.loads.pickLeastLoaded(c, sortedKeys(users), o.reviewers); err != nil {
+			return operations.Reject, nil, err
+		}
+	} else if keys := sortedKeys(teams); len(keys) > 0 {
+		if len(keys) > o.reviewers {
+			keys = keys[:o.reviewers]
+		}
+		userData.TeamReviewers = keys
+	}
+	if len(userData.Reviewers) == 0 && len(userData.TeamReviewers) == 0 {
+		return operations.Reject, nil, nil
+	}
+	return operations.Accept, userData, nil
+}
+
+func (o *ownersAssignOperation) IssueListOptions(c *operations.Context) *github.IssueListByRepoOptions {
+	return nil
+}
+
+func (o *ownersAssignOperation) PullRequestListOptions(c *operations.Context) *github.PullRequestListOptions {
+	return &github.PullRequestListOptions{
+		State: "open",
+		ListOptions: github.ListOptions{
+			PerPage: 200,
+		},
+	}
+}
+
+// rules returns the ownership rules, which come from the configuration when specified, or from the
+// CODEOWNERS file of the pull request's base branch.
+func (o *ownersAssignOperation) rules(c *operations.Context, pr *github.PullRequest) ([]ownersRule, error) {
+	if o.owners != nil {
+		return o.owners, nil
+	}
+	ref := ""
+	if pr.Base != nil && pr.Base.Ref != nil {
+		ref = *pr.Base.Ref
+	}
+
+	o.Lock()
+	rules, ok := o.codeOwners[ref]
+	o.Unlock()
+	if ok {
+		return rules, nil
+	}
+	rules = []ownersRule{}
+	for _, path := range codeOwnersLocations {
+		content, err := gh.GetFileContent(c.Client, c.Username, c.Repository, path, ref)
+		if err != nil {
+			return nil, err
+		}
+		if content == nil {
+			continue
+		}
+		if rules, err = parseOwnersRules(strings.Split(string(content), "\n")); err != nil {
+			return nil, errors.Wrapf(err, "invalid %s", path)
+		}
+		break
+	}
+	o.Lock()
+	o.codeOwners[ref] = rules
+	o.Unlock()
+	return rules, nil
+}
+
+// splitOwner splits "@user" into the user login, and "@org/team" into its organization and team.
+func splitOwner(owner string) (string, string) {
+	s := strings.SplitN(strings.TrimPrefix(owner, "@"), "/", 2)
+	if len(s) == 1 {
+		return s[0], ""
+	}
+	return s[0], s[1]
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
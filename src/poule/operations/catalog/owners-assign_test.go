@@ -0,0 +1,217 @@
+package catalog
+
+import (
+	"reflect"
+	"testing"
+
+	"poule/gh"
+	"poule/operations"
+	"poule/test"
+
+	"github.com/google/go-github/github"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestOwnersOf(t *testing.T) {
+	rules, err := parseOwnersRules([]string{
+		"# Default owners",
+		"*                 @docker/maintainers",
+		"*.md              @docs-owner # Documentation",
+		"/daemon/          @daemon-owner someone@example.com",
+		"logger            @logger-owner",
+		"api/types/*.go    @api-owner",
+		"/daemon/graph.go",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for filename, expected := range map[string][]string{
+		"Makefile":                   {"@docker/maintainers"},
+		"docs/index.md":              {"@docs-owner"},
+		"daemon/daemon.go":           {"@daemon-owner"},
+		"daemon/README.md":           {"@daemon-owner"},
+		"daemon/logger/logger.go":    {"@logger-owner"},
+		"cmd/logger":                 {"@logger-owner"},
+		"api/types/container.go":     {"@api-owner"},
+		"api/types/swarm/service.go": {"@docker/maintainers"},
+		"daemon/graph.go":            nil,
+		"vendor/daemon/somewhere.go": {"@docker/maintainers"},
+	} {
+		if owners := ownersOf(rules, filename); !reflect.DeepEqual(owners, expected) {
+			t.Fatalf("Expected owners %v for %q, got %v", expected, filename, owners)
+		}
+	}
+}
+
+func TestOwnersAssign(t *testing.T) {
+	clt, ctx := makeContext()
+
+	item := test.NewPullRequestBuilder(test.IssueNumber).
+		BaseBranch(ctx.Username, ctx.Repository, "master", test.CommitSHA[0]).
+		State("open").
+		UserLogin("author").
+		Item()
+
+	// Set up the mock objects: the CODEOWNERS file is read from the base branch.
+	codeOwners := "daemon/ @author @busy @idle\n*.md @" + ctx.Username + "/docs\n"
+	clt.MockRepositories.
+		On("GetContents", ctx.Username, ctx.Repository, ".github/CODEOWNERS", &github.RepositoryContentGetOptions{Ref: "master"}).
+		Return(&github.RepositoryContent{Content: github.String(codeOwners)}, nil, nil, nil).
+		Once()
+	clt.MockPullRequests.
+		On("ListReviewers", ctx.Username, ctx.Repository, test.IssueNumber).
+		Return(&gh.Reviewers{}, nil, nil)
+	clt.MockPullRequests.
+		On("ListFiles", ctx.Username, ctx.Repository, test.IssueNumber, mock.AnythingOfType("*github.ListOptions")).
+		Return([]*github.CommitFile{
+			{Filename: github.String("daemon/daemon.go")},
+			{Filename: github.String("docs/index.md")},
+		}, nil, nil)
+	clt.MockPullRequests.
+		On("ListReviews", ctx.Username, ctx.Repository, test.IssueNumber).
+		Return([]*github.PullRequestReview{}, nil, nil)
+	for user, load := range map[string]int{"busy": 12, "idle": 1} {
+		clt.MockSearch.
+			On("Issues", "repo:"+ctx.Username+"/"+ctx.Repository+" is:pr is:open review-requested:"+user, mock.Anything).
+			Return(&github.IssuesSearchResult{Total: github.Int(load)}, nil, nil).
+			Once()
+	}
+	clt.MockPullRequests.
+		On("RequestReviewers", ctx.Username, ctx.Repository, test.IssueNumber, gh.ReviewersRequest{Reviewers: []string{"idle"}}).
+		Return(nil, nil, nil).
+		Once()
+	clt.MockIssues.
+		On("AddAssignees", ctx.Username, ctx.Repository, test.IssueNumber, []string{"idle"}).
+		Return(nil, nil, nil).
+		Once()
+
+	config := map[string]interface{}{"assign": true}
+	op, err := (&ownersAssignDescriptor{}).OperationFromConfig(config)
+	if err != nil {
+		t.Fatalf("OperationFromConfig returned unexpected error %v", err)
+	}
+
+	// Call into the operation: the author is excluded, and the least loaded owner selected.
+	res, userData, err := op.Filter(ctx, item)
+	if err != nil {
+		t.Fatalf("Filter returned unexpected error %v", err)
+	}
+	if res != operations.Accept {
+		t.Fatalf("Filter returned unexpected result %v", res)
+	}
+	if err := op.Apply(ctx, item, userData); err != nil {
+		t.Fatalf("Apply returned unexpected error %v", err)
+	}
+
+	// The selection is accounted for in subsequent pull requests, without looking up loads again.
+	for i := 0; i < 10; i++ {
+		if _, userData, _ = op.Filter(ctx, item); userData.(ownersAssignUserData).Reviewers[0] != "idle" {
+			t.Fatalf("Expected idle owner to be selected until loads are balanced")
+		}
+	}
+	if _, userData, _ = op.Filter(ctx, item); userData.(ownersAssignUserData).Reviewers[0] != "busy" {
+		t.Fatalf("Expected busy owner to be selected once loads are balanced")
+	}
+	test.AssertExpectations(clt, t)
+	clt.MockSearch.AssertExpectations(t)
+}
+
+func TestOwnersAssignTeamsAndPendingRequests(t *testing.T) {
+	clt, ctx := makeContext()
+
+	item := test.NewPullRequestBuilder(test.IssueNumber).
+		BaseBranch(ctx.Username, ctx.Repository, "master", test.CommitSHA[0]).
+		State("open").
+		UserLogin("author").
+		Item()
+	clt.MockPullRequests.
+		On("ListReviewers", ctx.Username, ctx.Repository, test.IssueNumber).
+		Return(&gh.Reviewers{}, nil, nil).
+		Once()
+	clt.MockPullRequests.
+		On("ListFiles", ctx.Username, ctx.Repository, test.IssueNumber, mock.AnythingOfType("*github.ListOptions")).
+		Return([]*github.CommitFile{{Filename: github.String("docs/index.md")}}, nil, nil)
+	clt.MockPullRequests.
+		On("ListReviews", ctx.Username, ctx.Repository, test.IssueNumber).
+		Return([]*github.PullRequestReview{}, nil, nil).
+		Once()
+
+	// Owners from the configuration take precedence over CODEOWNERS, and only teams of the
+	// repository's organization are requested a review from.
+	config := map[string]interface{}{"owners": []interface{}{
+		"docs/ @author @other-org/docs @" + ctx.Username + "/docs",
+	}}
+	op, err := (&ownersAssignDescriptor{}).OperationFromConfig(config)
+	if err != nil {
+		t.Fatalf("OperationFromConfig returned unexpected error %v", err)
+	}
+	res, userData, err := op.Filter(ctx, item)
+	if err != nil || res != operations.Accept {
+		t.Fatalf("Filter returned unexpected result %v (error %v)", res, err)
+	}
+	if expected := (ownersAssignUserData{TeamReviewers: []string{"docs"}}); !reflect.DeepEqual(userData, expected) {
+		t.Fatalf("Expected user data %#v, got %#v", expected, userData)
+	}
+
+	// Pull requests with pending review requests are rejected.
+	clt.MockPullRequests.
+		On("ListReviewers", ctx.Username, ctx.Repository, test.IssueNumber).
+		Return(&gh.Reviewers{Users: []*github.User{{Login: github.String("someone")}}}, nil, nil).
+		Once()
+	if res, _, err := op.Filter(ctx, item); err != nil || res != operations.Reject {
+		t.Fatalf("Filter returned unexpected result %v (error %v)", res, err)
+	}
+	test.AssertExpectations(clt, t)
+}
+
+func TestOwnersAssignSkipsReviewers(t *testing.T) {
+	clt, ctx := makeContext()
+
+	item := test.NewPullRequestBuilder(test.IssueNumber).
+		BaseBranch(ctx.Username, ctx.Repository, "master", test.CommitSHA[0]).
+		State("open").
+		UserLogin("author").
+		Item()
+	clt.MockPullRequests.
+		On("ListReviewers", ctx.Username, ctx.Repository, test.IssueNumber).
+		Return(&gh.Reviewers{}, nil, nil)
+	clt.MockPullRequests.
+		On("ListFiles", ctx.Username, ctx.Repository, test.IssueNumber, mock.AnythingOfType("*github.ListOptions")).
+		Return([]*github.CommitFile{{Filename: github.String("daemon/daemon.go")}}, nil, nil)
+
+	config := map[string]interface{}{"owners": []interface{}{
+		"daemon/ @reviewer @" + ctx.Username + "/daemon",
+	}}
+	op, err := (&ownersAssignDescriptor{}).OperationFromConfig(config)
+	if err != nil {
+		t.Fatalf("OperationFromConfig returned unexpected error %v", err)
+	}
+
+	// The owner already submitted a review, which removed its pending request: neither the owner
+	// nor the team are requested again.
+	clt.MockPullRequests.
+		On("ListReviews", ctx.Username, ctx.Repository, test.IssueNumber).
+		Return([]*github.PullRequestReview{{User: &github.User{Login: github.String("Reviewer")}}}, nil, nil).
+		Once()
+	if res, _, err := op.Filter(ctx, item); err != nil || res != operations.Reject {
+		t.Fatalf("Filter returned unexpected result %v (error %v)", res, err)
+	}
+
+	// Reviews of the author don't count.
+	clt.MockSearch.
+		On("Issues", "repo:"+ctx.Username+"/"+ctx.Repository+" is:pr is:open review-requested:reviewer", mock.Anything).
+		Return(&github.IssuesSearchResult{Total: github.Int(0)}, nil, nil).
+		Once()
+	clt.MockPullRequests.
+		On("ListReviews", ctx.Username, ctx.Repository, test.IssueNumber).
+		Return([]*github.PullRequestReview{{User: &github.User{Login: github.String("author")}}}, nil, nil).
+		Once()
+	res, userData, err := op.Filter(ctx, item)
+	if err != nil || res != operations.Accept {
+		t.Fatalf("Filter returned unexpected result %v (error %v)", res, err)
+	}
+	if expected := (ownersAssignUserData{Reviewers: []string{"reviewer"}}); !reflect.DeepEqual(userData, expected) {
+		t.Fatalf("Expected user data %#v, got %#v", expected, userData)
+	}
+	test.AssertExpectations(clt, t)
+}
@@ -2,8 +2,6 @@ package catalog
 
 import (
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"strings"
 
 	yaml "gopkg.in/yaml.v2"
@@ -55,7 +53,7 @@ type pouleUpdaterOperation struct{}
 
 type pouleUpdaterUserData struct {
 	Merged bool
-	URL    string
+	Ref    string
 }
 
 func (o *pouleUpdaterOperation) Accepts() operations.AcceptedType {
@@ -87,7 +85,7 @@ func (o *pouleUpdaterOperation) Filter(c *operations.Context, item gh.Item) (ope
 	}
 
 	// List all files modified by the pull requests, and look for our special configuration file.
-	commitFiles, _, err := c.Client.PullRequests().ListFiles(c.Username, c.Repository, item.Number(), nil)
+	commitFiles, err := item.GetFiles(c.Client)
 	if err != nil {
 		return operations.Reject, nil, err
 	}
@@ -95,7 +93,7 @@ func (o *pouleUpdaterOperation) Filter(c *operations.Context, item gh.Item) (ope
 		if *commitFile.Filename == configuration.PouleConfigurationFile {
 			userData := pouleUpdaterUserData{
 				Merged: isMerged,
-				URL:    *commitFile.RawURL,
+				Ref:    *pr.Head.SHA,
 			}
 			return operations.Accept, userData, nil
 		}
@@ -119,17 +117,12 @@ func updatePouleConfiguration(repository string) error {
 }
 
 func validatePouleConfiguration(c *operations.Context, item gh.Item, userData pouleUpdaterUserData) error {
-	resp, err := http.Get(userData.URL)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("error retrieving file %q (%s)", userData.URL, resp.Status)
-	}
-	content, err := ioutil.ReadAll(resp.Body)
+	// Pull request head commits are reachable from the base repository, even for forks.
+	content, err := gh.GetFileContent(c.Client, c.Username, c.Repository, configuration.PouleConfigurationFile, userData.Ref)
 	if err != nil {
 		return err
+	} else if content == nil {
+		return fmt.Errorf("file %q not found at %q", configuration.PouleConfigurationFile, userData.Ref)
 	}
 
 	var actions configuration.Actions
@@ -144,16 +137,14 @@ func validatePouleConfiguration(c *operations.Context, item gh.Item, userData po
 func applyInvalidPouleConfiguration(c *operations.Context, item gh.Item, userData pouleUpdaterUserData, errs []error) error {
 	// Create the automated comment for that pull request, unless there is already one.
 	pr := item.PullRequest
-	if automatedComments, err := findAutomatedComments(c, pr, pouleValidationCommentToken); err != nil {
+	if exists, err := hasAutomatedComment(c, pr, pouleValidationCommentToken); err != nil {
 		return err
-	} else if len(automatedComments) != 0 {
+	} else if exists {
 		return nil
 	}
 
 	// Create the automated comment.
-	content := formatValidationComment(c, pr, errs)
-	comment := &github.IssueComment{Body: &content}
-	if _, _, err := c.Client.Issues().CreateComment(c.Username, c.Repository, *pr.Number, comment); err != nil {
+	if err := createAutomatedComment(c, pr, pouleValidationCommentToken, formatValidationComment(c, pr, errs)); err != nil {
 		return err
 	}
 
@@ -9,7 +9,9 @@ import (
 	"poule/configuration"
 	"poule/gh"
 	"poule/operations/settings"
+	"poule/runner/state"
 
+	"github.com/Sirupsen/logrus"
 	"github.com/google/go-github/github"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
@@ -100,8 +102,17 @@ func (o *pruneOperation) Apply(c *operations.Context, item gh.Item, userData int
 	issue := item.Issue
 	switch o.action {
 	case "close":
-		// TODO Find the last ping/warn message, and take the grace period into account.
-		break
+		body := formatCloseComment(issue, o)
+		if _, _, err := c.Client.Issues().CreateComment(c.Username, c.Repository, *issue.Number, &github.IssueComment{
+			Body: &body,
+		}); err != nil {
+			return err
+		}
+		state := "closed"
+		_, _, err := c.Client.Issues().Edit(c.Username, c.Repository, *issue.Number, &github.IssueRequest{
+			State: &state,
+		})
+		return err
 	case "force-close":
 		state := "closed"
 		_, _, err := c.Client.Issues().Edit(c.Username, c.Repository, *issue.Number, &github.IssueRequest{
@@ -126,22 +137,28 @@ func (o *pruneOperation) Apply(c *operations.Context, item gh.Item, userData int
 
 func (o *pruneOperation) Describe(c *operations.Context, item gh.Item, userData interface{}) string {
 	issue := item.Issue
+	if o.action == "close" {
+		return fmt.Sprintf("Execute %s action on issue #%d (last pinged on %s)",
+			o.action, *issue.Number, userData.(time.Time).Format(time.RFC3339))
+	}
 	return fmt.Sprintf("Execute %s action on issue #%d (last commented on %s)",
 		o.action, *issue.Number, userData.(time.Time).Format(time.RFC3339))
 }
 
 func (o *pruneOperation) Filter(c *operations.Context, item gh.Item) (operations.FilterResult, interface{}, error) {
+	// The close action only applies to issues which were previously pinged or
+	// warned, and for which the grace period has expired.
+	issue := item.Issue
+	if o.action == "close" {
+		return o.filterClose(c, issue)
+	}
+
 	// Retrieve comments for that issue since our threshold plus our grace
 	// period plus one day.
-	issue := item.Issue
-	comments, _, err := c.Client.Issues().ListComments(c.Username, c.Repository, *issue.Number, &github.IssueListCommentsOptions{
-		Since: time.Now().Add(-1*o.outdatedThreshold.Duration()).Add(-1*o.gracePeriod.Duration()).AddDate(0, 0, -1),
-		ListOptions: github.ListOptions{
-			PerPage: 200,
-		},
-	})
+	since := time.Now().Add(-1*o.outdatedThreshold.Duration()).Add(-1*o.gracePeriod.Duration()).AddDate(0, 0, -1)
+	comments, err := listIssueComments(c, issue, &since)
 	if err != nil {
-		return operations.Reject, nil, errors.Wrapf(err, "failed to retrieve comments for issue #%d", *issue.Number)
+		return operations.Reject, nil, err
 	}
 
 	// Figure out the last time the issue was commented on.
@@ -166,6 +183,67 @@ func (o *pruneOperation) Filter(c *operations.Context, item gh.Item) (operations
 	return operations.Accept, lastCommented, nil
 }
 
+func (o *pruneOperation) filterClose(c *operations.Context, issue *github.Issue) (operations.FilterResult, interface{}, error) {
+	// Find the last ping or warn message, either from the recorded history or
+	// from the comments themselves. The update time of the issue isn't
+	// relevant, as comments from other bots change it: only the comments
+	// posted since the recorded ping, or all of them if none was recorded, are
+	// considered.
+	lastPinged, err := o.lastRecordedPing(c, issue)
+	if err != nil {
+		return operations.Reject, nil, err
+	}
+	comments, err := listIssueComments(c, issue, lastPinged)
+	if err != nil {
+		return operations.Reject, nil, err
+	}
+	for _, comment := range comments {
+		if isPruneMarkerComment(comment) && (lastPinged == nil || comment.CreatedAt.After(*lastPinged)) {
+			lastPinged = comment.CreatedAt
+		}
+	}
+	if lastPinged == nil {
+		logrus.Debugf("rejecting issue #%d which was never pinged", *issue.Number)
+		return operations.Reject, nil, nil
+	}
+
+	// Verify that nobody but bots commented on the issue since then, and that
+	// the grace period has expired.
+	for _, comment := range comments {
+		if !isAutomatedComment(comment) && comment.CreatedAt.After(*lastPinged) {
+			logrus.Debugf("rejecting issue #%d commented on after last ping", *issue.Number)
+			return operations.Reject, nil, nil
+		}
+	}
+	if time.Since(*lastPinged) < o.gracePeriod.Duration() {
+		logrus.Debugf("rejecting issue #%d pinged within the grace period", *issue.Number)
+		return operations.Reject, nil, nil
+	}
+	return operations.Accept, *lastPinged, nil
+}
+
+func (o *pruneOperation) lastRecordedPing(c *operations.Context, issue *github.Issue) (*time.Time, error) {
+	if c.State == nil {
+		return nil, nil
+	}
+	var lastPinged *time.Time
+	repository := c.Username + "/" + c.Repository
+	for _, action := range []string{"ping", "warn"} {
+		record, err := state.Last(c.State, repository, *issue.Number, pruneRecordName(action))
+		if err != nil {
+			return nil, err
+		}
+		if record != nil && (lastPinged == nil || record.Time.After(*lastPinged)) {
+			lastPinged = &record.Time
+		}
+	}
+	return lastPinged, nil
+}
+
+func (o *pruneOperation) RecordName() string {
+	return pruneRecordName(o.action)
+}
+
 func (o *pruneOperation) IssueListOptions(c *operations.Context) *github.IssueListByRepoOptions {
 	return &github.IssueListByRepoOptions{
 		State:     "open",
@@ -208,6 +286,74 @@ This issue will be **automatically closed in %s** unless it is commented on.
 	return fmt.Sprintf(comment, base, o.gracePeriod.String())
 }
 
+func formatCloseComment(issue *github.Issue, o *pruneOperation) string {
+	comment := `<!-- %s:%s:%d%c -->
+@%s This issue is being automatically closed as it has not received any activity in over %s since we last asked for feedback.
+
+Please feel free to comment or reopen if it is still relevant. Thank you!`
+	return fmt.Sprintf(comment,
+		configuration.PouleToken,
+		o.action,
+		o.gracePeriod.Quantity,
+		o.gracePeriod.Unit,
+		*issue.User.Login,
+		o.gracePeriod.String(),
+	)
+}
+
+// listIssueComments returns all the comments of the issue, optionally only the ones updated since
+// the specified time.
+func listIssueComments(c *operations.Context, issue *github.Issue, since *time.Time) ([]*github.IssueComment, error) {
+	options := &github.IssueListCommentsOptions{
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+	if since != nil {
+		options.Since = *since
+	}
+	var comments []*github.IssueComment
+	for page := 1; page != 0; {
+		options.ListOptions.Page = page
+		pageComments, resp, err := c.Client.Issues().ListComments(c.Username, c.Repository, *issue.Number, options)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to retrieve comments for issue #%d", *issue.Number)
+		}
+		comments = append(comments, pageComments...)
+		if page = 0; resp != nil {
+			page = resp.NextPage
+		}
+	}
+	return comments, nil
+}
+
+// isAutomatedComment returns whether a comment was produced by a bot, either poule itself (as
+// indicated by the presence of the PouleToken) or any other GitHub bot account.
+func isAutomatedComment(comment *github.IssueComment) bool {
+	if comment.Body != nil && strings.Contains(*comment.Body, configuration.PouleToken) {
+		return true
+	}
+	return comment.User != nil && comment.User.Type != nil && *comment.User.Type == "Bot"
+}
+
+// isPruneMarkerComment returns whether a comment is a ping or warn message as generated by the
+// prune operation.
+func isPruneMarkerComment(comment *github.IssueComment) bool {
+	if comment.Body == nil {
+		return false
+	}
+	for _, action := range []string{"ping", "warn"} {
+		if strings.Contains(*comment.Body, fmt.Sprintf("<!-- %s:%s:", configuration.PouleToken, action)) {
+			return true
+		}
+	}
+	return false
+}
+
+func pruneRecordName(action string) string {
+	return "prune:" + action
+}
+
 func parseAction(action string) (string, error) {
 	switch action {
 	case "close", "force-close", "ping", "warn":
@@ -0,0 +1,217 @@
+package catalog
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"poule/configuration"
+	"poule/operations"
+	"poule/runner/state"
+	"poule/test"
+
+	"github.com/google/go-github/github"
+	"github.com/stretchr/testify/mock"
+)
+
+func makePruneCloseOperation(t *testing.T) operations.Operation {
+	config := operations.Configuration{
+		"action":             "close",
+		"grace-period":       "2w",
+		"outdated-threshold": "6m",
+	}
+	op, err := (&pruneDescriptor{}).OperationFromConfig(config)
+	if err != nil {
+		t.Fatalf("OperationFromConfig returned unexpected error %v", err)
+	}
+	return op
+}
+
+func makePruneComment(body, userType string, createdAt time.Time) *github.IssueComment {
+	return &github.IssueComment{
+		Body:      github.String(body),
+		CreatedAt: &createdAt,
+		UpdatedAt: &createdAt,
+		User: &github.User{
+			Login: github.String("someone"),
+			Type:  github.String(userType),
+		},
+	}
+}
+
+func TestPruneClose(t *testing.T) {
+	clt, ctx := makeContext()
+	op := makePruneCloseOperation(t)
+
+	pingedAt := time.Now().AddDate(0, 0, -15)
+	item := test.NewIssueBuilder(test.IssueNumber).
+		UserLogin("author").
+		UpdatedAt(pingedAt).
+		Item()
+
+	// Set up the mock objects.
+	pingComment := fmt.Sprintf("<!-- %s:ping:6m -->\nping", configuration.PouleToken)
+	clt.MockIssues.
+		On("ListComments", ctx.Username, ctx.Repository, test.IssueNumber, mock.AnythingOfType("*github.IssueListCommentsOptions")).
+		Return([]*github.IssueComment{
+			makePruneComment("Still happening", "User", pingedAt.AddDate(0, 0, -200)),
+			makePruneComment(pingComment, "User", pingedAt),
+			makePruneComment("Unrelated bot message", "Bot", pingedAt.AddDate(0, 0, 1)),
+		}, &github.Response{NextPage: 0}, nil)
+
+	clt.MockIssues.
+		On("CreateComment", ctx.Username, ctx.Repository, test.IssueNumber, mock.AnythingOfType("*github.IssueComment")).
+		Return(&github.IssueComment{}, nil, nil)
+
+	clt.MockIssues.
+		On("Edit", ctx.Username, ctx.Repository, test.IssueNumber, &github.IssueRequest{State: github.String("closed")}).
+		Return(nil, nil, nil)
+
+	// Call into the operation.
+	res, userData, err := op.Filter(ctx, item)
+	if err != nil {
+		t.Fatalf("Filter returned unexpected error %v", err)
+	}
+	if res != operations.Accept {
+		t.Fatalf("Filter returned unexpected result %v", res)
+	}
+	if lastPinged := userData.(time.Time); !lastPinged.Equal(pingedAt) {
+		t.Fatalf("Filter returned unexpected last ping time %v", lastPinged)
+	}
+	if err := op.Apply(ctx, item, userData); err != nil {
+		t.Fatalf("Apply returned unexpected error %v", err)
+	}
+	test.AssertExpectations(clt, t)
+}
+
+func TestPruneCloseRejected(t *testing.T) {
+	pingComment := fmt.Sprintf("<!-- %s:warn:6m -->\nwarn", configuration.PouleToken)
+	for name, tc := range map[string]struct {
+		comments func(pingedAt time.Time) []*github.IssueComment
+		expected operations.FilterResult
+	}{
+		"never pinged": {
+			comments: func(pingedAt time.Time) []*github.IssueComment {
+				return []*github.IssueComment{
+					makePruneComment("Still happening", "User", pingedAt),
+				}
+			},
+			expected: operations.Reject,
+		},
+		"commented after ping": {
+			comments: func(pingedAt time.Time) []*github.IssueComment {
+				return []*github.IssueComment{
+					makePruneComment(pingComment, "User", pingedAt),
+					makePruneComment("Still happening", "User", pingedAt.AddDate(0, 0, 1)),
+				}
+			},
+			expected: operations.Reject,
+		},
+		"within grace period": {
+			comments: func(pingedAt time.Time) []*github.IssueComment {
+				return []*github.IssueComment{
+					makePruneComment(pingComment, "User", time.Now().AddDate(0, 0, -1)),
+				}
+			},
+			expected: operations.Reject,
+		},
+	} {
+		clt, ctx := makeContext()
+		op := makePruneCloseOperation(t)
+
+		pingedAt := time.Now().AddDate(0, 0, -15)
+		item := test.NewIssueBuilder(test.IssueNumber).
+			UserLogin("author").
+			UpdatedAt(pingedAt).
+			Item()
+
+		clt.MockIssues.
+			On("ListComments", ctx.Username, ctx.Repository, test.IssueNumber, mock.AnythingOfType("*github.IssueListCommentsOptions")).
+			Return(tc.comments(pingedAt), &github.Response{NextPage: 0}, nil)
+
+		res, _, err := op.Filter(ctx, item)
+		if err != nil {
+			t.Fatalf("%s: Filter returned unexpected error %v", name, err)
+		}
+		if res != tc.expected {
+			t.Fatalf("%s: Filter returned unexpected result %v", name, res)
+		}
+		test.AssertExpectations(clt, t)
+	}
+}
+
+func TestPruneCloseFromState(t *testing.T) {
+	clt, ctx := makeContext()
+	op := makePruneCloseOperation(t)
+
+	// The ping message isn't part of the retrieved comments, but was recorded.
+	pingedAt := time.Now().AddDate(0, 0, -15)
+	ctx.State = state.NewMemoryStore()
+	ctx.State.Add(state.Record{
+		Repository: ctx.Username + "/" + ctx.Repository,
+		Number:     test.IssueNumber,
+		Operation:  "prune:ping",
+		Time:       pingedAt,
+	})
+	item := test.NewIssueBuilder(test.IssueNumber).
+		UserLogin("author").
+		UpdatedAt(pingedAt).
+		Item()
+
+	clt.MockIssues.
+		On("ListComments", ctx.Username, ctx.Repository, test.IssueNumber, mock.AnythingOfType("*github.IssueListCommentsOptions")).
+		Return([]*github.IssueComment{}, &github.Response{NextPage: 0}, nil)
+
+	res, userData, err := op.Filter(ctx, item)
+	if err != nil {
+		t.Fatalf("Filter returned unexpected error %v", err)
+	}
+	if res != operations.Accept {
+		t.Fatalf("Filter returned unexpected result %v", res)
+	}
+	if lastPinged := userData.(time.Time); !lastPinged.Equal(pingedAt) {
+		t.Fatalf("Filter returned unexpected last ping time %v", lastPinged)
+	}
+	test.AssertExpectations(clt, t)
+}
+
+func TestPruneClosePaginated(t *testing.T) {
+	clt, ctx := makeContext()
+	op := makePruneCloseOperation(t)
+
+	// The issue was recently updated by a bot, and the ping message is on the second page.
+	pingedAt := time.Now().AddDate(0, 0, -15)
+	item := test.NewIssueBuilder(test.IssueNumber).
+		UserLogin("author").
+		UpdatedAt(time.Now()).
+		Item()
+
+	pingComment := fmt.Sprintf("<!-- %s:ping:6m -->\nping", configuration.PouleToken)
+	clt.MockIssues.
+		On("ListComments", ctx.Username, ctx.Repository, test.IssueNumber, mock.MatchedBy(func(options *github.IssueListCommentsOptions) bool {
+			return options.Page == 1 && options.Since.IsZero()
+		})).
+		Return([]*github.IssueComment{
+			makePruneComment("Still happening", "User", pingedAt.AddDate(0, 0, -200)),
+		}, &github.Response{NextPage: 2}, nil).Once()
+	clt.MockIssues.
+		On("ListComments", ctx.Username, ctx.Repository, test.IssueNumber, mock.MatchedBy(func(options *github.IssueListCommentsOptions) bool {
+			return options.Page == 2
+		})).
+		Return([]*github.IssueComment{
+			makePruneComment(pingComment, "User", pingedAt),
+			makePruneComment("CI passed", "Bot", time.Now()),
+		}, &github.Response{NextPage: 0}, nil).Once()
+
+	res, userData, err := op.Filter(ctx, item)
+	if err != nil {
+		t.Fatalf("Filter returned unexpected error %v", err)
+	}
+	if res != operations.Accept {
+		t.Fatalf("Filter returned unexpected result %v", res)
+	}
+	if lastPinged := userData.(time.Time); !lastPinged.Equal(pingedAt) {
+		t.Fatalf("Filter returned unexpected last ping time %v", lastPinged)
+	}
+	test.AssertExpectations(clt, t)
+}
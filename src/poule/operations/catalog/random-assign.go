@@ -2,23 +2,67 @@ package catalog
 
 import (
 	"fmt"
+	"io/ioutil"
 	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"poule/gh"
 	"poule/operations"
+	"poule/runner/state"
 
+	"github.com/Sirupsen/logrus"
 	"github.com/google/go-github/github"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"github.com/urfave/cli"
 )
 
+const (
+	// assignStrategyRandom assigns items to a random user.
+	assignStrategyRandom = "random"
+
+	// assignStrategyRoundRobin assigns items to each user in turn.
+	assignStrategyRoundRobin = "round-robin"
+
+	// assignStrategyLeastLoaded assigns items to the user with the fewest open assigned items.
+	assignStrategyLeastLoaded = "least-loaded"
+
+	// vacationDateFormat is the format of vacation dates.
+	vacationDateFormat = "2006-01-02"
+
+	// roundRobinRecordName is the name under which the last user selected by the round-robin
+	// strategy is recorded in the state store.
+	roundRobinRecordName = "random-assign:cursor"
+)
+
+// roundRobinCursors are the last users assigned by the round-robin strategy, keyed by cursor file
+// or by repository. They are shared by all operations of the process, as the server creates a new
+// operation for each event.
+var roundRobinCursors = struct {
+	sync.Mutex
+	cursors map[string]string
+}{cursors: map[string]string{}}
+
 func init() {
 	registerOperation(&assignDescriptor{})
 }
 
 type assignOperationConfig struct {
-	Users []string `mapstructure:"users"`
+	CursorFile string                 `mapstructure:"cursor_file"`
+	Strategy   string                 `mapstructure:"strategy"`
+	Users      []string               `mapstructure:"users"`
+	Vacations  []assignVacationConfig `mapstructure:"vacations"`
+}
+
+// assignVacationConfig describes a period, inclusive of both dates, during which a user must not be
+// assigned any item.
+type assignVacationConfig struct {
+	User  string `mapstructure:"user"`
+	From  string `mapstructure:"from"`
+	Until string `mapstructure:"until"`
 }
 
 type assignDescriptor struct{}
@@ -28,6 +72,21 @@ func (d *assignDescriptor) CommandLineDescription() CommandLineDescription {
 		Name:        "random-assign",
 		Description: "Assign items to a random username from the `users` list.",
 		ArgsUsage:   "user [user...] ...",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "cursor-file",
+				Usage: "file persisting the last assigned user for the round-robin strategy",
+			},
+			cli.StringFlag{
+				Name:  "strategy",
+				Usage: "assignment strategy (random, round-robin, or least-loaded)",
+				Value: assignStrategyRandom,
+			},
+			cli.StringSliceFlag{
+				Name:  "vacation",
+				Usage: "period during which a user isn't assigned items (e.g., \"user:2017-07-01..2017-07-15\")",
+			},
+		},
 	}
 }
 
@@ -35,7 +94,18 @@ func (d *assignDescriptor) OperationFromCli(c *cli.Context) (operations.Operatio
 	if c.NArg() < 1 {
 		return nil, errors.Errorf("random-assign requires at least one argument")
 	}
-	assignOperationConfig := &assignOperationConfig{Users: c.Args()}
+	assignOperationConfig := &assignOperationConfig{
+		CursorFile: c.String("cursor-file"),
+		Strategy:   c.String("strategy"),
+		Users:      c.Args(),
+	}
+	for _, value := range c.StringSlice("vacation") {
+		vacation, err := parseVacationFlag(value)
+		if err != nil {
+			return nil, err
+		}
+		assignOperationConfig.Vacations = append(assignOperationConfig.Vacations, vacation)
+	}
 	return d.makeAssignOperation(assignOperationConfig)
 }
 
@@ -48,11 +118,89 @@ func (d *assignDescriptor) OperationFromConfig(c operations.Configuration) (oper
 }
 
 func (d *assignDescriptor) makeAssignOperation(config *assignOperationConfig) (operations.Operation, error) {
-	return &assignOperation{users: config.Users}, nil
+	if len(config.Users) == 0 {
+		return nil, errors.Errorf("random-assign requires at least one user")
+	}
+	operation := &assignOperation{
+		cursorFile: config.CursorFile,
+		strategy:   config.Strategy,
+		users:      config.Users,
+		loads:      newLoadCounter("is:open assignee:%s"),
+		now:        time.Now,
+	}
+	switch operation.strategy {
+	case "":
+		operation.strategy = assignStrategyRandom
+	case assignStrategyRandom, assignStrategyRoundRobin, assignStrategyLeastLoaded:
+	default:
+		return nil, errors.Errorf("unknown assignment strategy %q", config.Strategy)
+	}
+	for _, vacation := range config.Vacations {
+		v, err := makeVacation(vacation)
+		if err != nil {
+			return nil, err
+		}
+		operation.vacations = append(operation.vacations, v)
+	}
+	return operation, nil
+}
+
+// parseVacationFlag parses a vacation in the "user:from..until" format.
+func parseVacationFlag(value string) (assignVacationConfig, error) {
+	s := strings.SplitN(value, ":", 2)
+	if len(s) != 2 {
+		return assignVacationConfig{}, errors.Errorf("invalid vacation %q (expected \"user:from..until\")", value)
+	}
+	dates := strings.SplitN(s[1], "..", 2)
+	if len(dates) != 2 {
+		return assignVacationConfig{}, errors.Errorf("invalid vacation %q (expected \"user:from..until\")", value)
+	}
+	return assignVacationConfig{User: s[0], From: dates[0], Until: dates[1]}, nil
+}
+
+func makeVacation(config assignVacationConfig) (assignVacation, error) {
+	if config.User == "" {
+		return assignVacation{}, errors.Errorf("vacation requires a user")
+	}
+	from, err := time.Parse(vacationDateFormat, config.From)
+	if err != nil {
+		return assignVacation{}, errors.Wrapf(err, "invalid vacation start date %q for %q", config.From, config.User)
+	}
+	until, err := time.Parse(vacationDateFormat, config.Until)
+	if err != nil {
+		return assignVacation{}, errors.Wrapf(err, "invalid vacation end date %q for %q", config.Until, config.User)
+	}
+	if until.Before(from) {
+		return assignVacation{}, errors.Errorf("vacation of %q ends before it starts", config.User)
+	}
+	// The end date is inclusive.
+	return assignVacation{user: config.User, from: from, until: until.AddDate(0, 0, 1)}, nil
+}
+
+// assignVacation is a period [from, until) during which the user isn't assigned items.
+type assignVacation struct {
+	user  string
+	from  time.Time
+	until time.Time
 }
 
 type assignOperation struct {
-	users []string
+	cursorFile string
+	strategy   string
+	users      []string
+	vacations  []assignVacation
+
+	// loads counts the open items assigned to each user for the least-loaded strategy.
+	loads *loadCounter
+
+	// cursor is the last user selected by the round-robin strategy while filtering, which only
+	// becomes the shared cursor once an item is actually assigned to that user.
+	sync.Mutex
+	cursor       string
+	cursorLoaded bool
+
+	// now is the current time, overridable for testing purposes.
+	now func() time.Time
 }
 
 func (o *assignOperation) Accepts() operations.AcceptedType {
@@ -60,8 +208,13 @@ func (o *assignOperation) Accepts() operations.AcceptedType {
 }
 
 func (o *assignOperation) Apply(c *operations.Context, item gh.Item, userData interface{}) error {
-	_, _, err := c.Client.Issues().AddAssignees(c.Username, c.Repository, item.Number(), []string{userData.(string)})
-	return err
+	if _, _, err := c.Client.Issues().AddAssignees(c.Username, c.Repository, item.Number(), []string{userData.(string)}); err != nil {
+		return err
+	}
+	if o.strategy == assignStrategyRoundRobin {
+		return o.saveCursor(c, userData.(string))
+	}
+	return nil
 }
 
 func (o *assignOperation) Describe(c *operations.Context, item gh.Item, userData interface{}) string {
@@ -74,11 +227,27 @@ func (o *assignOperation) Filter(c *operations.Context, item gh.Item) (operation
 		return operations.Reject, nil, nil
 	}
 
-retry:
-	assignee := o.users[rand.Intn(len(o.users))]
-	// Filter out author
-	if item.User() != nil && assignee == *item.User().Login {
-		goto retry
+	candidates := o.candidates(item)
+	if len(candidates) == 0 {
+		logrus.Warnf("no available user to assign #%d to", item.Number())
+		return operations.Reject, nil, nil
+	}
+
+	var assignee string
+	switch o.strategy {
+	case assignStrategyRoundRobin:
+		var err error
+		if assignee, err = o.pickNext(c, candidates); err != nil {
+			return operations.Reject, nil, err
+		}
+	case assignStrategyLeastLoaded:
+		picked, err := o.loads.pickLeastLoaded(c, candidates, 1)
+		if err != nil {
+			return operations.Reject, nil, err
+		}
+		assignee = picked[0]
+	default:
+		assignee = candidates[rand.Intn(len(candidates))]
 	}
 	return operations.Accept, assignee, nil
 }
@@ -100,3 +269,124 @@ func (o *assignOperation) PullRequestListOptions(c *operations.Context) *github.
 		},
 	}
 }
+
+// candidates returns the users who can be assigned the item, excluding its author and the users
+// currently on vacation.
+func (o *assignOperation) candidates(item gh.Item) []string {
+	now := o.now()
+	candidates := []string{}
+	for _, user := range o.users {
+		if item.User() != nil && item.User().Login != nil && strings.EqualFold(user, *item.User().Login) {
+			continue
+		}
+		if o.onVacation(user, now) {
+			continue
+		}
+		candidates = append(candidates, user)
+	}
+	return candidates
+}
+
+func (o *assignOperation) onVacation(user string, now time.Time) bool {
+	for _, vacation := range o.vacations {
+		if strings.EqualFold(vacation.user, user) && !now.Before(vacation.from) && now.Before(vacation.until) {
+			return true
+		}
+	}
+	return false
+}
+
+// pickNext selects the first candidate following the last selected user in the list of users. The
+// shared cursor isn't advanced until the item is assigned, so that dry runs, plans, and items which
+// end up not being assigned don't affect subsequent runs.
+func (o *assignOperation) pickNext(c *operations.Context, candidates []string) (string, error) {
+	o.Lock()
+	defer o.Unlock()
+	if !o.cursorLoaded {
+		roundRobinCursors.Lock()
+		cursor, ok := roundRobinCursors.cursors[o.cursorKey(c)]
+		roundRobinCursors.Unlock()
+		if !ok {
+			var err error
+			if cursor, err = o.loadCursor(c); err != nil {
+				return "", err
+			}
+		}
+		o.cursor, o.cursorLoaded = cursor, true
+	}
+
+	start := 0
+	for i, user := range o.users {
+		if user == o.cursor {
+			start = i + 1
+			break
+		}
+	}
+	for i := range o.users {
+		user := o.users[(start+i)%len(o.users)]
+		for _, candidate := range candidates {
+			if user == candidate {
+				o.cursor = user
+				return user, nil
+			}
+		}
+	}
+	return candidates[0], nil
+}
+
+// cursorKey returns the key of the round-robin cursor of the operation in roundRobinCursors.
+func (o *assignOperation) cursorKey(c *operations.Context) string {
+	if o.cursorFile != "" {
+		return "file:" + o.cursorFile
+	}
+	return "repository:" + c.Username + "/" + c.Repository
+}
+
+// loadCursor retrieves the last user selected by the round-robin strategy from the cursor file when
+// specified, and from the state store otherwise. The store only records a digest of the user, which
+// is matched against the list of users.
+func (o *assignOperation) loadCursor(c *operations.Context) (string, error) {
+	if o.cursorFile != "" {
+		b, err := ioutil.ReadFile(o.cursorFile)
+		if err != nil && !os.IsNotExist(err) {
+			return "", errors.Wrapf(err, "failed to read cursor file %q", o.cursorFile)
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+	if c.State == nil {
+		return "", nil
+	}
+	record, err := state.Last(c.State, c.Username+"/"+c.Repository, 0, roundRobinRecordName)
+	if err != nil || record == nil {
+		return "", err
+	}
+	for _, user := range o.users {
+		if state.Digest(user) == record.Digest {
+			return user, nil
+		}
+	}
+	return "", nil
+}
+
+// saveCursor makes the specified user the last one selected by the round-robin strategy, and
+// persists it to the cursor file when specified, and to the state store otherwise.
+func (o *assignOperation) saveCursor(c *operations.Context, cursor string) error {
+	roundRobinCursors.Lock()
+	defer roundRobinCursors.Unlock()
+	roundRobinCursors.cursors[o.cursorKey(c)] = cursor
+	if o.cursorFile != "" {
+		if err := ioutil.WriteFile(o.cursorFile, []byte(cursor+"\n"), 0600); err != nil {
+			return errors.Wrapf(err, "failed to write cursor file %q", o.cursorFile)
+		}
+		return nil
+	}
+	if c.State == nil {
+		return nil
+	}
+	return c.State.Add(state.Record{
+		Repository: c.Username + "/" + c.Repository,
+		Operation:  roundRobinRecordName,
+		Digest:     state.Digest(cursor),
+		Time:       o.now(),
+	})
+}
@@ -1,10 +1,20 @@
 package catalog
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 
+	"poule/gh"
 	"poule/operations"
+	"poule/runner/state"
 	"poule/test"
+
+	"github.com/google/go-github/github"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestAssign(t *testing.T) {
@@ -13,10 +23,11 @@ func TestAssign(t *testing.T) {
 	// Create test pattern mapping.
 	users := []string{"user1", "user2"}
 
+	// Logins are case insensitive: the author is never assigned.
 	item := test.NewIssueBuilder(test.IssueNumber).
 		Title("This is the title of a pull request").
 		Body("Lorem ipsum dolor sit amet, consectetur adipiscing elit").
-		UserLogin(users[0]).
+		UserLogin("User1").
 		Item()
 
 	// Set up the mock objects.
@@ -45,3 +56,218 @@ func TestAssign(t *testing.T) {
 	}
 	test.AssertExpectations(clt, t)
 }
+
+func filterAssignee(t *testing.T, op operations.Operation, ctx *operations.Context, item gh.Item) string {
+	res, userData, err := op.Filter(ctx, item)
+	if err != nil {
+		t.Fatalf("Filter returned unexpected error %v", err)
+	}
+	if res != operations.Accept {
+		t.Fatalf("Filter returned unexpected result %v", res)
+	}
+	return userData.(string)
+}
+
+func TestAssignRoundRobin(t *testing.T) {
+	clt, ctx := makeContext()
+	dir, err := ioutil.TempDir("", "poule-assign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cursorFile := filepath.Join(dir, "cursor")
+
+	config := map[string]interface{}{
+		"cursor_file": cursorFile,
+		"strategy":    "round-robin",
+		"users":       []string{"user1", "user2", "user3"},
+	}
+	op, err := (&assignDescriptor{}).OperationFromConfig(config)
+	if err != nil {
+		t.Fatalf("OperationFromConfig returned unexpected error %v", err)
+	}
+
+	// The author of the item is skipped.
+	item := test.NewIssueBuilder(test.IssueNumber).UserLogin("user2").Item()
+	var assignees []string
+	for i := 0; i < 4; i++ {
+		assignees = append(assignees, filterAssignee(t, op, ctx, item))
+	}
+	if expected := []string{"user1", "user3", "user1", "user3"}; !reflect.DeepEqual(assignees, expected) {
+		t.Fatalf("Expected assignees %v, got %v", expected, assignees)
+	}
+
+	// Applying the operation persists the cursor, from which a restarted process resumes.
+	clt.MockIssues.
+		On("AddAssignees", ctx.Username, ctx.Repository, test.IssueNumber, []string{"user3"}).
+		Return(nil, nil, nil)
+	if err := op.Apply(ctx, item, "user3"); err != nil {
+		t.Fatalf("Apply returned unexpected error %v", err)
+	}
+	resetRoundRobinCursors()
+	op, err = (&assignDescriptor{}).OperationFromConfig(config)
+	if err != nil {
+		t.Fatalf("OperationFromConfig returned unexpected error %v", err)
+	}
+	if assignee := filterAssignee(t, op, ctx, item); assignee != "user1" {
+		t.Fatalf("Expected assignee %q after reloading the cursor, got %q", "user1", assignee)
+	}
+	test.AssertExpectations(clt, t)
+}
+
+func TestAssignRoundRobinFromState(t *testing.T) {
+	resetRoundRobinCursors()
+	clt, ctx := makeContext()
+	ctx.State = state.NewMemoryStore()
+	config := map[string]interface{}{
+		"strategy": "round-robin",
+		"users":    []string{"user1", "user2", "user3"},
+	}
+	clt.MockIssues.
+		On("AddAssignees", ctx.Username, ctx.Repository, test.IssueNumber, mock.Anything).
+		Return(nil, nil, nil)
+
+	// The server creates a new operation for each event, which resumes after the previous one.
+	item := test.NewIssueBuilder(test.IssueNumber).Item()
+	var assignees []string
+	for i := 0; i < 4; i++ {
+		op, err := (&assignDescriptor{}).OperationFromConfig(config)
+		if err != nil {
+			t.Fatalf("OperationFromConfig returned unexpected error %v", err)
+		}
+		assignee := filterAssignee(t, op, ctx, item)
+		if err := op.Apply(ctx, item, assignee); err != nil {
+			t.Fatalf("Apply returned unexpected error %v", err)
+		}
+		assignees = append(assignees, assignee)
+	}
+	if expected := []string{"user1", "user2", "user3", "user1"}; !reflect.DeepEqual(assignees, expected) {
+		t.Fatalf("Expected assignees %v, got %v", expected, assignees)
+	}
+
+	// The cursor is recorded in the state store, from which a restarted process resumes.
+	resetRoundRobinCursors()
+	op, err := (&assignDescriptor{}).OperationFromConfig(config)
+	if err != nil {
+		t.Fatalf("OperationFromConfig returned unexpected error %v", err)
+	}
+	if assignee := filterAssignee(t, op, ctx, item); assignee != "user2" {
+		t.Fatalf("Expected assignee %q after reloading the cursor, got %q", "user2", assignee)
+	}
+	test.AssertExpectations(clt, t)
+}
+
+func TestAssignRoundRobinWithoutApply(t *testing.T) {
+	resetRoundRobinCursors()
+	_, ctx := makeContext()
+	ctx.State = state.NewMemoryStore()
+	config := map[string]interface{}{
+		"strategy": "round-robin",
+		"users":    []string{"user1", "user2", "user3"},
+	}
+
+	// Items which are filtered but not assigned (e.g., in dry run mode) don't advance the cursor.
+	item := test.NewIssueBuilder(test.IssueNumber).Item()
+	for i := 0; i < 2; i++ {
+		op, err := (&assignDescriptor{}).OperationFromConfig(config)
+		if err != nil {
+			t.Fatalf("OperationFromConfig returned unexpected error %v", err)
+		}
+		if assignee := filterAssignee(t, op, ctx, item); assignee != "user1" {
+			t.Fatalf("Expected assignee %q without any prior assignment, got %q", "user1", assignee)
+		}
+	}
+}
+
+func resetRoundRobinCursors() {
+	roundRobinCursors.Lock()
+	roundRobinCursors.cursors = map[string]string{}
+	roundRobinCursors.Unlock()
+}
+
+func TestAssignLeastLoaded(t *testing.T) {
+	clt, ctx := makeContext()
+
+	for user, load := range map[string]int{"user1": 3, "user2": 1, "user3": 2} {
+		clt.MockSearch.
+			On("Issues", "repo:"+ctx.Username+"/"+ctx.Repository+" is:open assignee:"+user, mock.Anything).
+			Return(&github.IssuesSearchResult{Total: github.Int(load)}, nil, nil).
+			Once()
+	}
+
+	config := map[string]interface{}{
+		"strategy": "least-loaded",
+		"users":    []string{"user1", "user2", "user3"},
+	}
+	op, err := (&assignDescriptor{}).OperationFromConfig(config)
+	if err != nil {
+		t.Fatalf("OperationFromConfig returned unexpected error %v", err)
+	}
+
+	// Selections account for the assignments made by the operation, and ties are resolved in the
+	// order of the list of users.
+	item := test.NewIssueBuilder(test.IssueNumber).UserLogin("someone").Item()
+	var assignees []string
+	for i := 0; i < 4; i++ {
+		assignees = append(assignees, filterAssignee(t, op, ctx, item))
+	}
+	if expected := []string{"user2", "user2", "user3", "user1"}; !reflect.DeepEqual(assignees, expected) {
+		t.Fatalf("Expected assignees %v, got %v", expected, assignees)
+	}
+	clt.MockSearch.AssertExpectations(t)
+}
+
+func TestAssignVacations(t *testing.T) {
+	_, ctx := makeContext()
+
+	config := map[string]interface{}{
+		"users": []string{"user1", "user2"},
+		"vacations": []interface{}{
+			map[interface{}]interface{}{"user": "user2", "from": "2017-07-01", "until": "2017-07-15"},
+		},
+	}
+	op, err := (&assignDescriptor{}).OperationFromConfig(config)
+	if err != nil {
+		t.Fatalf("OperationFromConfig returned unexpected error %v", err)
+	}
+	assignOp := op.(*assignOperation)
+
+	item := test.NewIssueBuilder(test.IssueNumber).UserLogin("user1").Item()
+	for _, tc := range []struct {
+		now      time.Time
+		expected operations.FilterResult
+	}{
+		{time.Date(2017, time.June, 30, 23, 0, 0, 0, time.UTC), operations.Accept},
+		{time.Date(2017, time.July, 1, 0, 0, 0, 0, time.UTC), operations.Reject},
+		{time.Date(2017, time.July, 15, 23, 0, 0, 0, time.UTC), operations.Reject},
+		{time.Date(2017, time.July, 16, 0, 0, 0, 0, time.UTC), operations.Accept},
+	} {
+		now := tc.now
+		assignOp.now = func() time.Time { return now }
+
+		// When every candidate is excluded, the item is rejected.
+		res, userData, err := op.Filter(ctx, item)
+		if err != nil {
+			t.Fatalf("Filter returned unexpected error %v", err)
+		}
+		if res != tc.expected {
+			t.Fatalf("Expected result %v at %v, got %v", tc.expected, now, res)
+		}
+		if res == operations.Accept && userData.(string) != "user2" {
+			t.Fatalf("Expected assignee %q at %v, got %v", "user2", now, userData)
+		}
+	}
+}
+
+func TestAssignConfigurationErrors(t *testing.T) {
+	for _, config := range []map[string]interface{}{
+		{},
+		{"users": []string{"user1"}, "strategy": "unknown"},
+		{"users": []string{"user1"}, "vacations": []interface{}{map[string]interface{}{"user": "user1", "from": "July 1st", "until": "2017-07-15"}}},
+		{"users": []string{"user1"}, "vacations": []interface{}{map[string]interface{}{"user": "user1", "from": "2017-07-15", "until": "2017-07-01"}}},
+	} {
+		if _, err := (&assignDescriptor{}).OperationFromConfig(config); err == nil {
+			t.Fatalf("Expected error for configuration %v", config)
+		}
+	}
+}
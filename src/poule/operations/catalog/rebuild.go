@@ -32,6 +32,7 @@ func (d *prRebuildDescriptor) CommandLineDescription() CommandLineDescription {
 	return CommandLineDescription{
 		Name:        "rebuild",
 		Description: "Rebuild configurations of a given state",
+		ArgsUsage:   "[configuration...]",
 		Flags: []cli.Flag{
 			cli.StringSliceFlag{
 				Name:  "configurations",
@@ -53,7 +54,7 @@ func (d *prRebuildDescriptor) CommandLineDescription() CommandLineDescription {
 func (d *prRebuildDescriptor) OperationFromCli(c *cli.Context) (operations.Operation, error) {
 	return &prRebuildOperation{
 		Builder:        rebuildPR,
-		Configurations: c.StringSlice("configurations"),
+		Configurations: append(c.StringSlice("configurations"), c.Args()...),
 		Label:          c.String("label"),
 		Statuses:       c.StringSlice("status"),
 	}, nil
@@ -2,8 +2,6 @@ package catalog
 
 import (
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"poule/gh"
 	"poule/operations"
 	"strings"
@@ -37,7 +35,7 @@ func (d *versionMilestoneDescriptor) OperationFromConfig(operations.Configuratio
 }
 
 type versionMilestoneOperation struct {
-	VersionGetter func(repository string) (string, error)
+	VersionGetter func(c *operations.Context, repository string) (string, error)
 }
 
 func (o *versionMilestoneOperation) Accepts() operations.AcceptedType {
@@ -56,7 +54,7 @@ func (o *versionMilestoneOperation) Describe(c *operations.Context, item gh.Item
 }
 
 func (o *versionMilestoneOperation) Filter(c *operations.Context, item gh.Item) (operations.FilterResult, interface{}, error) {
-	// We only consider merged pull requests against the master branch which don't already have a
+	// We only consider merged pull requests against the default branch which don't already have a
 	// milestone set.
 	pr := item.PullRequest
 	switch {
@@ -66,14 +64,21 @@ func (o *versionMilestoneOperation) Filter(c *operations.Context, item gh.Item)
 	case pr.Milestone != nil:
 		logrus.Debugf("rejecting pull request with milestone %d (%q)", *pr.Milestone.Number, *pr.Milestone.Title)
 		return operations.Reject, nil, nil
-	case *pr.Base.Ref != "master":
-		logrus.Debugf("rejecting pull request against non-master branch %q", *pr.Base.Ref)
+	}
+
+	// Retrieving the default branch requires an API call: only do so for candidate pull requests.
+	defaultBranch, err := baseDefaultBranch(c, pr)
+	if err != nil {
+		return operations.Reject, nil, err
+	}
+	if *pr.Base.Ref != defaultBranch {
+		logrus.Debugf("rejecting pull request against non-default branch %q", *pr.Base.Ref)
 		return operations.Reject, nil, nil
 	}
 
 	// We need to find the milestone that pull request belongs to: we get the VERSION file at the
 	// root of the repository, and try to find a matching milestone from there.
-	version, err := o.VersionGetter(item.Repository())
+	version, err := o.VersionGetter(c, item.Repository())
 	if err != nil {
 		return operations.Reject, nil, err
 	}
@@ -115,19 +120,28 @@ func (o *versionMilestoneOperation) PullRequestListOptions(c *operations.Context
 	return nil
 }
 
-func getVersionFromRepository(repository string) (string, error) {
-	versionURL := fmt.Sprintf("https://raw.githubusercontent.com/%s/master/VERSION", repository)
-	resp, err := http.Get(versionURL)
+func getVersionFromRepository(c *operations.Context, repository string) (string, error) {
+	owner, name := c.Username, c.Repository
+	if parts := strings.SplitN(repository, "/", 2); len(parts) == 2 {
+		owner, name = parts[0], parts[1]
+	}
+	content, err := gh.GetFileContent(c.Client, owner, name, "VERSION", "")
 	if err != nil {
-		return "", fmt.Errorf("failed to retrieve version from %q: %v", versionURL, err)
+		return "", fmt.Errorf("failed to retrieve version from %q: %v", repository, err)
+	} else if content == nil {
+		return "", fmt.Errorf("failed to retrieve version from %q: VERSION file not found", repository)
 	}
-	defer resp.Body.Close()
 
 	// Get the version number alone.
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read version response body: %v", err)
-	}
-	versionString := strings.SplitN(string(body), "-", 2)[0]
+	versionString := strings.SplitN(strings.TrimSpace(string(content)), "-", 2)[0]
 	return versionString, nil
 }
+
+// baseDefaultBranch returns the default branch of the repository a pull request is made against,
+// which is usually part of the pull request payload.
+func baseDefaultBranch(c *operations.Context, pr *github.PullRequest) (string, error) {
+	if pr.Base != nil && pr.Base.Repo != nil && pr.Base.Repo.DefaultBranch != nil {
+		return *pr.Base.Repo.DefaultBranch, nil
+	}
+	return gh.GetDefaultBranch(c.Client, c.Username, c.Repository)
+}
@@ -14,7 +14,7 @@ import (
 func TestAutoMilestone(t *testing.T) {
 	clt, ctx := makeContext()
 	operation := &versionMilestoneOperation{
-		VersionGetter: func(repository string) (string, error) {
+		VersionGetter: func(c *operations.Context, repository string) (string, error) {
 			return "test-version", nil
 		},
 	}
@@ -25,6 +25,10 @@ func TestAutoMilestone(t *testing.T) {
 		HeadBranch(ctx.Username, ctx.Repository, "head", test.CommitSHA[0]).
 		BaseBranch(ctx.Username, ctx.Repository, "master", test.CommitSHA[1]).Value
 
+	// Mock the repositories API.
+	clt.MockRepositories.On("Get", ctx.Username, ctx.Repository).
+		Return(&github.Repository{DefaultBranch: github.String("master")}, nil, nil)
+
 	// Mock the milestones API.
 	milestones := []*github.Milestone{
 		{
@@ -71,3 +75,25 @@ func TestAutoMilestone(t *testing.T) {
 	}
 	test.AssertExpectations(clt, t)
 }
+
+func TestAutoMilestoneRejectsBeforeAPICalls(t *testing.T) {
+	clt, ctx := makeContext()
+	operation := &versionMilestoneOperation{}
+
+	// Unmerged pull requests and pull requests with a milestone are rejected without retrieving the
+	// default branch of the repository.
+	unmerged := test.NewPullRequestBuilder(test.IssueNumber).
+		Merged(false).
+		BaseBranch(ctx.Username, ctx.Repository, "master", test.CommitSHA[1]).Value
+	milestoned := test.NewPullRequestBuilder(test.IssueNumber).
+		Merged(true).
+		BaseBranch(ctx.Username, ctx.Repository, "master", test.CommitSHA[1]).Value
+	milestoned.Milestone = &github.Milestone{Number: github.Int(1), Title: github.String("old-version")}
+
+	for _, pullr := range []*github.PullRequest{unmerged, milestoned} {
+		if res, _, err := operation.Filter(ctx, gh.MakePullRequestItem(pullr)); err != nil || res != operations.Reject {
+			t.Fatalf("Expected pull request to be rejected, got %v (error %v)", res, err)
+		}
+	}
+	test.AssertExpectations(clt, t)
+}
@@ -0,0 +1,66 @@
+package operations
+
+import (
+	"encoding/json"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+// Event is a GitHub event which triggered an operation. Attributes which aren't part of the event
+// are nil (e.g., Label is only set for label related actions).
+type Event struct {
+	// Name is the GitHub event type (e.g., "pull_request_review").
+	Name string
+
+	// Action is the action that was performed (e.g., "labeled"), which is empty for events such as
+	// "push".
+	Action string
+
+	// Sender is the user who triggered the event.
+	Sender *github.User
+
+	// Label is the label which was added or removed, or the label which was created or edited for
+	// "label" events.
+	Label *github.Label
+
+	// Comment is the comment which was created, edited, or deleted.
+	Comment *github.IssueComment
+
+	// Changes holds the previous values of the attributes which were edited.
+	Changes *github.EditChange
+
+	// Payload is the raw JSON payload of the event.
+	Payload json.RawMessage
+}
+
+// NewEvent parses the payload of a GitHub event.
+func NewEvent(name string, payload []byte) (*Event, error) {
+	var m struct {
+		Action  string               `json:"action"`
+		Sender  *github.User         `json:"sender"`
+		Label   *github.Label        `json:"label"`
+		Comment *github.IssueComment `json:"comment"`
+		Changes *github.EditChange   `json:"changes"`
+	}
+	if err := json.Unmarshal(payload, &m); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %q event", name)
+	}
+	return &Event{
+		Name:    name,
+		Action:  m.Action,
+		Sender:  m.Sender,
+		Label:   m.Label,
+		Comment: m.Comment,
+		Changes: m.Changes,
+		Payload: payload,
+	}, nil
+}
+
+// SenderLogin returns the login of the user who triggered the event, or an empty string.
+func (e *Event) SenderLogin() string {
+	if e == nil || e.Sender == nil || e.Sender.Login == nil {
+		return ""
+	}
+	return *e.Sender.Login
+}
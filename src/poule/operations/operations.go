@@ -2,6 +2,7 @@ package operations
 
 import (
 	"poule/gh"
+	"poule/runner/state"
 
 	"github.com/google/go-github/github"
 )
@@ -16,6 +17,17 @@ type Context struct {
 
 	// Repository is the name of the GitHub repository.
 	Repository string
+
+	// State is the history of applied operations. It may be nil when no store is configured.
+	State state.Store
+
+	// Event is the GitHub event which triggered the operation. It is nil when the operation wasn't
+	// triggered by an event (e.g., when run from the command line or on a schedule).
+	Event *Event
+
+	// Permissions caches the lookups of users permissions and memberships, and is shared between
+	// operations. It may be nil, in which case filters cache lookups on their own.
+	Permissions *gh.PermissionCache
 }
 
 // FilterResult describes the result of an operation filter.
@@ -81,3 +93,11 @@ type Operation interface {
 	// when listing pull requests for the specified context.
 	PullRequestListOptions(*Context) *github.PullRequestListOptions
 }
+
+// RecordedOperation is optionally implemented by operations which need their application to be
+// recorded in the state store under a more specific name than their type (for example, to
+// distinguish between the different actions of a same operation).
+type RecordedOperation interface {
+	// RecordName returns the name under which the application of the operation is recorded.
+	RecordName() string
+}
@@ -0,0 +1,408 @@
+package settings
+
+import (
+	"fmt"
+	"strings"
+
+	"poule/gh"
+	"poule/operations"
+)
+
+// Filter expressions combine filters using boolean operators, for example:
+//
+//	is:pr and (labels:area/network or labels:area/swarm) and not author:dependabot and age>30d
+//
+// Leaves have the form `type:value`, where type is any of the filter types supported by MakeFilter,
+// or `type<value`, `type>value`, and `type=value` for filters which compare a quantity. Leaves can
+// be combined with `and` (which is implied between consecutive leaves), `or`, `not`, and grouped
+// using parentheses. Values containing spaces or parentheses must be double quoted.
+
+// ParseError is the error returned when a filter expression is malformed.
+type ParseError struct {
+	Expression string
+	Position   int
+	Message    string
+}
+
+// Error returns a string representation of the parse error.
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("invalid filter expression %q at position %d: %s", e.Expression, e.Position+1, e.Message)
+}
+
+// ParseFilterExpression parses a filter expression into a Filter.
+func ParseFilterExpression(expression string) (*Filter, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+	p := &expressionParser{expression: expression, tokens: tokens}
+	if p.done() {
+		return nil, p.errorf(len(expression), "empty expression")
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, p.errorf(p.peek().pos, "unexpected %q", p.peek().text)
+	}
+	return asFilter(expressionFilter{root}), nil
+}
+
+// expressionFilter is a filtering strategy defined by a filter expression.
+type expressionFilter struct {
+	root filterNode
+}
+
+// ApplyItem applies the filter expression to the specified item.
+func (f expressionFilter) ApplyItem(context operations.Context, item *gh.Item) bool {
+	return f.root.apply(context, item)
+}
+
+// String returns a string representation of the filter
+func (f expressionFilter) String() string {
+	return fmt.Sprintf("ExpressionFilter(%s)", f.root)
+}
+
+// filterNode is a node of the abstract syntax tree of a filter expression.
+type filterNode interface {
+	// apply returns whether the node accepts the specified item.
+	apply(context operations.Context, item *gh.Item) bool
+
+	// excludes returns whether the node rejects all pull requests (or all issues).
+	excludes(pullRequests bool) bool
+
+	String() string
+}
+
+type leafNode struct {
+	filter *Filter
+}
+
+func (n leafNode) apply(context operations.Context, item *gh.Item) bool {
+	return n.filter.Apply(context, item)
+}
+
+func (n leafNode) excludes(pullRequests bool) bool {
+	f, ok := n.filter.Strategy.(IsFilter)
+	return ok && f.PullRequestOnly != pullRequests
+}
+
+func (n leafNode) String() string {
+	return n.filter.Strategy.String()
+}
+
+type notNode struct {
+	child filterNode
+}
+
+func (n notNode) apply(context operations.Context, item *gh.Item) bool {
+	return !n.child.apply(context, item)
+}
+
+func (n notNode) excludes(pullRequests bool) bool {
+	// Only the negation of a type filter is known to exclude an item type.
+	if leaf, ok := n.child.(leafNode); ok {
+		f, ok := leaf.filter.Strategy.(IsFilter)
+		return ok && f.PullRequestOnly == pullRequests
+	}
+	return false
+}
+
+func (n notNode) String() string {
+	return fmt.Sprintf("not %s", n.child)
+}
+
+type andNode struct {
+	children []filterNode
+}
+
+func (n andNode) apply(context operations.Context, item *gh.Item) bool {
+	for _, child := range n.children {
+		if !child.apply(context, item) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n andNode) excludes(pullRequests bool) bool {
+	for _, child := range n.children {
+		if child.excludes(pullRequests) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n andNode) String() string {
+	return joinNodes(n.children, " and ")
+}
+
+type orNode struct {
+	children []filterNode
+}
+
+func (n orNode) apply(context operations.Context, item *gh.Item) bool {
+	for _, child := range n.children {
+		if child.apply(context, item) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n orNode) excludes(pullRequests bool) bool {
+	for _, child := range n.children {
+		if !child.excludes(pullRequests) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n orNode) String() string {
+	return joinNodes(n.children, " or ")
+}
+
+func joinNodes(nodes []filterNode, sep string) string {
+	s := make([]string, len(nodes))
+	for i, node := range nodes {
+		s[i] = node.String()
+	}
+	return "(" + strings.Join(s, sep) + ")"
+}
+
+// Lexing.
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenLeftParen
+	tokenRightParen
+	tokenAnd
+	tokenOr
+	tokenNot
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func tokenize(expression string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expression); {
+		switch c := expression[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokenLeftParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokenRightParen, ")", i})
+			i++
+		default:
+			start, word, quoted := i, []byte{}, false
+			for i < len(expression) && !strings.ContainsRune(" \t\n\r()", rune(expression[i])) {
+				if expression[i] != '"' {
+					word = append(word, expression[i])
+					i++
+					continue
+				}
+
+				// Read a quoted section, in which backslash escapes the next character.
+				quoted, i = true, i+1
+				for ; i < len(expression) && expression[i] != '"'; i++ {
+					if expression[i] == '\\' && i+1 < len(expression) {
+						i++
+					}
+					word = append(word, expression[i])
+				}
+				if i == len(expression) {
+					return nil, &ParseError{expression, start, "unterminated quoted value"}
+				}
+				i++
+			}
+			tokens = append(tokens, makeWordToken(string(word), start, quoted))
+		}
+	}
+	return tokens, nil
+}
+
+func makeWordToken(word string, pos int, quoted bool) token {
+	if !quoted {
+		switch strings.ToLower(word) {
+		case "and":
+			return token{tokenAnd, word, pos}
+		case "or":
+			return token{tokenOr, word, pos}
+		case "not":
+			return token{tokenNot, word, pos}
+		}
+	}
+	return token{tokenWord, word, pos}
+}
+
+// Parsing.
+
+type expressionParser struct {
+	expression string
+	tokens     []token
+	index      int
+}
+
+func (p *expressionParser) done() bool {
+	return p.index >= len(p.tokens)
+}
+
+func (p *expressionParser) peek() token {
+	return p.tokens[p.index]
+}
+
+func (p *expressionParser) errorf(pos int, format string, args ...interface{}) error {
+	return &ParseError{p.expression, pos, fmt.Sprintf(format, args...)}
+}
+
+// parseOr parses: and ("or" and)*
+func (p *expressionParser) parseOr() (filterNode, error) {
+	node, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	children := []filterNode{node}
+	for !p.done() && p.peek().kind == tokenOr {
+		p.index++
+		node, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, node)
+	}
+	if len(children) == 1 {
+		return children[0], nil
+	}
+	return orNode{children}, nil
+}
+
+// parseAnd parses: unary (["and"] unary)*
+func (p *expressionParser) parseAnd() (filterNode, error) {
+	node, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	children := []filterNode{node}
+	for !p.done() {
+		switch p.peek().kind {
+		case tokenAnd:
+			p.index++
+		case tokenWord, tokenNot, tokenLeftParen:
+		default:
+			return makeAndNode(children), nil
+		}
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, node)
+	}
+	return makeAndNode(children), nil
+}
+
+func makeAndNode(children []filterNode) filterNode {
+	if len(children) == 1 {
+		return children[0]
+	}
+	return andNode{children}
+}
+
+// parseUnary parses: "not" unary | "(" or ")" | leaf
+func (p *expressionParser) parseUnary() (filterNode, error) {
+	if p.done() {
+		return nil, p.errorf(len(p.expression), "unexpected end of expression")
+	}
+	switch tok := p.peek(); tok.kind {
+	case tokenNot:
+		p.index++
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node}, nil
+	case tokenLeftParen:
+		p.index++
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.done() || p.peek().kind != tokenRightParen {
+			return nil, p.errorf(tok.pos, "unbalanced parenthesis")
+		}
+		p.index++
+		return node, nil
+	case tokenWord:
+		p.index++
+		return p.parseLeaf(tok)
+	default:
+		return nil, p.errorf(tok.pos, "unexpected %q", tok.text)
+	}
+}
+
+// parseLeaf parses a `type:value` or `type<op>value` filter.
+func (p *expressionParser) parseLeaf(tok token) (filterNode, error) {
+	i := strings.IndexAny(tok.text, ":<>=")
+	if i <= 0 || i == len(tok.text)-1 {
+		return nil, p.errorf(tok.pos, "expected filter of the form \"type:value\", got %q", tok.text)
+	}
+	filterType, operator, value := tok.text[:i], tok.text[i], tok.text[i+1:]
+	if operator == ':' {
+		return p.makeLeaf(tok, filterType, value)
+	}
+	return p.makeComparisonLeaf(tok, filterType, operator, value)
+}
+
+func (p *expressionParser) makeLeaf(tok token, filterType, value string) (filterNode, error) {
+	filter, err := MakeFilter(filterType, value)
+	if err != nil {
+		return nil, p.errorf(tok.pos, "%v", err)
+	}
+	return leafNode{filter}, nil
+}
+
+// countFilterTypes are the filters which compare a count, and take the comparison operator as the
+// first character of their value (e.g., "comments:>10").
+var countFilterTypes = map[string]bool{
+	"additions":     true,
+	"changed-files": true,
+	"comments":      true,
+	"deletions":     true,
+}
+
+// makeComparisonLeaf creates a leaf for comparison operators, which are only supported by filters
+// comparing a count or a duration.
+func (p *expressionParser) makeComparisonLeaf(tok token, filterType string, operator byte, value string) (filterNode, error) {
+	switch {
+	case filterType == "age" || filterType == "updated":
+		// Duration filters accept items older (or inactive for longer) than their value.
+		switch operator {
+		case '>':
+			return p.makeLeaf(tok, filterType, value)
+		case '<':
+			node, err := p.makeLeaf(tok, filterType, value)
+			if err != nil {
+				return nil, err
+			}
+			return notNode{node}, nil
+		}
+		return nil, p.errorf(tok.pos, "operator %q is not supported for %q filter", operator, filterType)
+	case countFilterTypes[filterType]:
+		return p.makeLeaf(tok, filterType, string(operator)+value)
+	case filterTypes[filterType] == nil:
+		_, err := MakeFilter(filterType, value)
+		return nil, p.errorf(tok.pos, "%v", err)
+	default:
+		return nil, p.errorf(tok.pos, "operator %q is not supported for %q filter", operator, filterType)
+	}
+}
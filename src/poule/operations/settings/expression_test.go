@@ -0,0 +1,89 @@
+package settings
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"poule/operations"
+	"poule/test"
+)
+
+func TestFilterExpression(t *testing.T) {
+	context := operations.Context{Client: &test.Client{}}
+	old := time.Now().Add(-60 * 24 * time.Hour)
+	recent := time.Now().Add(-24 * time.Hour)
+
+	makeIssue := func(createdAt time.Time, labels ...string) *test.IssueBuilder {
+		builder := test.NewIssueBuilder(test.IssueNumber).Labels(labels)
+		builder.Value.CreatedAt = &createdAt
+		return builder
+	}
+	for _, tc := range []struct {
+		expression string
+		builder    *test.IssueBuilder
+		expected   bool
+	}{
+		{"is:issue labels:bug", makeIssue(old, "bug"), true},
+		{"is:issue and (labels:area/network or labels:area/swarm)", makeIssue(old, "area/swarm"), true},
+		{"is:issue and (labels:area/network or labels:area/swarm)", makeIssue(old, "area/builder"), false},
+		{"not labels:bug", makeIssue(old, "bug"), false},
+		{"NOT (labels:bug OR labels:feature)", makeIssue(old, "docs"), true},
+		{"age>30d", makeIssue(old), true},
+		{"age>30d", makeIssue(recent), false},
+		{"age<30d", makeIssue(recent), true},
+		{"labels:bug or labels:feature and age>30d", makeIssue(recent, "bug"), true},
+		{`labels:"kind/bug" and not labels:"status/needs info"`, makeIssue(old, "kind/bug", "status/needs info"), false},
+	} {
+		filter, err := ParseFilterExpression(tc.expression)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %q: %v", tc.expression, err)
+		}
+		item := tc.builder.Item()
+		if result := filter.Apply(context, &item); result != tc.expected {
+			t.Fatalf("Expected %q to return %t, got %t (%s)", tc.expression, tc.expected, result, filter.Strategy)
+		}
+	}
+}
+
+func TestFilterExpressionErrors(t *testing.T) {
+	for expression, expected := range map[string]string{
+		"":                          "empty expression",
+		"is:pr and":                 "unexpected end of expression",
+		"(is:pr or labels:bug":      "unbalanced parenthesis",
+		"is:pr)":                    `unexpected ")"`,
+		"is:pr or or labels:bug":    `unexpected "or"`,
+		"labels":                    `expected filter of the form "type:value"`,
+		"unknown:value":             `unknown filter type "unknown"`,
+		`contains:"unterminated`:    "unterminated quoted value",
+		"age=3d":                    `operator '=' is not supported`,
+		"labels>foo":                `operator '>' is not supported for "labels" filter`,
+		"unknown<3":                 `unknown filter type "unknown"`,
+		"is:pr and comments:>x":     `invalid value ">x" for "comments" filter`,
+		"labels:bug and is:nothing": `position 16`,
+	} {
+		_, err := ParseFilterExpression(expression)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("Expected error containing %q for %q, got %v", expected, expression, err)
+		}
+	}
+}
+
+func TestFilterExpressionItemTypes(t *testing.T) {
+	for expression, expected := range map[string][2]bool{
+		"is:pr and labels:bug":           {false, true},
+		"is:issue or labels:bug":         {true, true},
+		"not is:issue":                   {false, true},
+		"(is:pr and age>3d) or is:issue": {true, true},
+		"is:issue and (is:pr or is:pr)":  {false, false},
+	} {
+		filter, err := ParseFilterExpression(expression)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %q: %v", expression, err)
+		}
+		filters := Filters{filter}
+		if FilterIncludesIssues(filters) != expected[0] || FilterIncludesPullRequests(filters) != expected[1] {
+			t.Fatalf("Unexpected item types for %q", expression)
+		}
+	}
+}
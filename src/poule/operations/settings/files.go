@@ -0,0 +1,203 @@
+package settings
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"poule/gh"
+	"poule/operations"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+// FilesFilter filters pull requests based on the files they modify. Patterns are globs matched
+// against the full path of files, in which `*` and `?` don't match path separators and `**`
+// matches any number of directories. Patterns prefixed with `!` exclude files. The filter passes
+// if any modified file matches an including pattern (or if there are only excluding patterns) and
+// no excluding pattern. Issues never pass the filter.
+type FilesFilter struct {
+	patterns []string
+	include  []*regexp.Regexp
+	exclude  []*regexp.Regexp
+}
+
+func makeFilesFilter(value string) (*Filter, error) {
+	patterns, err := splitFilterValues("files", value, `comma separated globs such as "daemon/**,!**/*_test.go"`)
+	if err != nil {
+		return nil, err
+	}
+	f := FilesFilter{patterns: patterns}
+	for _, pattern := range patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		regex, err := CompileGlob(strings.TrimPrefix(pattern, "!"))
+		if err != nil {
+			return nil, errors.Errorf("invalid pattern %q for \"files\" filter: %v", pattern, err)
+		}
+		if negated {
+			f.exclude = append(f.exclude, regex)
+		} else {
+			f.include = append(f.include, regex)
+		}
+	}
+	return asFilter(f), nil
+}
+
+// ApplyItem applies the filter to the specified item.
+func (f FilesFilter) ApplyItem(context operations.Context, item *gh.Item) bool {
+	if !item.IsPullRequest() {
+		return false
+	}
+	files, err := item.GetFiles(context.Client)
+	if err != nil {
+		logrus.Errorf("failed to list files of pull request #%d: %v", item.Number(), err)
+		return false
+	}
+	for _, file := range files {
+		if file.Filename != nil && f.matches(*file.Filename) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f FilesFilter) matches(filename string) bool {
+	for _, regex := range f.exclude {
+		if regex.MatchString(filename) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, regex := range f.include {
+		if regex.MatchString(filename) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns a string representation of the filter
+func (f FilesFilter) String() string {
+	return fmt.Sprintf("FilesFilter(%s)", strings.Join(f.patterns, ","))
+}
+
+// CompileGlob translates a glob pattern into a regular expression. A trailing slash designates
+// all files under a directory.
+func CompileGlob(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, errors.New("empty pattern")
+	}
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+	var expr bytes.Buffer
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case c == '*':
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
+
+// SizeFilter filters pull requests based on the size of their diff. Issues never pass the filter.
+type SizeFilter struct {
+	attribute string
+	filtValue string
+	predicate func(int) bool
+}
+
+func makeSizeFilter(attribute, value string) (*Filter, error) {
+	predicate, err := parseCountPredicate(attribute, value)
+	if err != nil {
+		return nil, err
+	}
+	return asFilter(SizeFilter{
+		attribute: attribute,
+		filtValue: value,
+		predicate: predicate,
+	}), nil
+}
+
+func makeAdditionsFilter(value string) (*Filter, error) {
+	return makeSizeFilter("additions", value)
+}
+
+func makeChangedFilesFilter(value string) (*Filter, error) {
+	return makeSizeFilter("changed-files", value)
+}
+
+func makeDeletionsFilter(value string) (*Filter, error) {
+	return makeSizeFilter("deletions", value)
+}
+
+// ApplyItem applies the filter to the specified item. Diff statistics aren't part of pull request
+// listings: the pull request is retrieved once and kept in the item.
+func (f SizeFilter) ApplyItem(context operations.Context, item *gh.Item) bool {
+	if !item.IsPullRequest() {
+		return false
+	}
+	value := f.value(item.PullRequest)
+	if value == nil {
+		if !refreshPullRequest(context, item) {
+			return false
+		}
+		if value = f.value(item.PullRequest); value == nil {
+			return false
+		}
+	}
+	return f.predicate(*value)
+}
+
+func (f SizeFilter) value(pr *github.PullRequest) *int {
+	switch f.attribute {
+	case "additions":
+		return pr.Additions
+	case "deletions":
+		return pr.Deletions
+	default:
+		return pr.ChangedFiles
+	}
+}
+
+// String returns a string representation of the filter
+func (f SizeFilter) String() string {
+	return fmt.Sprintf("SizeFilter(%s%s)", f.attribute, f.filtValue)
+}
+
+// refreshPullRequest retrieves the complete pull request object of an item, which holds attributes
+// not returned by listings, and returns whether it succeeded.
+func refreshPullRequest(context operations.Context, item *gh.Item) bool {
+	if err := item.RefreshPullRequest(context.Client); err != nil {
+		logrus.Error(err)
+		return false
+	}
+	return true
+}
+
+// completePullRequest retrieves the pull request object of a partial item, such as a search result,
+// and returns whether it succeeded.
+func completePullRequest(context operations.Context, item *gh.Item) bool {
+	if err := item.CompletePullRequest(context.Client); err != nil {
+		logrus.Error(err)
+		return false
+	}
+	return true
+}
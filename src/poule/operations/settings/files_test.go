@@ -0,0 +1,107 @@
+package settings
+
+import (
+	"testing"
+
+	"poule/operations"
+	"poule/test"
+
+	"github.com/google/go-github/github"
+)
+
+func TestCompileGlob(t *testing.T) {
+	for pattern, cases := range map[string]map[string]bool{
+		"daemon/**": {
+			"daemon/daemon.go":        true,
+			"daemon/logger/logger.go": true,
+			"daemonize.go":            false,
+			"cmd/daemon/main.go":      false,
+		},
+		"**/*_test.go": {
+			"daemon_test.go":            true,
+			"daemon/logger/log_test.go": true,
+			"daemon/logger/log.go":      false,
+		},
+		"docs/": {
+			"docs/index.md":     true,
+			"docs/api/v1.25.md": true,
+			"README.md":         false,
+		},
+		"*.md": {
+			"README.md":     true,
+			"docs/index.md": false,
+		},
+		"api/v1.2?.go": {
+			"api/v1.25.go":  true,
+			"api/v1.2/x.go": false,
+		},
+	} {
+		regex, err := CompileGlob(pattern)
+		if err != nil {
+			t.Fatalf("Unexpected error compiling %q: %v", pattern, err)
+		}
+		for filename, expected := range cases {
+			if result := regex.MatchString(filename); result != expected {
+				t.Fatalf("Expected %q to match %q: %t, got %t", pattern, filename, expected, result)
+			}
+		}
+	}
+}
+
+func TestFilesAndSizeFilters(t *testing.T) {
+	clt := &test.Client{}
+	context := operations.Context{
+		Client:     clt,
+		Username:   test.Username,
+		Repository: test.Repository,
+	}
+
+	// The file list is paginated, and retrieved only once for all filters.
+	clt.MockPullRequests.On("ListFiles", test.Username, test.Repository, test.IssueNumber, &github.ListOptions{Page: 1, PerPage: 100}).Return([]*github.CommitFile{
+		{Filename: github.String("daemon/daemon.go")},
+		{Filename: github.String("daemon/daemon_test.go")},
+	}, &github.Response{NextPage: 2}, nil).Once()
+	clt.MockPullRequests.On("ListFiles", test.Username, test.Repository, test.IssueNumber, &github.ListOptions{Page: 2, PerPage: 100}).Return([]*github.CommitFile{
+		{Filename: github.String("docs/reference/commandline/dockerd.md")},
+	}, &github.Response{}, nil).Once()
+
+	// Diff statistics are only part of the complete pull request object.
+	full := test.NewPullRequestBuilder(test.IssueNumber).
+		BaseBranch(test.Username, test.Repository, "master", test.CommitSHA[0]).
+		Value
+	full.Additions, full.Deletions, full.ChangedFiles = github.Int(120), github.Int(4), github.Int(3)
+	clt.MockPullRequests.On("Get", test.Username, test.Repository, test.IssueNumber).Return(full, nil, nil).Once()
+
+	item := test.NewPullRequestBuilder(test.IssueNumber).
+		BaseBranch(test.Username, test.Repository, "master", test.CommitSHA[0]).
+		Item()
+	for expression, expected := range map[string]bool{
+		"files:daemon/**":                       true,
+		"files:!**/*_test.go":                   true,
+		"files:daemon/**,!**/*_test.go":         true,
+		"files:**/*_test.go,!daemon/**":         false,
+		"files:docs/ and files:!docs/":          true,
+		"files:api/**":                          false,
+		"additions>100 and deletions<10":        true,
+		"changed-files=3 and not additions>500": true,
+		"changed-files:>3":                      false,
+	} {
+		filter, err := ParseFilterExpression(expression)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %q: %v", expression, err)
+		}
+		if result := filter.Apply(context, &item); result != expected {
+			t.Fatalf("Expected %q to return %t, got %t", expression, expected, result)
+		}
+	}
+	test.AssertExpectations(clt, t)
+
+	// Pull request filters never pass for issues.
+	issue := test.NewIssueBuilder(test.IssueNumber).Item()
+	for _, expression := range []string{"files:**", "additions>0"} {
+		filter, _ := ParseFilterExpression(expression)
+		if filter.Apply(context, &issue) {
+			t.Fatalf("Expected %q to reject issues", expression)
+		}
+	}
+}
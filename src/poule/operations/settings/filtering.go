@@ -2,7 +2,9 @@ package settings
 
 import (
 	"fmt"
+	"path"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -25,18 +27,101 @@ var FilteringFlag = cli.StringSliceFlag{
 	Usage: "filter based on item attributes",
 }
 
-// ParseCliFilters reads filter definitions from the command line.
-func ParseCliFilters(c *cli.Context) ([]*Filter, error) {
-	value, err := NewMultiValuedKeysFromSlice(c.StringSlice(filterFlagName))
-	if err != nil {
-		return nil, err
+// legacyFilterRegexp matches command-line filters of the form `type:value`.
+var legacyFilterRegexp = regexp.MustCompile(`(?s)^(~?[a-z-]+):(.*)$`)
+
+// ParseCliFilters reads filter definitions from the command line, all of which must be satisfied.
+// Each occurence of the flag is either a single `type:value` filter, the value of which is taken
+// literally (e.g., `contains:(a|b)`), or a filter expression.
+func ParseCliFilters(c *cli.Context) (Filters, error) {
+	filters := Filters{}
+	for _, value := range c.StringSlice(filterFlagName) {
+		var filter *Filter
+		var err error
+		if filterType, filterValue, ok := parseLegacyFilter(value); ok {
+			filter, err = MakeFilter(filterType, filterValue)
+		} else {
+			filter, err = ParseFilterExpression(value)
+		}
+		if err != nil {
+			return Filters{}, err
+		}
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+// parseLegacyFilter splits a command-line filter of the form `type:value` which predates filter
+// expressions. Filters using expression operators, or made of several filters, are not.
+func parseLegacyFilter(value string) (string, string, bool) {
+	m := legacyFilterRegexp.FindStringSubmatch(value)
+	if m == nil || filterTypes[m[1]] == nil {
+		return "", "", false
+	}
+	for i, word := range strings.Fields(m[2]) {
+		if i == 0 {
+			continue
+		}
+		switch strings.ToLower(word) {
+		case "and", "or", "not":
+			return "", "", false
+		}
+		if filterType := legacyFilterRegexp.FindStringSubmatch(word); filterType != nil && filterTypes[filterType[1]] != nil {
+			return "", "", false
+		}
 	}
-	return ParseConfigurationFilters(value.ToSerializedFormat())
+	return m[1], m[2], true
 }
 
 // ParseConfigurationFilters reads filter definitions from the serialized
-// configuration format.
-func ParseConfigurationFilters(values map[string]interface{}) (Filters, error) {
+// configuration format, which is either:
+//
+//   - A mapping of filter types to their respective values, all of which must be satisfied.
+//   - A filter expression.
+//   - A sequence of filter expressions, all of which must be satisfied.
+func ParseConfigurationFilters(values interface{}) (Filters, error) {
+	switch values := values.(type) {
+	case nil:
+		return Filters{}, nil
+	case string:
+		return parseFilterExpressions([]string{values})
+	case []string:
+		return parseFilterExpressions(values)
+	case []interface{}:
+		expressions := make([]string, len(values))
+		for i, v := range values {
+			s, ok := v.(string)
+			if !ok {
+				return Filters{}, errors.Errorf("non-string \"%v\" in filter expressions", v)
+			}
+			expressions[i] = s
+		}
+		return parseFilterExpressions(expressions)
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for k, v := range values {
+			m[fmt.Sprint(k)] = v
+		}
+		return parseFilterMap(m)
+	case map[string]interface{}:
+		return parseFilterMap(values)
+	}
+	return Filters{}, errors.Errorf("invalid data type \"%#v\" for filters", values)
+}
+
+func parseFilterExpressions(expressions []string) (Filters, error) {
+	filters := Filters{}
+	for _, expression := range expressions {
+		filter, err := ParseFilterExpression(expression)
+		if err != nil {
+			return Filters{}, err
+		}
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+func parseFilterMap(values map[string]interface{}) (Filters, error) {
 	filters := Filters{}
 	for filterType, rawValue := range values {
 		value, err := filterValue(rawValue)
@@ -55,8 +140,9 @@ func ParseConfigurationFilters(values map[string]interface{}) (Filters, error) {
 // Filters is a collection of Filter instances.
 type Filters []*Filter
 
-// Apply returns true only if all filters accept the item.
-func (f Filters) Apply(context operations.Context, item gh.Item) bool {
+// Apply returns true only if all filters accept the item. The issue related to a pull request is
+// retrieved at most once and kept in the item for subsequent filters and operations.
+func (f Filters) Apply(context operations.Context, item *gh.Item) bool {
 	for _, filter := range f {
 		if !filter.Apply(context, item) {
 			return false
@@ -72,7 +158,10 @@ type Filter struct {
 
 // Apply returns whether the internal strategy is accepting or rejecting the
 // specified GitHub item.
-func (f *Filter) Apply(context operations.Context, item gh.Item) bool {
+func (f *Filter) Apply(context operations.Context, item *gh.Item) bool {
+	if f, ok := f.Strategy.(itemFilter); ok {
+		return f.ApplyItem(context, item)
+	}
 	switch {
 	case item.IsIssue():
 		if f, ok := f.Strategy.(issueFilter); ok {
@@ -82,12 +171,28 @@ func (f *Filter) Apply(context operations.Context, item gh.Item) bool {
 		if f, ok := f.Strategy.(pullRequestFilter); ok {
 			return f.ApplyPullRequest(context, item.PullRequest)
 		}
+		// Strategies which only apply to issues are given the issue related to the pull request,
+		// which holds attributes such as labels.
+		if f, ok := f.Strategy.(issueFilter); ok {
+			issue, err := item.GetRelatedIssue(context.Client)
+			if err != nil {
+				logrus.Errorf("failed to retrieve issue for pull request #%d: %v", item.Number(), err)
+				return false
+			}
+			return f.ApplyIssue(context, issue)
+		}
 	default:
 		panic("unreachable")
 	}
 	return true
 }
 
+// itemFilter is a filtering strategy that applies to any GitHub item.
+type itemFilter interface {
+	ApplyItem(operations.Context, *gh.Item) bool
+	String() string
+}
+
 // issueFilter is a filtering strategy that applies to GitHub issues.
 type issueFilter interface {
 	ApplyIssue(operations.Context, *github.Issue) bool
@@ -101,21 +206,42 @@ type pullRequestFilter interface {
 	String() string
 }
 
+// filterTypes maps filter type identifiers to their constructor.
+var filterTypes = map[string]func(string) (*Filter, error){
+	"additions":     makeAdditionsFilter,
+	"age":           makeAgeFilter,
+	"assigned":      makeAssignedFilter,
+	"assignee":      makeAssigneeFilter,
+	"author":        makeAuthorFilter,
+	"base":          makeBaseFilter,
+	"changed-files": makeChangedFilesFilter,
+	"comments":      makeCommentsFilter,
+	"contains":      makeContainsFilter,
+	"deletions":     makeDeletionsFilter,
+	"draft":         makeDraftFilter,
+	"event-label":   makeEventLabelFilter,
+	"files":         makeFilesFilter,
+	"is":            makeIsFilter,
+	"labels":        makeWithLabelsFilter,
+	"~labels":       makeWithoutLabelsFilter,
+	"mergeable":     makeMergeableFilter,
+	"milestone":     makeMilestoneFilter,
+	"sender":        makeSenderFilter,
+	"state":         makeStateFilter,
+	"updated":       makeUpdatedFilter,
+}
+
 // MakeFilter creates a filter from a type identifier and a string value.
 func MakeFilter(filterType, value string) (*Filter, error) {
-	typeMapping := map[string]func(string) (*Filter, error){
-		"age":      makeAgeFilter,
-		"assigned": makeAssignedFilter,
-		"comments": makeCommentsFilter,
-		"is":       makeIsFilter,
-		"labels":   makeWithLabelsFilter,
-		"~labels":  makeWithoutLabelsFilter,
-		"contains": makeContainsFilter,
-	}
-	if constructor, ok := typeMapping[filterType]; ok {
+	if constructor, ok := filterTypes[filterType]; ok {
 		return constructor(value)
 	}
-	return nil, errors.Errorf("unknown filter type %q", filterType)
+	types := make([]string, 0, len(filterTypes))
+	for t := range filterTypes {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return nil, errors.Errorf("unknown filter type %q (supported types: %s)", filterType, strings.Join(types, ", "))
 }
 
 // ContainsFilter implements a filter for regexps that checks comments.
@@ -224,30 +350,34 @@ type CommentsFilter struct {
 }
 
 func makeCommentsFilter(value string) (*Filter, error) {
+	predicate, err := parseCountPredicate("comments", value)
+	if err != nil {
+		return nil, err
+	}
+	return asFilter(CommentsFilter{
+		filtValue: value,
+		predicate: predicate,
+	}), nil
+}
+
+// parseCountPredicate parses a comparison of the form "<N", "=N", or ">N".
+func parseCountPredicate(filterType, value string) (func(int) bool, error) {
 	var count int
 	var operation rune
 	if n, err := fmt.Sscanf(value, "%c%d", &operation, &count); n != 2 || err != nil {
-		return nil, errors.Errorf("invalid value %q for \"comments\" filter", value)
+		return nil, errors.Errorf("invalid value %q for %q filter", value, filterType)
 	}
 
-	var predicate func(int) bool
 	switch operation {
 	case '<':
-		predicate = func(n int) bool { return n < count }
-		break
+		return func(n int) bool { return n < count }, nil
 	case '=':
-		predicate = func(n int) bool { return n == count }
-		break
+		return func(n int) bool { return n == count }, nil
 	case '>':
-		predicate = func(n int) bool { return n > count }
-		break
+		return func(n int) bool { return n > count }, nil
 	default:
-		return nil, errors.Errorf("invalid operator %c for \"comments\" filter", operation)
+		return nil, errors.Errorf("invalid operator %c for %q filter", operation, filterType)
 	}
-	return asFilter(CommentsFilter{
-		filtValue: value,
-		predicate: predicate,
-	}), nil
 }
 
 // ApplyIssue applies the filter to the specified issue.
@@ -342,6 +472,428 @@ func (f WithoutLabelsFilter) String() string {
 	return fmt.Sprintf("WithoutLabelsFilter(%s)", strings.Join(f.labels, ","))
 }
 
+// AssigneeFilter filters items based on their assignees.
+type AssigneeFilter struct {
+	assignees []string
+}
+
+func makeAssigneeFilter(value string) (*Filter, error) {
+	assignees, err := splitFilterValues("assignee", value, `comma separated logins, or "none"`)
+	if err != nil {
+		return nil, err
+	}
+	return asFilter(AssigneeFilter{assignees}), nil
+}
+
+// ApplyItem applies the filter to the specified item, which passes if any of its assignees is
+// listed. The "none" value designates unassigned items.
+func (f AssigneeFilter) ApplyItem(context operations.Context, item *gh.Item) bool {
+	assignees := item.Assignees()
+	if len(assignees) == 0 && item.Assignee() != nil {
+		assignees = []*github.User{item.Assignee()}
+	}
+	if len(f.assignees) == 1 && f.assignees[0] == "none" {
+		return len(assignees) == 0
+	}
+	for _, assignee := range assignees {
+		if assignee.Login != nil && containsLogin(f.assignees, *assignee.Login) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns a string representation of the filter
+func (f AssigneeFilter) String() string {
+	return fmt.Sprintf("AssigneeFilter(%s)", strings.Join(f.assignees, ","))
+}
+
+// AuthorFilter filters items based on their author, which can be designated by login, by
+// organization ("@org") or team ("@org/team") membership, or as a "first-time-contributor" (i.e.,
+// without any other merged pull request in the repository).
+type AuthorFilter struct {
+	authors []string
+	cache   *gh.PermissionCache
+}
+
+const firstTimeContributor = "first-time-contributor"
+
+func makeAuthorFilter(value string) (*Filter, error) {
+	expected := `comma separated logins, "@org", "@org/team", or "first-time-contributor"`
+	authors, err := splitFilterValues("author", value, expected)
+	if err != nil {
+		return nil, err
+	}
+	for _, author := range authors {
+		if org, team := splitTeam(author); strings.HasPrefix(author, "@") && (org == "" || strings.HasSuffix(author, "/") || strings.Contains(team, "/")) {
+			return nil, errors.Errorf("invalid value %q for \"author\" filter (expected %s)", value, expected)
+		}
+	}
+	return asFilter(AuthorFilter{authors: authors, cache: gh.NewPermissionCache(0)}), nil
+}
+
+// ApplyItem applies the filter to the specified item, which passes if its author matches any of
+// the filter values.
+func (f AuthorFilter) ApplyItem(context operations.Context, item *gh.Item) bool {
+	user := item.User()
+	if user == nil || user.Login == nil {
+		return false
+	}
+	for _, author := range f.authors {
+		matches, err := f.matches(context, item, author, *user.Login)
+		if err != nil {
+			logrus.Errorf("failed to check author of item #%d: %v", item.Number(), err)
+			continue
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+func (f AuthorFilter) matches(context operations.Context, item *gh.Item, author, login string) (bool, error) {
+	switch {
+	case author == firstTimeContributor:
+		return f.isFirstTimeContributor(context, item, login)
+	case strings.HasPrefix(author, "@"):
+		org, team := splitTeam(author)
+		if team == "" {
+			return f.permissions(context).IsMember(context.Client, org, login)
+		}
+		return f.permissions(context).IsTeamMember(context.Client, org, team, login)
+	default:
+		return strings.EqualFold(author, login), nil
+	}
+}
+
+func (f AuthorFilter) isFirstTimeContributor(context operations.Context, item *gh.Item, login string) (bool, error) {
+	repository := context.Username + "/" + context.Repository
+	v, err := f.permissions(context).Lookup("merged:"+repository+":"+login, func() (interface{}, error) {
+		// Two results are enough to know whether any pull request other than the item was merged.
+		query := fmt.Sprintf("repo:%s is:pr is:merged author:%s", repository, login)
+		result, _, err := context.Client.Search().Issues(query, &github.SearchOptions{
+			ListOptions: github.ListOptions{PerPage: 2},
+		})
+		if err != nil {
+			return nil, err
+		}
+		numbers := []int{}
+		for _, issue := range result.Issues {
+			numbers = append(numbers, *issue.Number)
+		}
+		return numbers, nil
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to search merged pull requests of %q", login)
+	}
+	for _, number := range v.([]int) {
+		if number != item.Number() {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// permissions returns the cache shared through the context if any, and the filter's own otherwise.
+func (f AuthorFilter) permissions(context operations.Context) *gh.PermissionCache {
+	if context.Permissions != nil {
+		return context.Permissions
+	}
+	return f.cache
+}
+
+// String returns a string representation of the filter
+func (f AuthorFilter) String() string {
+	return fmt.Sprintf("AuthorFilter(%s)", strings.Join(f.authors, ","))
+}
+
+// BaseFilter filters pull requests based on their base branch. Issues never pass the filter.
+type BaseFilter struct {
+	branch string
+}
+
+func makeBaseFilter(value string) (*Filter, error) {
+	if value == "" {
+		return nil, errors.Errorf("invalid value %q for \"base\" filter (expected a branch name)", value)
+	}
+	return asFilter(BaseFilter{value}), nil
+}
+
+// ApplyItem applies the filter to the specified item.
+func (f BaseFilter) ApplyItem(context operations.Context, item *gh.Item) bool {
+	if !item.IsPullRequest() || !completePullRequest(context, item) {
+		return false
+	}
+	pr := item.PullRequest
+	return pr.Base != nil && pr.Base.Ref != nil && *pr.Base.Ref == f.branch
+}
+
+// String returns a string representation of the filter
+func (f BaseFilter) String() string {
+	return fmt.Sprintf("BaseFilter(%s)", f.branch)
+}
+
+// DraftFilter filters pull requests based on whether they are drafts. Issues never pass the
+// filter.
+type DraftFilter struct {
+	isDraft bool
+}
+
+func makeDraftFilter(value string) (*Filter, error) {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, errors.Errorf("invalid value %q for \"draft\" filter (expected \"true\" or \"false\")", value)
+	}
+	return asFilter(DraftFilter{b}), nil
+}
+
+// ApplyItem applies the filter to the specified item. Draft status isn't part of pull request
+// listings: it is retrieved once and kept in the item.
+func (f DraftFilter) ApplyItem(context operations.Context, item *gh.Item) bool {
+	if !item.IsPullRequest() {
+		return false
+	}
+	isDraft, err := item.IsDraft(context.Client)
+	if err != nil {
+		logrus.Error(err)
+		return false
+	}
+	return isDraft == f.isDraft
+}
+
+// String returns a string representation of the filter
+func (f DraftFilter) String() string {
+	return fmt.Sprintf("DraftFilter(%t)", f.isDraft)
+}
+
+// EventLabelFilter filters items based on the label carried by the triggering event (e.g., the
+// label which was added for a "labeled" action). Items which weren't processed in response to an
+// event carrying a label never pass the filter.
+type EventLabelFilter struct {
+	patterns []string
+}
+
+func makeEventLabelFilter(value string) (*Filter, error) {
+	expected := `comma separated label names or patterns, such as "rebuild/*"`
+	patterns, err := splitFilterValues("event-label", value, expected)
+	if err != nil {
+		return nil, err
+	}
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.Errorf("invalid value %q for \"event-label\" filter (expected %s)", value, expected)
+		}
+	}
+	return asFilter(EventLabelFilter{patterns}), nil
+}
+
+// ApplyItem applies the filter to the specified item, which passes if the label of the triggering
+// event matches any of the patterns.
+func (f EventLabelFilter) ApplyItem(context operations.Context, item *gh.Item) bool {
+	if context.Event == nil || context.Event.Label == nil || context.Event.Label.Name == nil {
+		return false
+	}
+	for _, pattern := range f.patterns {
+		if ok, _ := path.Match(pattern, *context.Event.Label.Name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns a string representation of the filter
+func (f EventLabelFilter) String() string {
+	return fmt.Sprintf("EventLabelFilter(%s)", strings.Join(f.patterns, ","))
+}
+
+// MergeableFilter filters pull requests based on whether they can be merged without conflicts.
+// Issues, as well as pull requests for which GitHub hasn't computed mergeability yet, never pass
+// the filter.
+type MergeableFilter struct {
+	isMergeable bool
+}
+
+func makeMergeableFilter(value string) (*Filter, error) {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, errors.Errorf("invalid value %q for \"mergeable\" filter (expected \"true\" or \"false\")", value)
+	}
+	return asFilter(MergeableFilter{b}), nil
+}
+
+// ApplyItem applies the filter to the specified item. Mergeability isn't part of pull request
+// listings: the pull request is retrieved once and kept in the item.
+func (f MergeableFilter) ApplyItem(context operations.Context, item *gh.Item) bool {
+	if !item.IsPullRequest() {
+		return false
+	}
+	if item.PullRequest.Mergeable == nil && !refreshPullRequest(context, item) {
+		return false
+	}
+	return item.PullRequest.Mergeable != nil && *item.PullRequest.Mergeable == f.isMergeable
+}
+
+// String returns a string representation of the filter
+func (f MergeableFilter) String() string {
+	return fmt.Sprintf("MergeableFilter(%t)", f.isMergeable)
+}
+
+// MilestoneFilter filters items based on the title of their milestone.
+type MilestoneFilter struct {
+	title string
+}
+
+func makeMilestoneFilter(value string) (*Filter, error) {
+	if value == "" {
+		return nil, errors.Errorf("invalid value %q for \"milestone\" filter (expected a milestone title, or \"none\")", value)
+	}
+	return asFilter(MilestoneFilter{value}), nil
+}
+
+// ApplyItem applies the filter to the specified item. The "none" value designates items without a
+// milestone.
+func (f MilestoneFilter) ApplyItem(context operations.Context, item *gh.Item) bool {
+	milestone := item.Milestone()
+	if f.title == "none" {
+		return milestone == nil
+	}
+	return milestone != nil && milestone.Title != nil && *milestone.Title == f.title
+}
+
+// String returns a string representation of the filter
+func (f MilestoneFilter) String() string {
+	return fmt.Sprintf("MilestoneFilter(%s)", f.title)
+}
+
+// SenderFilter filters items based on the user who triggered the event, which can be designated by
+// login, or by organization ("@org") or team ("@org/team") membership. Items which weren't
+// processed in response to an event never pass the filter.
+type SenderFilter struct {
+	author AuthorFilter
+}
+
+func makeSenderFilter(value string) (*Filter, error) {
+	expected := `comma separated logins, "@org", or "@org/team"`
+	senders, err := splitFilterValues("sender", value, expected)
+	if err != nil {
+		return nil, err
+	}
+	for _, sender := range senders {
+		if org, team := splitTeam(sender); sender == firstTimeContributor || strings.HasPrefix(sender, "@") && (org == "" || strings.HasSuffix(sender, "/") || strings.Contains(team, "/")) {
+			return nil, errors.Errorf("invalid value %q for \"sender\" filter (expected %s)", value, expected)
+		}
+	}
+	return asFilter(SenderFilter{AuthorFilter{authors: senders, cache: gh.NewPermissionCache(0)}}), nil
+}
+
+// ApplyItem applies the filter to the specified item, which passes if the sender of the triggering
+// event matches any of the filter values.
+func (f SenderFilter) ApplyItem(context operations.Context, item *gh.Item) bool {
+	if context.Event == nil || context.Event.Sender == nil || context.Event.Sender.Login == nil {
+		return false
+	}
+	for _, sender := range f.author.authors {
+		matches, err := f.author.matches(context, item, sender, *context.Event.Sender.Login)
+		if err != nil {
+			logrus.Errorf("failed to check sender of event for item #%d: %v", item.Number(), err)
+			continue
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns a string representation of the filter
+func (f SenderFilter) String() string {
+	return fmt.Sprintf("SenderFilter(%s)", strings.Join(f.author.authors, ","))
+}
+
+// StateFilter filters items based on their state. Merged pull requests are also closed.
+type StateFilter struct {
+	state string
+}
+
+func makeStateFilter(value string) (*Filter, error) {
+	switch value {
+	case "open", "closed", "merged":
+		return asFilter(StateFilter{value}), nil
+	default:
+		return nil, errors.Errorf("invalid value %q for \"state\" filter (expected \"open\", \"closed\", or \"merged\")", value)
+	}
+}
+
+// ApplyItem applies the filter to the specified item.
+func (f StateFilter) ApplyItem(context operations.Context, item *gh.Item) bool {
+	if f.state == "merged" {
+		if !item.IsPullRequest() || !completePullRequest(context, item) {
+			return false
+		}
+		pr := item.PullRequest
+		return (pr.Merged != nil && *pr.Merged) || pr.MergedAt != nil
+	}
+	return item.State() == f.state
+}
+
+// String returns a string representation of the filter
+func (f StateFilter) String() string {
+	return fmt.Sprintf("StateFilter(%s)", f.state)
+}
+
+// UpdatedFilter filters items based on the time since their last update.
+type UpdatedFilter struct {
+	age ExtDuration
+}
+
+func makeUpdatedFilter(value string) (*Filter, error) {
+	d, err := ParseExtDuration(value)
+	if err != nil {
+		return nil, errors.Errorf("invalid value %q for \"updated\" filter (expected a duration such as \"2d\", \"3w\", \"4m\", or \"1y\")", value)
+	}
+	return asFilter(UpdatedFilter{d}), nil
+}
+
+// ApplyItem applies the filter to the specified item, which passes if it wasn't updated for the
+// filter's duration.
+func (f UpdatedFilter) ApplyItem(context operations.Context, item *gh.Item) bool {
+	return time.Since(item.UpdatedAt()) > f.age.Duration()
+}
+
+// String returns a string representation of the filter
+func (f UpdatedFilter) String() string {
+	return fmt.Sprintf("UpdatedFilter(%s)", f.age)
+}
+
+func splitFilterValues(filterType, value, expected string) ([]string, error) {
+	values := strings.Split(value, ",")
+	for _, v := range values {
+		if v == "" {
+			return nil, errors.Errorf("invalid value %q for %q filter (expected %s)", value, filterType, expected)
+		}
+	}
+	return values, nil
+}
+
+// splitTeam splits "@org/team" into its organization and team parts.
+func splitTeam(value string) (string, string) {
+	s := strings.SplitN(strings.TrimPrefix(value, "@"), "/", 2)
+	if len(s) == 1 {
+		return s[0], ""
+	}
+	return s[0], s[1]
+}
+
+func containsLogin(logins []string, login string) bool {
+	for _, l := range logins {
+		if strings.EqualFold(l, login) {
+			return true
+		}
+	}
+	return false
+}
+
 // Type conversion utilities.
 
 func asFilter(impl fmt.Stringer) *Filter {
@@ -384,6 +936,9 @@ func FilterIncludesIssues(filters []*Filter) bool {
 		if f, ok := filter.Strategy.(IsFilter); ok && f.PullRequestOnly {
 			return false
 		}
+		if f, ok := filter.Strategy.(expressionFilter); ok && f.root.excludes(false) {
+			return false
+		}
 	}
 	return true
 }
@@ -395,6 +950,9 @@ func FilterIncludesPullRequests(filters []*Filter) bool {
 		if f, ok := filter.Strategy.(IsFilter); ok && !f.PullRequestOnly {
 			return false
 		}
+		if f, ok := filter.Strategy.(expressionFilter); ok && f.root.excludes(true) {
+			return false
+		}
 	}
 	return true
 }
@@ -0,0 +1,304 @@
+package settings
+
+import (
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"poule/gh"
+	"poule/operations"
+	"poule/test"
+
+	"github.com/google/go-github/github"
+	"github.com/stretchr/testify/mock"
+	"github.com/urfave/cli"
+)
+
+func TestFiltersOnPullRequest(t *testing.T) {
+	clt := &test.Client{}
+	context := operations.Context{
+		Client:     clt,
+		Username:   test.Username,
+		Repository: test.Repository,
+	}
+
+	// Labels of a pull request are only available on its related issue, which must be retrieved
+	// once for all filters.
+	issue := test.NewIssueBuilder(test.IssueNumber).Labels([]string{"bug", "triaged"}).Value
+	clt.MockIssues.On("Get", test.Username, test.Repository, test.IssueNumber).Return(issue, nil, nil).Once()
+
+	item := test.NewPullRequestBuilder(test.IssueNumber).
+		BaseBranch(test.Username, test.Repository, "master", test.CommitSHA[0]).
+		Item()
+	for _, tc := range []struct {
+		filters  map[string]interface{}
+		expected bool
+	}{
+		{map[string]interface{}{"labels": "bug", "~labels": "duplicate"}, true},
+		{map[string]interface{}{"labels": []interface{}{"bug", "triaged"}}, true},
+		{map[string]interface{}{"labels": "bug", "~labels": "triaged"}, false},
+		{map[string]interface{}{"assigned": "true"}, false},
+	} {
+		filters, err := ParseConfigurationFilters(tc.filters)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result := filters.Apply(context, &item); result != tc.expected {
+			t.Fatalf("Expected filters %v to return %t, got %t", tc.filters, tc.expected, result)
+		}
+	}
+
+	if item.Issue != issue || item.Type() != "pull_request" {
+		t.Fatalf("Expected related issue to be kept in pull request item")
+	}
+	test.AssertExpectations(clt, t)
+}
+
+func TestItemAttributeFilters(t *testing.T) {
+	clt := &test.Client{}
+	context := operations.Context{
+		Client:     clt,
+		Username:   test.Username,
+		Repository: test.Repository,
+	}
+
+	updatedAt := time.Now().Add(-10 * 24 * time.Hour)
+	issue := test.NewIssueBuilder(test.IssueNumber).UserLogin("contributor").UpdatedAt(updatedAt).Value
+	issue.State = github.String("open")
+	issue.Milestone = &github.Milestone{Title: github.String("1.13.0")}
+	issue.Assignees = []*github.User{{Login: github.String("icecrime")}}
+
+	pr := test.NewPullRequestBuilder(test.IssueNumber+1).
+		BaseBranch(test.Username, test.Repository, "release", test.CommitSHA[0]).
+		UserLogin("maintainer").
+		Merged(true).
+		State("closed").
+		Value
+	pr.UpdatedAt = &updatedAt
+
+	// Mergeability is retrieved on demand, and draft status always is.
+	mergeable := *pr
+	mergeable.Mergeable = github.Bool(true)
+	clt.MockPullRequests.On("Get", test.Username, test.Repository, test.IssueNumber+1).Return(&mergeable, nil, nil).Once()
+	clt.MockPullRequests.On("IsDraft", test.Username, test.Repository, test.IssueNumber+1).Return(false, nil, nil)
+
+	for _, tc := range []struct {
+		expression string
+		issue      bool
+		pr         bool
+	}{
+		{"author:contributor,someone", true, false},
+		{"assignee:icecrime", true, false},
+		{"assignee:none", false, true},
+		{`milestone:"1.13.0"`, true, false},
+		{"milestone:none", false, true},
+		{"state:open", true, false},
+		{"state:closed", false, true},
+		{"state:merged", false, true},
+		{"updated:1w", true, true},
+		{"updated<1w", false, false},
+		{"base:release", false, true},
+		{"draft:false", false, true},
+		{"not draft:true", true, true},
+		{"mergeable:true", false, true},
+	} {
+		filter, err := ParseFilterExpression(tc.expression)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %q: %v", tc.expression, err)
+		}
+		issueItem, prItem := gh.MakeIssueItem(issue), gh.MakePullRequestItem(pr)
+		if result := filter.Apply(context, &issueItem); result != tc.issue {
+			t.Fatalf("Expected %q to return %t for issue, got %t", tc.expression, tc.issue, result)
+		}
+		if result := filter.Apply(context, &prItem); result != tc.pr {
+			t.Fatalf("Expected %q to return %t for pull request, got %t", tc.expression, tc.pr, result)
+		}
+	}
+	test.AssertExpectations(clt, t)
+}
+
+func TestDraftFilterCachesStatus(t *testing.T) {
+	clt := &test.Client{}
+	context := operations.Context{
+		Client:     clt,
+		Username:   test.Username,
+		Repository: test.Repository,
+	}
+
+	// Draft status is retrieved once, and kept in the item for subsequent evaluations.
+	clt.MockPullRequests.On("IsDraft", test.Username, test.Repository, test.IssueNumber).Return(true, nil, nil).Once()
+
+	filter, err := ParseFilterExpression("draft:true and not draft:false")
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := gh.MakePullRequestItem(test.NewPullRequestBuilder(test.IssueNumber).
+		BaseBranch(test.Username, test.Repository, "master", test.CommitSHA[0]).
+		Value)
+	for i := 0; i < 2; i++ {
+		if !filter.Apply(context, &item) {
+			t.Fatalf("Expected draft pull request to pass the filter")
+		}
+	}
+	test.AssertExpectations(clt, t)
+}
+
+func TestAuthorFilterMembership(t *testing.T) {
+	clt := &test.Client{}
+	context := operations.Context{
+		Client:     clt,
+		Username:   test.Username,
+		Repository: test.Repository,
+	}
+
+	// Lookups are cached by the filter, and only performed once per author.
+	clt.MockOrganizations.On("IsMember", "docker", "outsider").Return(false, nil, nil).Once()
+	clt.MockOrganizations.On("ListTeams", "docker", &github.ListOptions{Page: 1, PerPage: 100}).Return([]*github.Team{
+		{ID: github.Int(42), Slug: github.String("maintainers")},
+	}, &github.Response{}, nil).Once()
+	clt.MockOrganizations.On("IsTeamMember", 42, "outsider").Return(false, nil, nil).Once()
+	clt.MockSearch.On("Issues", "repo:"+test.Username+"/"+test.Repository+" is:pr is:merged author:outsider", mock.Anything).Return(&github.IssuesSearchResult{
+		Issues: []github.Issue{{Number: github.Int(test.IssueNumber)}},
+	}, nil, nil).Once()
+
+	filter, err := MakeFilter("author", "@docker,@docker/maintainers,first-time-contributor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		// The only merged pull request of the author is the item itself.
+		item := test.NewPullRequestBuilder(test.IssueNumber).UserLogin("outsider").Item()
+		if !filter.Apply(context, &item) {
+			t.Fatalf("Expected author to be a first time contributor")
+		}
+	}
+	item := test.NewPullRequestBuilder(test.IssueNumber + 1).UserLogin("outsider").Item()
+	if filter.Apply(context, &item) {
+		t.Fatalf("Expected author not to be a first time contributor")
+	}
+	test.AssertExpectations(clt, t)
+	clt.MockSearch.AssertExpectations(t)
+}
+
+func TestAuthorFilterSharedPermissions(t *testing.T) {
+	clt := &test.Client{}
+	context := operations.Context{
+		Client:      clt,
+		Username:    test.Username,
+		Repository:  test.Repository,
+		Event:       &operations.Event{Sender: &github.User{Login: github.String("outsider")}},
+		Permissions: gh.NewPermissionCache(0),
+	}
+
+	// Lookups are cached in the context, and shared between filters.
+	clt.MockOrganizations.On("IsMember", "docker", "outsider").Return(false, nil, nil).Once()
+
+	for _, value := range []string{"author:@docker", "sender:@docker"} {
+		filter, err := ParseFilterExpression(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		item := test.NewPullRequestBuilder(test.IssueNumber).UserLogin("outsider").Item()
+		if filter.Apply(context, &item) {
+			t.Fatalf("Expected %q not to pass the filter", value)
+		}
+	}
+	test.AssertExpectations(clt, t)
+}
+
+func TestEventFilters(t *testing.T) {
+	clt := &test.Client{}
+	clt.MockOrganizations.On("IsMember", "docker", "outsider").Return(false, nil, nil).Once()
+
+	labelFilter, err := ParseFilterExpression("event-label:rebuild/*,ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	senderFilter, err := ParseFilterExpression("sender:icecrime,@docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		event          *operations.Event
+		expectedLabel  bool
+		expectedSender bool
+	}{
+		// Items processed outside of an event never pass.
+		{nil, false, false},
+		{&operations.Event{Label: &github.Label{Name: github.String("rebuild/janky")}, Sender: &github.User{Login: github.String("icecrime")}}, true, true},
+		{&operations.Event{Label: &github.Label{Name: github.String("ci")}, Sender: &github.User{Login: github.String("outsider")}}, true, false},
+		{&operations.Event{Label: &github.Label{Name: github.String("rebuild")}}, false, false},
+	} {
+		context := operations.Context{
+			Client:     clt,
+			Username:   test.Username,
+			Repository: test.Repository,
+			Event:      tc.event,
+		}
+		item := test.NewPullRequestBuilder(test.IssueNumber).Item()
+		if actual := labelFilter.Apply(context, &item); actual != tc.expectedLabel {
+			t.Fatalf("Expected event-label filter to return %t for event %#v", tc.expectedLabel, tc.event)
+		}
+		if actual := senderFilter.Apply(context, &item); actual != tc.expectedSender {
+			t.Fatalf("Expected sender filter to return %t for event %#v", tc.expectedSender, tc.event)
+		}
+	}
+	test.AssertExpectations(clt, t)
+}
+
+func TestFilterValueErrors(t *testing.T) {
+	for filter, expected := range map[string]string{
+		"state:draft":                   `invalid value "draft" for "state" filter (expected "open", "closed", or "merged")`,
+		"author:@":                      `invalid value "@" for "author" filter`,
+		"assignee:a,,b":                 `invalid value "a,,b" for "assignee" filter`,
+		"draft:maybe":                   `invalid value "maybe" for "draft" filter`,
+		"updated:recently":              `invalid value "recently" for "updated" filter`,
+		"event-label:[":                 `invalid value "[" for "event-label" filter`,
+		"sender:first-time-contributor": `invalid value "first-time-contributor" for "sender" filter`,
+		"reviewer:me":                   `unknown filter type "reviewer" (supported types: additions, age, assigned,`,
+	} {
+		_, err := ParseFilterExpression(filter)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("Expected error containing %q for %q, got %v", expected, filter, err)
+		}
+	}
+}
+
+func TestParseCliFilters(t *testing.T) {
+	for _, tc := range []struct {
+		args     []string
+		strategy string
+	}{
+		// Single `type:value` filters take their value literally.
+		{[]string{"contains:foo bar"}, "ContainsFilter(foo bar)"},
+		{[]string{"contains:(a|b)"}, "ContainsFilter((a|b))"},
+		{[]string{"comments:>10"}, "CommentsFilter(>10)"},
+		// Others are filter expressions.
+		{[]string{"is:pr and labels:bug"}, "ExpressionFilter((IsFilter(PullRequestOnly=true) and WithLabelsFilter(bug)))"},
+		{[]string{"is:pr labels:bug"}, "ExpressionFilter((IsFilter(PullRequestOnly=true) and WithLabelsFilter(bug)))"},
+		{[]string{"comments>10"}, "ExpressionFilter(CommentsFilter(>10))"},
+	} {
+		set := flag.NewFlagSet("test", flag.ContinueOnError)
+		FilteringFlag.Apply(set)
+		if err := set.Parse(append([]string{"--filter"}, tc.args...)); err != nil {
+			t.Fatal(err)
+		}
+		filters, err := ParseCliFilters(cli.NewContext(nil, set, nil))
+		if err != nil {
+			t.Fatalf("Unexpected error for %v: %v", tc.args, err)
+		}
+		if strategy := filters[0].Strategy.String(); len(filters) != 1 || strategy != tc.strategy {
+			t.Fatalf("Expected strategy %q for %v, got %q", tc.strategy, tc.args, strategy)
+		}
+	}
+
+	// Comparison operators only apply to filters comparing a count or a duration.
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	FilteringFlag.Apply(set)
+	set.Parse([]string{"--filter", "labels>foo"})
+	if _, err := ParseCliFilters(cli.NewContext(nil, set, nil)); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Fatalf("Expected unsupported operator error, got %v", err)
+	}
+}
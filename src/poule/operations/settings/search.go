@@ -0,0 +1,166 @@
+package settings
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// searchFilter is a filtering strategy which can be translated into GitHub search qualifiers.
+type searchFilter interface {
+	// SearchQualifiers returns the search qualifiers equivalent to the filter at the specified
+	// time, or false if the filter cannot be expressed as a search query.
+	SearchQualifiers(now time.Time) ([]string, bool)
+}
+
+// SearchQualifiers translates the filters which can be expressed as GitHub search qualifiers.
+// Filters which cannot be translated are ignored: because search qualifiers only narrow down the
+// set of items, all filters must still be evaluated on the search results.
+func SearchQualifiers(filters Filters, now time.Time) []string {
+	var qualifiers []string
+	for _, filter := range filters {
+		if f, ok := filter.Strategy.(searchFilter); ok {
+			if q, ok := f.SearchQualifiers(now); ok {
+				qualifiers = append(qualifiers, q...)
+			}
+		}
+	}
+	return qualifiers
+}
+
+// SearchQualifiers returns the search qualifiers equivalent to the filter.
+func (f AgeFilter) SearchQualifiers(now time.Time) ([]string, bool) {
+	// Search dates have a day granularity: the qualifier includes the limit day, and the filter
+	// itself excludes items which are not old enough.
+	limit := now.Add(-f.age.Duration())
+	return []string{"created:<=" + limit.UTC().Format("2006-01-02")}, true
+}
+
+// SearchQualifiers returns the search qualifiers equivalent to the filter.
+func (f AssignedFilter) SearchQualifiers(now time.Time) ([]string, bool) {
+	// There is no qualifier for items with any assignee.
+	if f.isAssigned {
+		return nil, false
+	}
+	return []string{"no:assignee"}, true
+}
+
+// SearchQualifiers returns the search qualifiers equivalent to the filter.
+func (f AssigneeFilter) SearchQualifiers(now time.Time) ([]string, bool) {
+	switch {
+	case len(f.assignees) == 1 && f.assignees[0] == "none":
+		return []string{"no:assignee"}, true
+	case len(f.assignees) == 1:
+		return []string{"assignee:" + f.assignees[0]}, true
+	}
+	return nil, false
+}
+
+// SearchQualifiers returns the search qualifiers equivalent to the filter.
+func (f AuthorFilter) SearchQualifiers(now time.Time) ([]string, bool) {
+	// Only a single login can be expressed as a qualifier.
+	if len(f.authors) != 1 || f.authors[0] == firstTimeContributor || strings.HasPrefix(f.authors[0], "@") {
+		return nil, false
+	}
+	return []string{"author:" + f.authors[0]}, true
+}
+
+// SearchQualifiers returns the search qualifiers equivalent to the filter.
+func (f BaseFilter) SearchQualifiers(now time.Time) ([]string, bool) {
+	return []string{"base:" + f.branch}, true
+}
+
+// SearchQualifiers returns the search qualifiers equivalent to the filter.
+func (f CommentsFilter) SearchQualifiers(now time.Time) ([]string, bool) {
+	value := strings.TrimPrefix(f.filtValue, "=")
+	if _, err := strconv.Atoi(strings.TrimLeft(value, "<>")); err != nil {
+		return nil, false
+	}
+	return []string{"comments:" + value}, true
+}
+
+// SearchQualifiers returns the search qualifiers equivalent to the filter.
+func (f DraftFilter) SearchQualifiers(now time.Time) ([]string, bool) {
+	return []string{fmt.Sprintf("draft:%t", f.isDraft)}, true
+}
+
+// SearchQualifiers returns the search qualifiers equivalent to the filter.
+func (f IsFilter) SearchQualifiers(now time.Time) ([]string, bool) {
+	if f.PullRequestOnly {
+		return []string{"is:pr"}, true
+	}
+	return []string{"is:issue"}, true
+}
+
+// SearchQualifiers returns the search qualifiers equivalent to the filter.
+func (f MilestoneFilter) SearchQualifiers(now time.Time) ([]string, bool) {
+	if f.title == "none" {
+		return []string{"no:milestone"}, true
+	}
+	return []string{fmt.Sprintf("milestone:%q", f.title)}, true
+}
+
+// SearchQualifiers returns the search qualifiers equivalent to the filter.
+func (f StateFilter) SearchQualifiers(now time.Time) ([]string, bool) {
+	if f.state == "merged" {
+		return []string{"is:merged"}, true
+	}
+	return []string{"state:" + f.state}, true
+}
+
+// SearchQualifiers returns the search qualifiers equivalent to the filter.
+func (f UpdatedFilter) SearchQualifiers(now time.Time) ([]string, bool) {
+	limit := now.Add(-f.age.Duration())
+	return []string{"updated:<=" + limit.UTC().Format("2006-01-02")}, true
+}
+
+// SearchQualifiers returns the search qualifiers equivalent to the filter.
+func (f WithLabelsFilter) SearchQualifiers(now time.Time) ([]string, bool) {
+	return labelQualifiers("label:", f.labels), true
+}
+
+// SearchQualifiers returns the search qualifiers equivalent to the filter.
+func (f WithoutLabelsFilter) SearchQualifiers(now time.Time) ([]string, bool) {
+	return labelQualifiers("-label:", f.labels), true
+}
+
+// SearchQualifiers returns the search qualifiers equivalent to the filter expression. Only
+// conjunctions can be expressed as a search query.
+func (f expressionFilter) SearchQualifiers(now time.Time) ([]string, bool) {
+	return nodeSearchQualifiers(f.root, now)
+}
+
+func nodeSearchQualifiers(node filterNode, now time.Time) ([]string, bool) {
+	switch n := node.(type) {
+	case leafNode:
+		if f, ok := n.filter.Strategy.(searchFilter); ok {
+			return f.SearchQualifiers(now)
+		}
+	case andNode:
+		// A conjunction can be partially translated, as qualifiers only narrow down the results.
+		var qualifiers []string
+		for _, child := range n.children {
+			if q, ok := nodeSearchQualifiers(child, now); ok {
+				qualifiers = append(qualifiers, q...)
+			}
+		}
+		return qualifiers, len(qualifiers) != 0
+	case notNode:
+		// Only the negation of a single label can be translated.
+		if leaf, ok := n.child.(leafNode); ok {
+			if f, ok := leaf.filter.Strategy.(WithLabelsFilter); ok && len(f.labels) == 1 {
+				return labelQualifiers("-label:", f.labels), true
+			}
+		}
+	}
+	return nil, false
+}
+
+func labelQualifiers(prefix string, labels []string) []string {
+	qualifiers := make([]string, len(labels))
+	for i, label := range labels {
+		qualifiers[i] = prefix + fmt.Sprintf("%q", label)
+	}
+	return qualifiers
+}
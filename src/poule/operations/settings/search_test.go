@@ -0,0 +1,32 @@
+package settings
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSearchQualifiers(t *testing.T) {
+	now := time.Date(2017, time.March, 31, 12, 0, 0, 0, time.UTC)
+	for values, expected := range map[string][]string{
+		"is:pr":                                {"is:pr"},
+		"base:master and draft:false":          {"base:master", "draft:false"},
+		"labels:bug,kind/feature":              {`label:"bug"`, `label:"kind/feature"`},
+		`~labels:"status/needs info"`:          {`-label:"status/needs info"`},
+		"age:30d":                              {"created:<=2017-03-01"},
+		"comments:>10":                         {"comments:>10"},
+		"assigned:false":                       {"no:assignee"},
+		"assigned:true":                        nil,
+		"is:issue and not labels:bug":          {"is:issue", `-label:"bug"`},
+		"is:issue and (labels:a or ~labels:b)": {"is:issue"},
+		"labels:a or labels:b":                 nil,
+	} {
+		filter, err := ParseFilterExpression(values)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %q: %v", values, err)
+		}
+		if qualifiers := SearchQualifiers(Filters{filter}, now); !reflect.DeepEqual(qualifiers, expected) {
+			t.Fatalf("Expected qualifiers %q for %q, got %q", expected, values, qualifiers)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"poule/gh"
+	"poule/operations"
+	"poule/runner/plan"
+	"poule/runner/report"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+)
+
+// ApplyPlan applies the operation to the items of a previously computed plan.
+//
+// Each item is retrieved again and skipped if it was updated since planning. The operation's
+// filtering is then run once more, both as a safety net and to recover the Go type of the userdata:
+// the planned userdata is what eventually gets passed to Apply, so that the applied changes are
+// exactly the reviewed ones. Operations must therefore derive any state they persist when applied
+// (such as the round-robin cursor of random-assign) from the userdata rather than from filtering.
+func (r *OperationRunner) ApplyPlan(planned *plan.Operation) error {
+	context := r.makeContext()
+	errs := make([]error, len(planned.Items))
+	parallelize(r.Config.Concurrency, len(planned.Items), func(i int) {
+		errs[i] = r.applyPlannedItem(context, planned.Items[i])
+	})
+
+	var result Errors
+	for _, err := range errs {
+		result = result.Append(err)
+	}
+	return result.ErrorOrNil()
+}
+
+func (r *OperationRunner) applyPlannedItem(context *operations.Context, planned plan.Item) error {
+	item, err := getItem(context, planned.Type, planned.Number)
+	if err != nil {
+		return err
+	}
+
+	// Skip items which changed since planning.
+	if !item.UpdatedAt().Equal(planned.UpdatedAt) {
+		r.skip(item, "item was updated since planning")
+		return nil
+	}
+	result, userdata, err := r.Operation.Filter(context, item)
+	switch {
+	case err != nil:
+		r.report(item, report.Failed, planned.Description, err)
+		return errors.Wrapf(err, "failed to filter item #%d", item.Number())
+	case result != operations.Accept:
+		r.skip(item, "item is no longer accepted by the operation")
+		return nil
+	}
+
+	// Substitute the planned userdata to the freshly computed one.
+	if userdata, err = decodeUserData(planned.UserData, userdata); err != nil {
+		r.report(item, report.Failed, planned.Description, err)
+		return errors.Wrapf(err, "failed to decode planned userdata for item #%d", item.Number())
+	}
+	if err := r.applyItem(context, item, userdata); err != nil {
+		return errors.Wrapf(err, "failed to apply operation to item #%d", item.Number())
+	}
+	return nil
+}
+
+func (r *OperationRunner) skip(item gh.Item, reason string) {
+	logrus.WithFields(logrus.Fields{
+		"item_num":   item.Number(),
+		"item_type":  item.Type(),
+		"repository": r.Config.Repository,
+	}).Warnf("skipping planned item: %s", reason)
+	r.report(item, report.Skipped, reason, nil)
+}
+
+// getItem retrieves the current state of a GitHub item.
+func getItem(context *operations.Context, itemType string, number int) (gh.Item, error) {
+	switch itemType {
+	case "issue":
+		issue, _, err := context.Client.Issues().Get(context.Username, context.Repository, number)
+		if err != nil {
+			return gh.Item{}, errors.Wrapf(err, "failed to retrieve issue #%d", number)
+		}
+		return gh.MakeIssueItem(issue), nil
+	case "pull_request":
+		pr, _, err := context.Client.PullRequests().Get(context.Username, context.Repository, number)
+		if err != nil {
+			return gh.Item{}, errors.Wrapf(err, "failed to retrieve pull request #%d", number)
+		}
+		return gh.MakePullRequestItem(pr), nil
+	}
+	return gh.Item{}, fmt.Errorf("unknown item type %q", itemType)
+}
+
+// decodeUserData deserializes the planned userdata into a value of the same type as the template.
+func decodeUserData(data json.RawMessage, template interface{}) (interface{}, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+	if template == nil {
+		var v interface{}
+		err := json.Unmarshal(data, &v)
+		return v, err
+	}
+	v := reflect.New(reflect.TypeOf(template))
+	if err := json.Unmarshal(data, v.Interface()); err != nil {
+		return nil, err
+	}
+	return v.Elem().Interface(), nil
+}
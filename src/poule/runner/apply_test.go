@@ -0,0 +1,95 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"poule/configuration"
+	"poule/gh"
+	"poule/operations"
+	"poule/operations/catalog"
+	"poule/runner/plan"
+	"poule/runner/report"
+	"poule/test"
+)
+
+// userDataOperation accepts all items with a userdata that differs on each call.
+type userDataOperation struct {
+	sortedOperation
+	calls   int
+	applied map[int][]string
+}
+
+func (o *userDataOperation) Apply(c *operations.Context, item gh.Item, userData interface{}) error {
+	o.applied[item.Number()] = userData.([]string)
+	return nil
+}
+
+func (o *userDataOperation) Filter(c *operations.Context, item gh.Item) (operations.FilterResult, interface{}, error) {
+	o.calls++
+	return operations.Accept, []string{"call", string(rune('0' + o.calls))}, nil
+}
+
+func TestApplyPlan(t *testing.T) {
+	planned := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	unchanged := test.NewIssueBuilder(1).UpdatedAt(planned).Item()
+	updated := test.NewIssueBuilder(2).UpdatedAt(planned).Item()
+
+	p := plan.New().AddOperation(test.Username+"/"+test.Repository, "test", nil)
+	p.Add(unchanged, "", []string{"planned"})
+	p.Add(updated, "", []string{"planned"})
+
+	clt := &test.Client{}
+	clt.MockIssues.On("Get", test.Username, test.Repository, 1).Return(unchanged.Issue, nil, nil)
+	clt.MockIssues.On("Get", test.Username, test.Repository, 2).Return(test.NewIssueBuilder(2).UpdatedAt(time.Now()).Value, nil, nil)
+
+	op := &userDataOperation{applied: map[int][]string{}}
+	r := NewOperationRunner(&configuration.Config{Repository: test.Username + "/" + test.Repository}, op)
+	r.Report = report.New()
+	r.client = clt
+	if err := r.ApplyPlan(p); err != nil {
+		t.Fatalf("ApplyPlan returned unexpected error %v", err)
+	}
+
+	// Only the unchanged item should be applied, with the planned userdata.
+	if len(op.applied) != 1 || len(op.applied[1]) != 1 || op.applied[1][0] != "planned" {
+		t.Fatalf("Unexpected applications %v", op.applied)
+	}
+	entries := r.Report.Entries()
+	if len(entries) != 2 || entries[0].Outcome != report.Applied || entries[1].Outcome != report.Skipped {
+		t.Fatalf("Unexpected report entries %v", entries)
+	}
+}
+
+func TestApplyPlanRoundRobin(t *testing.T) {
+	planned := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	item := test.NewIssueBuilder(1).UpdatedAt(planned).Item()
+
+	// The plan assigns the item to another user than the one filtering picks when applying it.
+	p := plan.New().AddOperation(test.Username+"/"+test.Repository, "random-assign", nil)
+	p.Add(item, "", "user2")
+
+	clt := &test.Client{}
+	clt.MockIssues.On("Get", test.Username, test.Repository, 1).Return(item.Issue, nil, nil)
+	clt.MockIssues.On("AddAssignees", test.Username, test.Repository, 1, []string{"user2"}).Return(nil, nil, nil).Once()
+
+	config := operations.Configuration{"strategy": "round-robin", "users": []string{"user1", "user2", "user3"}}
+	op, err := catalog.ByNameIndex["random-assign"].OperationFromConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewOperationRunner(&configuration.Config{Repository: test.Username + "/" + test.Repository}, op)
+	r.client = clt
+	if err := r.ApplyPlan(p); err != nil {
+		t.Fatalf("ApplyPlan returned unexpected error %v", err)
+	}
+
+	// The round-robin resumes after the planned assignee.
+	if op, err = catalog.ByNameIndex["random-assign"].OperationFromConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	if _, userData, err := op.Filter(r.makeContext(), test.NewIssueBuilder(2).Item()); err != nil || userData != "user3" {
+		t.Fatalf("Expected next assignee %q, got %v (error %v)", "user3", userData, err)
+	}
+	clt.MockIssues.AssertExpectations(t)
+}
@@ -0,0 +1,192 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"poule/gh"
+	"poule/operations"
+
+	"github.com/pkg/errors"
+)
+
+// Version is the version of the plan file format.
+const Version = 1
+
+// Plan is the serializable outcome of the filtering phase of one or more operations: it lists the
+// items each operation would be applied to, along with the corresponding userdata.
+type Plan struct {
+	Version    int          `json:"version"`
+	CreatedAt  time.Time    `json:"created_at"`
+	Operations []*Operation `json:"operations"`
+
+	mu sync.Mutex
+}
+
+// Operation is the plan for a single operation on a single repository.
+type Operation struct {
+	// Repository is the full name of the repository the operation applies to.
+	Repository string `json:"repository"`
+
+	// Type is the name of the operation.
+	Type string `json:"type"`
+
+	// Settings is the configuration of the operation when it originates from a configuration file.
+	Settings operations.Configuration `json:"settings,omitempty"`
+
+	// CommandLine is true when the operation was created from command-line Arguments rather than
+	// from Settings.
+	CommandLine bool     `json:"command_line,omitempty"`
+	Arguments   []string `json:"arguments,omitempty"`
+
+	// Items are the items accepted by the operation.
+	Items []Item `json:"items"`
+
+	mu sync.Mutex
+}
+
+// Item is a planned application of an operation to a GitHub item.
+type Item struct {
+	// Type is the GitHub item type (either "issue" or "pull_request").
+	Type string `json:"type"`
+
+	// Number is the GitHub item number.
+	Number int `json:"number"`
+
+	// UpdatedAt is the last update time of the item at the time of planning: the planned operation
+	// is only applied if the item wasn't updated since.
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Description is the operation's description of the planned change.
+	Description string `json:"description,omitempty"`
+
+	// UserData is the JSON serialized userdata returned by the operation's filtering phase.
+	UserData json.RawMessage `json:"userdata"`
+}
+
+// New returns an empty plan.
+func New() *Plan {
+	return &Plan{
+		Version:   Version,
+		CreatedAt: time.Now(),
+	}
+}
+
+// AddOperation adds a new operation to the plan, and returns it so that items can be added.
+func (p *Plan) AddOperation(repository, operationType string, settings operations.Configuration) *Operation {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	op := &Operation{
+		Repository: repository,
+		Type:       operationType,
+		Settings:   normalizeConfiguration(settings),
+		Items:      []Item{},
+	}
+	p.Operations = append(p.Operations, op)
+	return op
+}
+
+// AddCommandLineOperation adds a new operation created from command-line arguments to the plan.
+func (p *Plan) AddCommandLineOperation(repository, operationType string, arguments []string) *Operation {
+	op := p.AddOperation(repository, operationType, nil)
+	op.CommandLine = true
+	op.Arguments = arguments
+	return op
+}
+
+// Add records the planned application of the operation to an item. It is safe for concurrent use.
+func (o *Operation) Add(item gh.Item, description string, userdata interface{}) error {
+	b, err := json.Marshal(userdata)
+	if err != nil {
+		return errors.Wrapf(err, "failed to serialize userdata for item #%d", item.Number())
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.Items = append(o.Items, Item{
+		Type:        item.Type(),
+		Number:      item.Number(),
+		UpdatedAt:   item.UpdatedAt(),
+		Description: description,
+		UserData:    b,
+	})
+	return nil
+}
+
+// ItemCount returns the total number of planned items.
+func (p *Plan) ItemCount() int {
+	count := 0
+	for _, op := range p.Operations {
+		count += len(op.Items)
+	}
+	return count
+}
+
+// Read loads a plan from the specified file.
+func Read(path string) (*Plan, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read plan file %q", path)
+	}
+	var p Plan
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse plan file %q", path)
+	}
+	if p.Version != Version {
+		return nil, errors.Errorf("unsupported plan file version %d (expected %d)", p.Version, Version)
+	}
+	return &p, nil
+}
+
+// Write saves the plan to the specified file.
+func (p *Plan) Write(path string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to serialize plan")
+	}
+	if err := ioutil.WriteFile(path, append(b, '\n'), 0644); err != nil {
+		return errors.Wrapf(err, "failed to write plan file %q", path)
+	}
+	return nil
+}
+
+// normalizeConfiguration converts the maps produced by the YAML decoder (which have interface{}
+// keys) so that the configuration can be serialized to JSON.
+func normalizeConfiguration(c operations.Configuration) operations.Configuration {
+	if c == nil {
+		return nil
+	}
+	result := operations.Configuration{}
+	for k, v := range c {
+		result[k] = normalizeValue(v)
+	}
+	return result
+}
+
+func normalizeValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for k, value := range v {
+			m[fmt.Sprint(k)] = normalizeValue(value)
+		}
+		return m
+	case map[string]interface{}:
+		m := map[string]interface{}{}
+		for k, value := range v {
+			m[k] = normalizeValue(value)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, value := range v {
+			s[i] = normalizeValue(value)
+		}
+		return s
+	}
+	return v
+}
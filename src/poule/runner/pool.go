@@ -0,0 +1,65 @@
+package runner
+
+import (
+	"strings"
+	"sync"
+)
+
+// Errors is a collection of errors which occurred while processing the stock of GitHub items.
+type Errors []error
+
+// Append returns the collection with the specified error appended, flattening nested collections
+// and ignoring nil errors.
+func (e Errors) Append(err error) Errors {
+	switch err := err.(type) {
+	case nil:
+		return e
+	case Errors:
+		return append(e, err...)
+	default:
+		return append(e, err)
+	}
+}
+
+// Error returns the concatenation of all error messages.
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// ErrorOrNil returns nil for an empty collection, and the collection itself otherwise.
+func (e Errors) ErrorOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// parallelize calls fn for every index in [0, n) using at most `concurrency` goroutines, and
+// returns when all calls are completed. A concurrency lower than 2 results in sequential calls.
+func parallelize(concurrency, n int, fn func(i int)) {
+	if concurrency < 2 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	wg := sync.WaitGroup{}
+	sem := make(chan struct{}, concurrency)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
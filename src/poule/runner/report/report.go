@@ -0,0 +1,242 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Outcome is the result of running an operation on a single item.
+type Outcome string
+
+const (
+	// Accepted means that the item was accepted by the operation, but that the operation wasn't
+	// applied (typically because of dry run).
+	Accepted Outcome = "accepted"
+
+	// Rejected means that the item was rejected by the filters or by the operation.
+	Rejected Outcome = "rejected"
+
+	// Terminal means that the item was rejected by the operation, and that no further items were
+	// considered.
+	Terminal Outcome = "terminal"
+
+	// Applied means that the operation was successfully applied to the item.
+	Applied Outcome = "applied"
+
+	// Failed means that filtering or applying the operation failed for the item.
+	Failed Outcome = "failed"
+
+	// Skipped means that a planned operation wasn't applied because the item changed since.
+	Skipped Outcome = "skipped"
+)
+
+// outcomes is the ordered list of all outcomes, used for summaries.
+var outcomes = []Outcome{Applied, Accepted, Rejected, Terminal, Skipped, Failed}
+
+// Format is a report output format.
+type Format string
+
+const (
+	// JSON outputs the report as a JSON array of entries.
+	JSON Format = "json"
+
+	// CSV outputs the report as a CSV file with a header line.
+	CSV Format = "csv"
+
+	// Markdown outputs the report as a Markdown summary followed by a table of entries.
+	Markdown Format = "markdown"
+)
+
+// Entry is the outcome of running an operation on a single item.
+type Entry struct {
+	Repository  string    `json:"repository"`
+	Operation   string    `json:"operation"`
+	ItemType    string    `json:"item_type"`
+	Number      int       `json:"number"`
+	Outcome     Outcome   `json:"outcome"`
+	Description string    `json:"description,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	Time        time.Time `json:"time"`
+}
+
+// Report collects the outcomes of operations runs. It is safe for concurrent use.
+type Report struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// New returns an empty report.
+func New() *Report {
+	return &Report{}
+}
+
+// Add records an entry in the report. It is a no-op on a nil report.
+func (r *Report) Add(entry Entry) {
+	if r == nil {
+		return
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+}
+
+// Entries returns the entries of the report, sorted by repository, operation, and item number.
+func (r *Report) Entries() []Entry {
+	r.mu.Lock()
+	entries := append([]Entry(nil), r.entries...)
+	r.mu.Unlock()
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Repository != b.Repository {
+			return a.Repository < b.Repository
+		}
+		if a.Operation != b.Operation {
+			return a.Operation < b.Operation
+		}
+		return a.Number < b.Number
+	})
+	return entries
+}
+
+// ParseFormat returns the format corresponding to the specified name. When the name is empty, the
+// format is inferred from the extension of the output path, defaulting to Markdown.
+func ParseFormat(name, path string) (Format, error) {
+	if name == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".json":
+			return JSON, nil
+		case ".csv":
+			return CSV, nil
+		default:
+			return Markdown, nil
+		}
+	}
+	switch f := Format(strings.ToLower(name)); f {
+	case JSON, CSV, Markdown:
+		return f, nil
+	case "md":
+		return Markdown, nil
+	}
+	return "", errors.Errorf("unknown report format %q", name)
+}
+
+// WriteFile writes the report to the specified path in the specified format. The "-" path
+// designates the standard output.
+func (r *Report) WriteFile(path string, format Format) error {
+	if path == "-" {
+		return r.Write(os.Stdout, format)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create report file %q", path)
+	}
+	if err := r.Write(f, format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Write writes the report to w in the specified format.
+func (r *Report) Write(w io.Writer, format Format) error {
+	switch format {
+	case JSON:
+		return r.writeJSON(w)
+	case CSV:
+		return r.writeCSV(w)
+	case Markdown:
+		return r.writeMarkdown(w)
+	}
+	return errors.Errorf("unknown report format %q", format)
+}
+
+func (r *Report) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r.Entries())
+}
+
+func (r *Report) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"repository", "operation", "item_type", "number", "outcome", "description", "error", "time"})
+	for _, e := range r.Entries() {
+		cw.Write([]string{
+			e.Repository,
+			e.Operation,
+			e.ItemType,
+			strconv.Itoa(e.Number),
+			string(e.Outcome),
+			e.Description,
+			e.Error,
+			e.Time.Format(time.RFC3339),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (r *Report) writeMarkdown(w io.Writer) error {
+	entries := r.Entries()
+
+	// Summarize outcomes per repository and operation.
+	type key struct{ repository, operation string }
+	var keys []key
+	counts := map[key]map[Outcome]int{}
+	for _, e := range entries {
+		k := key{e.Repository, e.Operation}
+		if _, ok := counts[k]; !ok {
+			keys = append(keys, k)
+			counts[k] = map[Outcome]int{}
+		}
+		counts[k][e.Outcome]++
+	}
+
+	var b strings.Builder
+	b.WriteString("## Summary\n\n| Repository | Operation |")
+	for _, o := range outcomes {
+		fmt.Fprintf(&b, " %s |", strings.Title(string(o)))
+	}
+	b.WriteString("\n|---|---|" + strings.Repeat("---:|", len(outcomes)) + "\n")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "| %s | %s |", k.repository, k.operation)
+		for _, o := range outcomes {
+			fmt.Fprintf(&b, " %d |", counts[k][o])
+		}
+		b.WriteString("\n")
+	}
+
+	// Detail all items, except for rejected ones which usually make up for most of the stock.
+	b.WriteString("\n## Details\n\n| Repository | Operation | Item | Outcome | Description |\n|---|---|---|---|---|\n")
+	for _, e := range entries {
+		if e.Outcome == Rejected {
+			continue
+		}
+		description := e.Description
+		if e.Error != "" {
+			description = e.Error
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s #%d | %s | %s |\n", e.Repository, e.Operation, e.ItemType, e.Number, e.Outcome, escapeMarkdown(description))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// escapeMarkdown makes a string safe for inclusion in a Markdown table cell.
+func escapeMarkdown(s string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(s)
+}
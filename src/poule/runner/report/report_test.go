@@ -0,0 +1,85 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func makeTestReport() *Report {
+	r := New()
+	r.Add(Entry{Repository: "icecrime/poule", Operation: "prune", ItemType: "issue", Number: 2, Outcome: Rejected})
+	r.Add(Entry{Repository: "icecrime/poule", Operation: "prune", ItemType: "issue", Number: 1, Outcome: Applied, Description: "closing | stale"})
+	r.Add(Entry{Repository: "icecrime/poule", Operation: "label", ItemType: "pull_request", Number: 3, Outcome: Failed, Error: "boom"})
+	return r
+}
+
+func TestReportJSON(t *testing.T) {
+	var b bytes.Buffer
+	if err := makeTestReport().Write(&b, JSON); err != nil {
+		t.Fatal(err)
+	}
+	var entries []Entry
+	if err := json.Unmarshal(b.Bytes(), &entries); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	if len(entries) != 3 || entries[0].Operation != "label" || entries[1].Number != 1 || entries[2].Number != 2 {
+		t.Fatalf("Unexpected entries %v", entries)
+	}
+}
+
+func TestReportCSV(t *testing.T) {
+	var b bytes.Buffer
+	if err := makeTestReport().Write(&b, CSV); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&b).ReadAll()
+	if err != nil {
+		t.Fatalf("Invalid CSV output: %v", err)
+	}
+	if len(records) != 4 || records[1][4] != string(Failed) || records[1][6] != "boom" {
+		t.Fatalf("Unexpected records %v", records)
+	}
+}
+
+func TestReportMarkdown(t *testing.T) {
+	var b bytes.Buffer
+	if err := makeTestReport().Write(&b, Markdown); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, expected := range []string{
+		"| icecrime/poule | prune | 1 | 0 | 1 | 0 | 0 | 0 |",
+		"| icecrime/poule | prune | issue #1 | applied | closing \\| stale |",
+		"| icecrime/poule | label | pull_request #3 | failed | boom |",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("Expected output to contain %q, got:\n%s", expected, out)
+		}
+	}
+	if strings.Contains(out, "issue #2") {
+		t.Fatalf("Rejected items should not be detailed:\n%s", out)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, tc := range []struct {
+		name, path string
+		expected   Format
+	}{
+		{"", "report.json", JSON},
+		{"", "report.CSV", CSV},
+		{"", "-", Markdown},
+		{"md", "report.json", Markdown},
+		{"json", "-", JSON},
+	} {
+		if f, err := ParseFormat(tc.name, tc.path); err != nil || f != tc.expected {
+			t.Fatalf("ParseFormat(%q, %q) = %q, %v; expected %q", tc.name, tc.path, f, err, tc.expected)
+		}
+	}
+	if _, err := ParseFormat("xml", ""); err == nil {
+		t.Fatalf("Expected error for unknown format")
+	}
+}
@@ -0,0 +1,109 @@
+package runner
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"poule/configuration"
+	"poule/gh"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+// ExpandRepositories returns the full names of the repositories designated by the selectors, in
+// order and without duplicates. Glob patterns and organization selectors are resolved by listing
+// the repositories of the organization.
+func ExpandRepositories(client gh.Client, selectors []string) ([]string, error) {
+	organizations := map[string][]string{}
+	listOrganization := func(org string) ([]string, error) {
+		if repositories, ok := organizations[org]; ok {
+			return repositories, nil
+		}
+		repositories, err := listOrganizationRepositories(client, org)
+		if err != nil {
+			return nil, err
+		}
+		organizations[org] = repositories
+		return repositories, nil
+	}
+
+	result := []string{}
+	seen := map[string]bool{}
+	add := func(repository string) {
+		if !seen[repository] {
+			seen[repository] = true
+			result = append(result, repository)
+		}
+	}
+	for _, selector := range selectors {
+		switch {
+		case strings.HasPrefix(selector, configuration.OrganizationSelectorPrefix):
+			repositories, err := listOrganization(strings.TrimPrefix(selector, configuration.OrganizationSelectorPrefix))
+			if err != nil {
+				return nil, err
+			}
+			for _, repository := range repositories {
+				add(repository)
+			}
+		case configuration.IsRepositoryPattern(selector):
+			s := strings.SplitN(selector, "/", 2)
+			if len(s) != 2 {
+				return nil, errors.Errorf("invalid repository pattern %q", selector)
+			}
+			repositories, err := listOrganization(s[0])
+			if err != nil {
+				return nil, err
+			}
+			matched := false
+			for _, repository := range repositories {
+				if ok, err := path.Match(s[1], strings.SplitN(repository, "/", 2)[1]); err != nil {
+					return nil, errors.Wrapf(err, "invalid repository pattern %q", selector)
+				} else if ok {
+					add(repository)
+					matched = true
+				}
+			}
+			if !matched {
+				return nil, errors.Errorf("no repository matches %q", selector)
+			}
+		default:
+			add(selector)
+		}
+	}
+	return result, nil
+}
+
+// listOrganizationRepositories returns the full names of the repositories of the organization,
+// excluding archived ones. The owner is listed as a user when it isn't an organization.
+func listOrganizationRepositories(client gh.Client, org string) ([]string, error) {
+	repositories := []string{}
+	list := client.Repositories().ListByOrg
+	options := &github.ListOptions{PerPage: 100}
+	for options.Page = 1; options.Page != 0; {
+		repos, resp, err := list(org, options)
+		if err != nil && options.Page == 1 && isNotFound(resp) {
+			list = client.Repositories().List
+			if repos, resp, err = list(org, options); err != nil {
+				return nil, errors.Wrapf(err, "failed to list repositories of %q", org)
+			}
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "failed to list repositories of %q", org)
+		}
+		for _, repo := range repos {
+			if repo.Name != nil && (repo.Archived == nil || !*repo.Archived) {
+				repositories = append(repositories, org+"/"+*repo.Name)
+			}
+		}
+		if resp == nil {
+			break
+		}
+		options.Page = resp.NextPage
+	}
+	return repositories, nil
+}
+
+func isNotFound(resp *github.Response) bool {
+	return resp != nil && resp.Response != nil && resp.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,83 @@
+package runner
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"poule/gh"
+	"poule/test"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+)
+
+func makeRepositories(names ...string) []*gh.Repository {
+	repositories := []*gh.Repository{}
+	for _, name := range names {
+		repositories = append(repositories, &gh.Repository{Repository: github.Repository{Name: github.String(name)}})
+	}
+	return repositories
+}
+
+func TestExpandRepositories(t *testing.T) {
+	clt := &test.Client{}
+
+	// The organization is listed once, across pages, even when used by several selectors.
+	clt.MockRepositories.
+		On("ListByOrg", "moby", mock.MatchedBy(func(o *github.ListOptions) bool { return o.Page == 1 })).
+		Return(makeRepositories("moby", "docker-ce"), &github.Response{NextPage: 2}, nil).
+		Once()
+	clt.MockRepositories.
+		On("ListByOrg", "moby", mock.MatchedBy(func(o *github.ListOptions) bool { return o.Page == 2 })).
+		Return(makeRepositories("docker-py", "tool"), &github.Response{}, nil).
+		Once()
+
+	repositories, err := ExpandRepositories(clt, []string{"icecrime/poule", "moby/docker-*", "org:moby", "icecrime/poule"})
+	if err != nil {
+		t.Fatalf("ExpandRepositories returned unexpected error %v", err)
+	}
+	expected := []string{"icecrime/poule", "moby/docker-ce", "moby/docker-py", "moby/moby", "moby/tool"}
+	if !reflect.DeepEqual(repositories, expected) {
+		t.Fatalf("Expected repositories %v, got %v", expected, repositories)
+	}
+	test.AssertExpectations(clt, t)
+}
+
+func TestExpandRepositoriesNoMatch(t *testing.T) {
+	clt := &test.Client{}
+	clt.MockRepositories.
+		On("ListByOrg", "moby", mock.Anything).
+		Return(makeRepositories("moby"), &github.Response{}, nil)
+
+	if _, err := ExpandRepositories(clt, []string{"moby/docker-*"}); err == nil {
+		t.Fatalf("Expected error for a pattern matching no repository")
+	}
+}
+
+func TestExpandRepositoriesOfUser(t *testing.T) {
+	clt := &test.Client{}
+
+	// Owners which aren't organizations are listed as users, and archived repositories are skipped.
+	notFound := &github.Response{Response: &http.Response{StatusCode: http.StatusNotFound}}
+	clt.MockRepositories.
+		On("ListByOrg", "icecrime", mock.Anything).
+		Return(nil, notFound, errors.New("not found")).
+		Once()
+	repositories := makeRepositories("poule", "archived")
+	repositories[1].Archived = github.Bool(true)
+	clt.MockRepositories.
+		On("List", "icecrime", mock.Anything).
+		Return(repositories, &github.Response{}, nil).
+		Once()
+
+	result, err := ExpandRepositories(clt, []string{"org:icecrime"})
+	if err != nil {
+		t.Fatalf("ExpandRepositories returned unexpected error %v", err)
+	}
+	if expected := []string{"icecrime/poule"}; !reflect.DeepEqual(result, expected) {
+		t.Fatalf("Expected repositories %v, got %v", expected, result)
+	}
+	test.AssertExpectations(clt, t)
+}
@@ -2,6 +2,7 @@ package runner
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"poule/configuration"
@@ -9,6 +10,9 @@ import (
 	"poule/operations"
 	"poule/operations/catalog"
 	"poule/operations/settings"
+	"poule/runner/plan"
+	"poule/runner/report"
+	"poule/runner/state"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/google/go-github/github"
@@ -25,6 +29,28 @@ type OperationRunner struct {
 
 	// Operation is the operation to execute.
 	Operation operations.Operation
+
+	// OperationName is the name under which applications of the operation are recorded.
+	OperationName string
+
+	// Plan collects the items accepted by the operation when not nil, in which case the operation
+	// isn't applied.
+	Plan *plan.Operation
+
+	// Report collects the outcome of the operation for each item when not nil.
+	Report *report.Report
+
+	// State is the store where applications of the operation are recorded.
+	State state.Store
+
+	// Event is the GitHub event which triggered the operation, if any.
+	Event *operations.Event
+
+	// Permissions caches the lookups of users permissions and memberships, if not nil.
+	Permissions *gh.PermissionCache
+
+	// client overrides the GitHub client created from Config, for testing purposes.
+	client gh.Client
 }
 
 // NewOperationRunner returns an OperationRunner.
@@ -32,6 +58,7 @@ func NewOperationRunner(config *configuration.Config, operation operations.Opera
 	return &OperationRunner{
 		Config:    config,
 		Operation: operation,
+		State:     state.NewMemoryStore(),
 	}
 }
 
@@ -54,28 +81,43 @@ func NewOperationRunnerFromConfig(config *configuration.Config, operationConfig
 		Config:        config,
 		GlobalFilters: filters,
 		Operation:     operation,
+		OperationName: operationConfig.Type,
+		State:         state.NewMemoryStore(),
 	}, nil
 }
 
 // Handle applies the operation to a single GitHub item.
 func (r *OperationRunner) Handle(item gh.Item) error {
-	return runSingle(r.Config, r.Operation, item, r.GlobalFilters)
+	return r.runSingle(item)
 }
 
 // HandleStock applies the operation to the entire stock of GitHub items.
 func (r *OperationRunner) HandleStock() error {
+	var errs Errors
 	if settings.FilterIncludesIssues(r.GlobalFilters) && r.Operation.Accepts()&operations.Issues == operations.Issues {
-		if err := runOnEveryItem(r.Config, r.Operation, &IssueLister{}, r.GlobalFilters); err != nil {
-			return err
-		}
-
+		errs = errs.Append(r.runOnEveryItem(r.makeLister(false, &IssueLister{})))
 	}
 	if settings.FilterIncludesPullRequests(r.GlobalFilters) && r.Operation.Accepts()&operations.PullRequests == operations.PullRequests {
-		if err := runOnEveryItem(r.Config, r.Operation, &PullRequestLister{}, r.GlobalFilters); err != nil {
-			return err
+		errs = errs.Append(r.runOnEveryItem(r.makeLister(true, &PullRequestLister{})))
+	}
+	return errs.ErrorOrNil()
+}
+
+// makeLister returns the lister to use for the stock of issues or pull requests: global filters
+// which can be expressed as search qualifiers are evaluated server-side using the Search API.
+// Listing is preferred when the only qualifiers are item types, which listers already distinguish,
+// as it returns complete pull requests.
+func (r *OperationRunner) makeLister(pullRequests bool, lister Lister) Lister {
+	if r.Config.DisableSearch {
+		return lister
+	}
+	qualifiers := settings.SearchQualifiers(r.GlobalFilters, time.Now())
+	for _, qualifier := range qualifiers {
+		if qualifier != "is:pr" && qualifier != "is:issue" {
+			return NewSearchLister(pullRequests, qualifiers, lister)
 		}
 	}
-	return nil
+	return lister
 }
 
 // Lister provides items for operations to run on.
@@ -137,74 +179,207 @@ func (r *PullRequestLister) ListItems(context *operations.Context, op operations
 	return items, resp, err
 }
 
-// runSingle runs the specified operations on a single GitHub item.
-func runSingle(c *configuration.Config, op operations.Operation, item gh.Item, filters settings.Filters) error {
-	context := operations.Context{}
-	context.Client = gh.MakeClient(c)
-	context.Username, context.Repository = c.SplitRepository()
+// makeContext returns the execution context for the operation.
+func (r *OperationRunner) makeContext() *operations.Context {
+	context := &operations.Context{}
+	if context.Client = r.client; context.Client == nil {
+		context.Client = gh.MakeClient(r.Config)
+	}
+	context.Username, context.Repository = r.Config.SplitRepository()
+	context.State = r.State
+	context.Event = r.Event
+	context.Permissions = r.Permissions
+	return context
+}
 
+// runSingle runs the operation on a single GitHub item.
+func (r *OperationRunner) runSingle(item gh.Item) error {
+	context := r.makeContext()
+	filterResult, userdata, err := r.filterItem(context, &item)
+	if err != nil || filterResult != operations.Accept {
+		r.reportFilter(item, filterResult, err)
+		return err
+	}
+	return r.applyItem(context, item, userdata)
+}
+
+// filterItem applies both the global filters and the operation-specific filtering to the item. Data
+// retrieved by the global filters (such as the issue related to a pull request) is kept in the item.
+func (r *OperationRunner) filterItem(context *operations.Context, item *gh.Item) (operations.FilterResult, interface{}, error) {
 	// Apply global filters to the item.
-	if !filters.Apply(context, item) {
-		return nil
+	if !r.GlobalFilters.Apply(*context, item) {
+		return operations.Reject, nil, nil
+	}
+
+	// Operations expect complete pull requests, which search results are not.
+	if err := item.CompletePullRequest(context.Client); err != nil {
+		return operations.Reject, nil, err
 	}
 
 	// Apply operation-specific filtering.
-	filterResult, userdata, err := op.Filter(&context, item)
-	if err != nil {
+	return r.Operation.Filter(context, *item)
+}
+
+// applyItem applies the operation to an item previously accepted by filterItem.
+func (r *OperationRunner) applyItem(context *operations.Context, item gh.Item, userdata interface{}) error {
+	c, op := r.Config, r.Operation
+	s := op.Describe(context, item, userdata)
+	if s != "" {
+		logrus.WithFields(logrus.Fields{
+			"dry_run":    c.DryRun,
+			"item_num":   item.Number(),
+			"item_type":  item.Type(),
+			"repository": c.Repository,
+		}).Info(s)
+	}
+	if r.Plan != nil {
+		r.report(item, report.Accepted, s, nil)
+		return r.Plan.Add(item, s, userdata)
+	}
+	if c.DryRun {
+		r.report(item, report.Accepted, s, nil)
+		return nil
+	}
+	if err := op.Apply(context, item, userdata); err != nil {
+		r.report(item, report.Failed, s, err)
 		return err
 	}
+	r.report(item, report.Applied, s, nil)
+	return r.record(item, userdata)
+}
 
-	// Proceed with operation application depending on the result of
-	// the filtering.
-	switch filterResult {
-	case operations.Accept:
-		if s := op.Describe(&context, item, userdata); s != "" {
-			logrus.WithFields(logrus.Fields{
-				"dry_run":    c.DryRun,
-				"item_num":   item.Number(),
-				"item_type":  item.Type(),
-				"repository": c.Repository,
-			}).Info(s)
-		}
-		if !c.DryRun {
-			if err := op.Apply(&context, item, userdata); err != nil {
-				return err
-			}
-		}
-		break
-	case operations.Terminal:
-		return nil
+// reportFilter reports the outcome of filtering an item which wasn't accepted.
+func (r *OperationRunner) reportFilter(item gh.Item, result operations.FilterResult, err error) {
+	switch {
+	case err != nil:
+		r.report(item, report.Failed, "", err)
+	case result == operations.Terminal:
+		r.report(item, report.Terminal, "", nil)
+	case result == operations.Reject:
+		r.report(item, report.Rejected, "", nil)
 	}
+}
 
-	return nil
+// report adds the outcome of the operation for the specified item to the report.
+func (r *OperationRunner) report(item gh.Item, outcome report.Outcome, description string, err error) {
+	if r.Report == nil {
+		return
+	}
+	entry := report.Entry{
+		Repository:  r.Config.Repository,
+		Operation:   r.OperationName,
+		ItemType:    item.Type(),
+		Number:      item.Number(),
+		Outcome:     outcome,
+		Description: description,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	r.Report.Add(entry)
 }
 
-// runOnEveryItem runs the specified operation on all known items as provided by the specified
-// lister.
-func runOnEveryItem(c *configuration.Config, op operations.Operation, lister Lister, filters settings.Filters) error {
-	context := operations.Context{}
-	context.Client = gh.MakeClient(c)
-	context.Username, context.Repository = c.SplitRepository()
+// record stores the application of the operation to the specified item.
+func (r *OperationRunner) record(item gh.Item, userdata interface{}) error {
+	if r.State == nil {
+		return nil
+	}
+	name := r.OperationName
+	if op, ok := r.Operation.(operations.RecordedOperation); ok {
+		name = op.RecordName()
+	}
+	if name == "" {
+		return nil
+	}
+	return r.State.Add(state.Record{
+		Repository: r.Config.Repository,
+		Number:     item.Number(),
+		Operation:  name,
+		Digest:     state.Digest(userdata),
+		Time:       time.Now(),
+	})
+}
 
+// runOnEveryItem runs the operation on all known items as provided by the specified lister.
+//
+// Items of a given page are filtered and applied concurrently by at most `Config.Concurrency`
+// workers, or sequentially when it is lower than 2. Errors for individual items don't interrupt the
+// processing of the stock: they are aggregated and returned once all items were handled.
+func (r *OperationRunner) runOnEveryItem(lister Lister) error {
+	var errs Errors
+	context := r.makeContext()
+	limiter := gh.RateLimiterForConfig(r.Config)
 	for page := 1; page != 0; {
-		items, resp, err := lister.ListItems(&context, op, page)
+		// Leave the configured share of the API budget to other consumers.
+		limiter.Wait(r.Config.RateLimitReserve)
+
+		items, resp, err := lister.ListItems(context, r.Operation, page)
 		if err != nil {
-			return err
+			return errs.Append(err).ErrorOrNil()
 		}
 
-		// Handle each issue, filtering them using the operation first.
-		for _, item := range items {
-			if err := runSingle(c, op, item, filters); err != nil {
-				return err
+		// Filter all items of the page. A Terminal result means that no subsequent item may be
+		// accepted: this is typically the case for operations working on sorted sets of data. Items
+		// following the first Terminal result are not filtered at all, and we only apply the
+		// operation to items which precede it before stopping the iteration there.
+		type filterOutcome struct {
+			result   operations.FilterResult
+			userdata interface{}
+			err      error
+		}
+		var mu sync.Mutex
+		terminal := len(items)
+		outcomes := make([]filterOutcome, len(items))
+		parallelize(r.Config.Concurrency, len(items), func(i int) {
+			mu.Lock()
+			skip := i > terminal
+			mu.Unlock()
+			if skip {
+				return
+			}
+			result, userdata, err := r.filterItem(context, &items[i])
+			outcomes[i] = filterOutcome{result, userdata, err}
+			if err == nil && result == operations.Terminal {
+				mu.Lock()
+				if i < terminal {
+					terminal = i
+				}
+				mu.Unlock()
 			}
+		})
+		for i := 0; i < len(items) && i <= terminal; i++ {
+			if outcomes[i].err != nil || outcomes[i].result != operations.Accept {
+				r.reportFilter(items[i], outcomes[i].result, outcomes[i].err)
+			}
+		}
+
+		// Apply the operation to all accepted items.
+		applyErrs := make([]error, terminal)
+		parallelize(r.Config.Concurrency, terminal, func(i int) {
+			switch outcome := outcomes[i]; {
+			case outcome.err != nil:
+				applyErrs[i] = errors.Wrapf(outcome.err, "failed to filter item #%d", items[i].Number())
+			case outcome.result == operations.Accept:
+				if err := r.applyItem(context, items[i], outcome.userdata); err != nil {
+					applyErrs[i] = errors.Wrapf(err, "failed to apply operation to item #%d", items[i].Number())
+				}
+			}
+		})
+		for _, err := range applyErrs {
+			errs = errs.Append(err)
+		}
+		if terminal != len(items) {
+			break
 		}
 
 		// Move on to the next page, and respect the specified delay to avoid
 		// hammering the GitHub API.
-		page = resp.NextPage
-		if c.Delay() > 0 {
-			time.Sleep(c.Delay())
+		if page = 0; resp != nil {
+			page = resp.NextPage
+		}
+		if r.Config.Delay() > 0 {
+			time.Sleep(r.Config.Delay())
 		}
 	}
-	return nil
+	return errs.ErrorOrNil()
 }
@@ -0,0 +1,161 @@
+package runner
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"poule/configuration"
+	"poule/gh"
+	"poule/operations"
+	"poule/runner/report"
+	"poule/test"
+
+	"github.com/google/go-github/github"
+)
+
+// sortedOperation accepts items up to a given number, after which it returns Terminal.
+type sortedOperation struct {
+	sync.Mutex
+	applied  []int
+	filtered []int
+	failing  int
+	terminal int
+}
+
+func (o *sortedOperation) Accepts() operations.AcceptedType {
+	return operations.Issues
+}
+
+func (o *sortedOperation) Apply(c *operations.Context, item gh.Item, userData interface{}) error {
+	if item.Number() == o.failing {
+		return fmt.Errorf("failure")
+	}
+	o.Lock()
+	defer o.Unlock()
+	o.applied = append(o.applied, item.Number())
+	return nil
+}
+
+func (o *sortedOperation) Describe(c *operations.Context, item gh.Item, userData interface{}) string {
+	return ""
+}
+
+func (o *sortedOperation) Filter(c *operations.Context, item gh.Item) (operations.FilterResult, interface{}, error) {
+	o.Lock()
+	o.filtered = append(o.filtered, item.Number())
+	o.Unlock()
+	if item.Number() >= o.terminal {
+		return operations.Terminal, nil, nil
+	}
+	return operations.Accept, nil, nil
+}
+
+func (o *sortedOperation) IssueListOptions(c *operations.Context) *github.IssueListByRepoOptions {
+	return &github.IssueListByRepoOptions{}
+}
+
+func (o *sortedOperation) PullRequestListOptions(c *operations.Context) *github.PullRequestListOptions {
+	return nil
+}
+
+// pagedLister returns pages of consecutively numbered issues.
+type pagedLister struct {
+	pages   int
+	perPage int
+}
+
+func (l *pagedLister) ListItems(context *operations.Context, op operations.Operation, page int) ([]gh.Item, *github.Response, error) {
+	items := []gh.Item{}
+	for i := 0; i < l.perPage; i++ {
+		items = append(items, test.NewIssueBuilder((page-1)*l.perPage+i).Item())
+	}
+	resp := &github.Response{}
+	if page < l.pages {
+		resp.NextPage = page + 1
+	}
+	return items, resp, nil
+}
+
+func TestRunOnEveryItemConcurrent(t *testing.T) {
+	config := &configuration.Config{
+		Concurrency: 4,
+		Repository:  test.Username + "/" + test.Repository,
+	}
+	op := &sortedOperation{failing: 3, terminal: 25}
+	r := NewOperationRunner(config, op)
+	r.Report = report.New()
+
+	err := r.runOnEveryItem(&pagedLister{pages: 5, perPage: 10})
+	if errs, ok := err.(Errors); !ok || len(errs) != 1 {
+		t.Fatalf("Expected a single aggregated error, got %v", err)
+	}
+
+	// All items before the terminal one should be applied, except for the failing one.
+	if len(op.applied) != op.terminal-1 {
+		t.Fatalf("Expected %d items to be applied, got %d", op.terminal-1, len(op.applied))
+	}
+	for _, number := range op.applied {
+		if number >= op.terminal || number == op.failing {
+			t.Fatalf("Unexpected application to item #%d", number)
+		}
+	}
+
+	// The report should have an entry for each item up to the terminal one.
+	outcomes := map[report.Outcome]int{}
+	for _, entry := range r.Report.Entries() {
+		outcomes[entry.Outcome]++
+	}
+	if outcomes[report.Applied] != op.terminal-1 || outcomes[report.Failed] != 1 || outcomes[report.Terminal] != 1 {
+		t.Fatalf("Unexpected report outcomes %v", outcomes)
+	}
+}
+
+func TestRunOnEveryItemSequential(t *testing.T) {
+	config := &configuration.Config{
+		Concurrency: 1,
+		Repository:  test.Username + "/" + test.Repository,
+	}
+	op := &sortedOperation{failing: -1, terminal: 25}
+	r := NewOperationRunner(config, op)
+
+	if err := r.runOnEveryItem(&pagedLister{pages: 5, perPage: 10}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	// Items are filtered in order, and none after the terminal one.
+	if len(op.filtered) != op.terminal+1 {
+		t.Fatalf("Expected %d items to be filtered, got %v", op.terminal+1, op.filtered)
+	}
+	for i, number := range op.filtered {
+		if number != i {
+			t.Fatalf("Unexpected filtering order %v", op.filtered)
+		}
+	}
+	if len(op.applied) != op.terminal {
+		t.Fatalf("Expected %d items to be applied, got %d", op.terminal, len(op.applied))
+	}
+}
+
+func TestRunOnEveryItemWithoutResponse(t *testing.T) {
+	config := &configuration.Config{
+		Repository: test.Username + "/" + test.Repository,
+	}
+	op := &sortedOperation{failing: -1, terminal: 100}
+	r := NewOperationRunner(config, op)
+
+	// A missing response ends the iteration after the first page.
+	if err := r.runOnEveryItem(&unpagedLister{}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(op.applied) != 1 {
+		t.Fatalf("Expected a single item to be applied, got %d", len(op.applied))
+	}
+}
+
+// unpagedLister returns a single issue without any response.
+type unpagedLister struct{}
+
+func (l *unpagedLister) ListItems(context *operations.Context, op operations.Operation, page int) ([]gh.Item, *github.Response, error) {
+	return []gh.Item{test.NewIssueBuilder(page).Item()}, nil, nil
+}
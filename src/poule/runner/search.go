@@ -0,0 +1,295 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"poule/gh"
+	"poule/operations"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+const (
+	// searchResultsCap is the maximum number of results the GitHub Search API returns for a query.
+	searchResultsCap = 1000
+
+	// searchPerPage is the maximum page size of the GitHub Search API.
+	searchPerPage = 100
+
+	// searchTimeFormat is the format of dates in search qualifiers.
+	searchTimeFormat = "2006-01-02T15:04:05Z"
+)
+
+// searchEpoch predates the creation of any GitHub item.
+var searchEpoch = time.Date(2008, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// SearchLister provides items for operations to run on using the GitHub Search API, which allows to
+// filter items server-side using search qualifiers.
+//
+// The Search API returns at most 1000 results for a given query: larger result sets are sliced into
+// date ranges of the sort field, which are listed in order. When the operation's list options can't
+// be expressed as a search query, the SearchLister falls back to the Fallback lister.
+type SearchLister struct {
+	// Fallback is the lister used when the operation's list options can't be translated.
+	Fallback Lister
+
+	// PullRequests is true when listing pull requests, and false when listing issues.
+	PullRequests bool
+
+	// Qualifiers are the search qualifiers derived from the global filters.
+	Qualifiers []string
+
+	// now is the current time, overridable for testing purposes.
+	now func() time.Time
+
+	initialized bool
+	fallback    bool
+	query       string
+	options     github.SearchOptions
+	windows     []string
+	window      int
+	windowPage  int
+}
+
+// NewSearchLister returns a SearchLister for issues or pull requests.
+func NewSearchLister(pullRequests bool, qualifiers []string, fallback Lister) *SearchLister {
+	return &SearchLister{
+		Fallback:     fallback,
+		PullRequests: pullRequests,
+		Qualifiers:   qualifiers,
+		now:          time.Now,
+	}
+}
+
+// ListItems returns a list of GitHub items for the specified operation to run on.
+func (l *SearchLister) ListItems(context *operations.Context, op operations.Operation, page int) ([]gh.Item, *github.Response, error) {
+	if !l.initialized || page == 1 {
+		if err := l.initialize(context, op); err != nil {
+			return nil, nil, err
+		}
+	}
+	if l.fallback {
+		return l.Fallback.ListItems(context, op, page)
+	}
+
+	// Retrieve the current page of the current window.
+	query := l.query
+	if window := l.windows[l.window]; window != "" {
+		query += " " + window
+	}
+	options := l.options
+	options.ListOptions = github.ListOptions{Page: l.windowPage, PerPage: searchPerPage}
+	result, resp, err := context.Client.Search().Issues(query, &options)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to search items with query %q", query)
+	}
+
+	// Move on to the next page or window.
+	next := &github.Response{}
+	if resp != nil {
+		*next = *resp
+	}
+	next.NextPage = 0
+	if resp != nil && resp.NextPage != 0 {
+		l.windowPage = resp.NextPage
+		next.NextPage = page + 1
+	} else if l.window+1 < len(l.windows) {
+		l.window, l.windowPage = l.window+1, 1
+		next.NextPage = page + 1
+	}
+
+	items := []gh.Item{}
+	for i := range result.Issues {
+		items = append(items, l.makeItem(context, &result.Issues[i]))
+	}
+	return items, next, nil
+}
+
+func (l *SearchLister) makeItem(context *operations.Context, issue *github.Issue) gh.Item {
+	// Search results don't hold their repository.
+	name := context.Repository
+	issue.Repository = &github.Repository{
+		FullName: github.String(context.Username + "/" + context.Repository),
+		Name:     &name,
+		Owner:    &github.User{Login: github.String(context.Username)},
+	}
+	if !l.PullRequests {
+		return gh.MakeIssueItem(issue)
+	}
+
+	// Search results are issues: the pull request is only retrieved when filters need attributes
+	// which are not shared with its issue, and once the item passed global filters.
+	return gh.MakePartialPullRequestItem(issue)
+}
+
+// initialize builds the search query from the operation's list options, and slices it into
+// windows of at most 1000 results.
+func (l *SearchLister) initialize(context *operations.Context, op operations.Operation) error {
+	l.initialized, l.fallback = true, false
+	l.window, l.windowPage = 0, 1
+
+	var ok bool
+	if l.PullRequests {
+		l.query, l.options, ok = pullRequestSearchQuery(op.PullRequestListOptions(context))
+	} else {
+		l.query, l.options, ok = issueSearchQuery(op.IssueListOptions(context))
+	}
+	if !ok {
+		logrus.Debug("list options can't be expressed as a search query: falling back to listing")
+		l.fallback = true
+		return nil
+	}
+	// The query already restricts the item type being listed.
+	terms := []string{fmt.Sprintf("repo:%s/%s", context.Username, context.Repository), l.query}
+	for _, qualifier := range l.Qualifiers {
+		if qualifier != "is:pr" && qualifier != "is:issue" {
+			terms = append(terms, qualifier)
+		}
+	}
+	l.query = strings.Join(terms, " ")
+
+	windows, err := l.makeWindows(context, searchEpoch, l.now().UTC())
+	if err != nil {
+		return err
+	}
+	l.windows = windows
+	if l.options.Order == "desc" {
+		for i, j := 0, len(l.windows)-1; i < j; i, j = i+1, j-1 {
+			l.windows[i], l.windows[j] = l.windows[j], l.windows[i]
+		}
+	}
+	return nil
+}
+
+// sliceField returns the date field used to slice large result sets, which is the sort field when
+// possible so that the global order is preserved.
+func (l *SearchLister) sliceField() string {
+	if l.options.Sort == "updated" {
+		return "updated"
+	}
+	return "created"
+}
+
+// makeWindows returns the list of qualifiers slicing the query into ascending date ranges of at
+// most 1000 results each. A single empty window is returned if the query doesn't need slicing.
+func (l *SearchLister) makeWindows(context *operations.Context, from, to time.Time) ([]string, error) {
+	count, err := l.count(context, "")
+	if err != nil || count <= searchResultsCap {
+		return []string{""}, err
+	}
+	return l.splitWindow(context, from, to)
+}
+
+func (l *SearchLister) splitWindow(context *operations.Context, from, to time.Time) ([]string, error) {
+	window := fmt.Sprintf("%s:%s..%s", l.sliceField(), from.Format(searchTimeFormat), to.Format(searchTimeFormat))
+	count, err := l.count(context, window)
+	if err != nil {
+		return nil, err
+	}
+	if count <= searchResultsCap || to.Sub(from) <= time.Second {
+		if count > searchResultsCap {
+			logrus.Warnf("search window %q has %d results: only the first %d will be processed", window, count, searchResultsCap)
+		}
+		if count == 0 {
+			return nil, nil
+		}
+		return []string{window}, nil
+	}
+
+	// Split the window in two halves, which don't overlap as search ranges are inclusive.
+	middle := from.Add(to.Sub(from) / 2).Truncate(time.Second)
+	lower, err := l.splitWindow(context, from, middle)
+	if err != nil {
+		return nil, err
+	}
+	upper, err := l.splitWindow(context, middle.Add(time.Second), to)
+	if err != nil {
+		return nil, err
+	}
+	return append(lower, upper...), nil
+}
+
+// count returns the total number of results for the query restricted by the specified window.
+func (l *SearchLister) count(context *operations.Context, window string) (int, error) {
+	query := l.query
+	if window != "" {
+		query += " " + window
+	}
+	result, _, err := context.Client.Search().Issues(query, &github.SearchOptions{
+		ListOptions: github.ListOptions{PerPage: 1},
+	})
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to search items with query %q", query)
+	}
+	if result.Total == nil {
+		return 0, nil
+	}
+	return *result.Total, nil
+}
+
+// issueSearchQuery translates issue list options into search qualifiers and options.
+func issueSearchQuery(o *github.IssueListByRepoOptions) (string, github.SearchOptions, bool) {
+	if o == nil || (o.Milestone != "" && o.Milestone != "none") {
+		return "", github.SearchOptions{}, false
+	}
+	qualifiers := []string{"is:issue"}
+	qualifiers = append(qualifiers, stateQualifier(o.State)...)
+	for _, label := range o.Labels {
+		qualifiers = append(qualifiers, fmt.Sprintf("label:%q", label))
+	}
+	for qualifier, value := range map[string]string{
+		"assignee": o.Assignee,
+		"author":   o.Creator,
+		"mentions": o.Mentioned,
+	} {
+		if value != "" {
+			qualifiers = append(qualifiers, qualifier+":"+value)
+		}
+	}
+	if o.Milestone == "none" {
+		qualifiers = append(qualifiers, "no:milestone")
+	}
+	if !o.Since.IsZero() {
+		qualifiers = append(qualifiers, "updated:>="+o.Since.UTC().Format(searchTimeFormat))
+	}
+	return strings.Join(qualifiers, " "), searchOptions(o.Sort, o.Direction), true
+}
+
+// pullRequestSearchQuery translates pull request list options into search qualifiers and options.
+func pullRequestSearchQuery(o *github.PullRequestListOptions) (string, github.SearchOptions, bool) {
+	if o == nil || o.Head != "" || (o.Sort != "" && o.Sort != "created" && o.Sort != "updated") {
+		return "", github.SearchOptions{}, false
+	}
+	qualifiers := []string{"is:pr"}
+	qualifiers = append(qualifiers, stateQualifier(o.State)...)
+	if o.Base != "" {
+		qualifiers = append(qualifiers, "base:"+o.Base)
+	}
+	return strings.Join(qualifiers, " "), searchOptions(o.Sort, o.Direction), true
+}
+
+func stateQualifier(state string) []string {
+	switch state {
+	case "", "open":
+		return []string{"state:open"}
+	case "closed":
+		return []string{"state:closed"}
+	}
+	return nil
+}
+
+// searchOptions returns the search options equivalent to the list sort order, which defaults to
+// descending creation date.
+func searchOptions(sort, direction string) github.SearchOptions {
+	if sort == "" {
+		sort = "created"
+	}
+	if direction == "" {
+		direction = "desc"
+	}
+	return github.SearchOptions{Sort: sort, Order: direction}
+}
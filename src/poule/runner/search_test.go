@@ -0,0 +1,255 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"poule/configuration"
+	"poule/gh"
+	"poule/operations"
+	"poule/operations/settings"
+	"poule/test"
+
+	"github.com/google/go-github/github"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakeSearch emulates the GitHub Search API over a set of issues sorted by descending creation
+// date, including the cap on the number of results.
+type fakeSearch struct {
+	issues  []github.Issue
+	queries []string
+}
+
+func (f *fakeSearch) matching(query string) []github.Issue {
+	from, to := time.Time{}, time.Now().Add(time.Hour)
+	for _, term := range strings.Fields(query) {
+		if !strings.HasPrefix(term, "created:") {
+			continue
+		}
+		bounds := strings.Split(strings.TrimPrefix(term, "created:"), "..")
+		from, _ = time.Parse(searchTimeFormat, bounds[0])
+		to, _ = time.Parse(searchTimeFormat, bounds[1])
+	}
+	var result []github.Issue
+	for _, issue := range f.issues {
+		if !issue.CreatedAt.Before(from) && !issue.CreatedAt.After(to) {
+			result = append(result, issue)
+		}
+	}
+	return result
+}
+
+func (f *fakeSearch) result(query string, opt *github.SearchOptions) *github.IssuesSearchResult {
+	f.queries = append(f.queries, query)
+	matching := f.matching(query)
+	total := len(matching)
+	if len(matching) > searchResultsCap {
+		matching = matching[:searchResultsCap]
+	}
+	page, perPage := opt.Page, opt.PerPage
+	if page == 0 {
+		page = 1
+	}
+	start, end := (page-1)*perPage, page*perPage
+	if start > len(matching) {
+		start = len(matching)
+	}
+	if end > len(matching) {
+		end = len(matching)
+	}
+	return &github.IssuesSearchResult{Total: &total, Issues: matching[start:end]}
+}
+
+func (f *fakeSearch) response(query string, opt *github.SearchOptions) *github.Response {
+	resp := &github.Response{}
+	if opt.Page*opt.PerPage < len(f.matching(query)) && opt.Page*opt.PerPage < searchResultsCap {
+		resp.NextPage = opt.Page + 1
+	}
+	return resp
+}
+
+func listAllItems(t *testing.T, lister Lister, context *operations.Context, op operations.Operation) []gh.Item {
+	var items []gh.Item
+	for page := 1; page != 0; {
+		pageItems, resp, err := lister.ListItems(context, op, page)
+		if err != nil {
+			t.Fatalf("ListItems returned unexpected error %v", err)
+		}
+		items = append(items, pageItems...)
+		page = resp.NextPage
+	}
+	return items
+}
+
+func TestSearchListerSlicesLargeResultSets(t *testing.T) {
+	now := time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)
+	search := &fakeSearch{}
+	for i := 0; i < 2500; i++ {
+		issue := test.NewIssueBuilder(i).Value
+		createdAt := now.Add(-time.Duration(i) * time.Hour)
+		issue.CreatedAt = &createdAt
+		search.issues = append(search.issues, *issue)
+	}
+
+	clt := &test.Client{}
+	clt.MockSearch.On("Issues", mock.Anything, mock.Anything).Return(search.result, search.response, nil)
+	context := &operations.Context{Client: clt, Username: test.Username, Repository: test.Repository}
+
+	lister := NewSearchLister(false, []string{`label:"bug"`}, &IssueLister{})
+	lister.now = func() time.Time { return now }
+	items := listAllItems(t, lister, context, &sortedOperation{})
+
+	// All items must be listed exactly once, in descending creation order.
+	if len(items) != len(search.issues) {
+		t.Fatalf("Expected %d items, got %d", len(search.issues), len(items))
+	}
+	for i := range items {
+		if items[i].Number() != i {
+			t.Fatalf("Expected item #%d at index %d, got #%d", i, i, items[i].Number())
+		}
+	}
+	expectedPrefix := "repo:" + test.Username + "/" + test.Repository + ` is:issue state:open label:"bug"`
+	for _, query := range search.queries {
+		if !strings.HasPrefix(query, expectedPrefix) {
+			t.Fatalf("Unexpected query %q", query)
+		}
+	}
+}
+
+func TestSearchListerItemTypeQualifiers(t *testing.T) {
+	search := &fakeSearch{}
+	clt := &test.Client{}
+	clt.MockSearch.On("Issues", mock.Anything, mock.Anything).Return(search.result, search.response, nil)
+	context := &operations.Context{Client: clt, Username: test.Username, Repository: test.Repository}
+
+	// Item type qualifiers of the filters don't contradict the type of items being listed.
+	lister := NewSearchLister(false, []string{"is:pr", `label:"bug"`}, &IssueLister{})
+	listAllItems(t, lister, context, &sortedOperation{})
+	expected := "repo:" + test.Username + "/" + test.Repository + ` is:issue state:open label:"bug"`
+	if len(search.queries) == 0 || search.queries[0] != expected {
+		t.Fatalf("Expected query %q, got %q", expected, search.queries)
+	}
+}
+
+func TestSearchListerFallback(t *testing.T) {
+	clt := &test.Client{}
+	context := &operations.Context{Client: clt, Username: test.Username, Repository: test.Repository}
+
+	// Filtering by milestone title cannot be expressed as a search query.
+	op := &sortedOperation{}
+	lister := NewSearchLister(false, []string{`label:"bug"`}, &pagedLister{pages: 2, perPage: 3})
+	items := listAllItems(t, lister, context, &milestoneOperation{op})
+	if len(items) != 6 {
+		t.Fatalf("Expected 6 items from the fallback lister, got %d", len(items))
+	}
+	test.AssertExpectations(clt, t)
+}
+
+// milestoneOperation lists issues of a specific milestone.
+type milestoneOperation struct {
+	*sortedOperation
+}
+
+func (o *milestoneOperation) IssueListOptions(c *operations.Context) *github.IssueListByRepoOptions {
+	return &github.IssueListByRepoOptions{Milestone: "1"}
+}
+
+func TestSearchQueryFromListOptions(t *testing.T) {
+	since := time.Date(2017, time.March, 1, 0, 0, 0, 0, time.UTC)
+	query, options, ok := issueSearchQuery(&github.IssueListByRepoOptions{
+		State:     "all",
+		Milestone: "none",
+		Creator:   "icecrime",
+		Labels:    []string{"status/needs info"},
+		Since:     since,
+		Sort:      "updated",
+		Direction: "asc",
+	})
+	expected := `is:issue label:"status/needs info" author:icecrime no:milestone updated:>=2017-03-01T00:00:00Z`
+	if !ok || query != expected {
+		t.Fatalf("Expected query %q, got %q", expected, query)
+	}
+	if options.Sort != "updated" || options.Order != "asc" {
+		t.Fatalf("Unexpected search options %#v", options)
+	}
+
+	if _, _, ok := pullRequestSearchQuery(&github.PullRequestListOptions{Sort: "popularity"}); ok {
+		t.Fatalf("Expected pull requests sorted by popularity not to be translated")
+	}
+}
+
+// baseOperation applies to pull requests, and records their base branch.
+type baseOperation struct {
+	sortedOperation
+	bases []string
+}
+
+func (o *baseOperation) Accepts() operations.AcceptedType {
+	return operations.PullRequests
+}
+
+func (o *baseOperation) Filter(c *operations.Context, item gh.Item) (operations.FilterResult, interface{}, error) {
+	o.bases = append(o.bases, *item.PullRequest.Base.Ref)
+	return operations.Reject, nil, nil
+}
+
+func (o *baseOperation) PullRequestListOptions(c *operations.Context) *github.PullRequestListOptions {
+	return &github.PullRequestListOptions{}
+}
+
+func TestSearchListerPartialPullRequests(t *testing.T) {
+	clt := &test.Client{}
+	clt.MockSearch.On("Issues", mock.Anything, mock.Anything).Return(&github.IssuesSearchResult{
+		Total: github.Int(2),
+		Issues: []github.Issue{
+			*test.NewIssueBuilder(1).Labels([]string{"bug"}).Value,
+			*test.NewIssueBuilder(2).Labels([]string{"bug", "wip"}).Value,
+		},
+	}, &github.Response{}, nil)
+
+	// Only the pull request which passes global filters is retrieved, before the operation's
+	// filtering.
+	clt.MockPullRequests.On("Get", test.Username, test.Repository, 1).Return(
+		test.NewPullRequestBuilder(1).BaseBranch(test.Username, test.Repository, "master", test.CommitSHA[0]).Value, nil, nil,
+	).Once()
+
+	filter, err := settings.ParseFilterExpression("labels:bug and not labels:wip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := &baseOperation{}
+	r := NewOperationRunner(&configuration.Config{Repository: test.Username + "/" + test.Repository}, op)
+	r.GlobalFilters = settings.Filters{filter}
+	r.client = clt
+	lister := r.makeLister(true, &PullRequestLister{})
+	if _, ok := lister.(*SearchLister); !ok {
+		t.Fatalf("Expected a search lister, got %T", lister)
+	}
+	if err := r.runOnEveryItem(lister); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(op.bases) != 1 || op.bases[0] != "master" {
+		t.Fatalf("Expected the operation to filter a complete pull request, got bases %v", op.bases)
+	}
+	test.AssertExpectations(clt, t)
+}
+
+func TestMakeListerWithoutQualifiers(t *testing.T) {
+	// Item type qualifiers alone don't narrow down listings.
+	filter, err := settings.ParseFilterExpression("is:pr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewOperationRunner(&configuration.Config{}, &baseOperation{})
+	r.GlobalFilters = settings.Filters{filter}
+	if lister := r.makeLister(true, &PullRequestLister{}); !isPullRequestLister(lister) {
+		t.Fatalf("Expected a pull request lister, got %T", lister)
+	}
+}
+
+func isPullRequestLister(lister Lister) bool {
+	_, ok := lister.(*PullRequestLister)
+	return ok
+}
@@ -0,0 +1,86 @@
+package state
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+)
+
+// FileStore is a Store persisted as an append-only file of JSON records, one per line. The entire
+// history is loaded in memory when the store is opened.
+type FileStore struct {
+	memory *MemoryStore
+	file   *os.File
+}
+
+// OpenFileStore opens the store persisted at the specified path, creating it if necessary. An
+// incomplete last record, as left by a process interrupted while writing it, is discarded.
+func OpenFileStore(path string) (*FileStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open state file %q", path)
+	}
+
+	// Load the existing history.
+	store := &FileStore{
+		memory: NewMemoryStore(),
+		file:   file,
+	}
+	reader := bufio.NewReader(file)
+	var offset int64
+	for line := 1; ; line++ {
+		b, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(b) != 0 {
+				logrus.WithField("path", path).Warnf("discarding incomplete record at line %d of state file", line)
+				if err := file.Truncate(offset); err != nil {
+					file.Close()
+					return nil, errors.Wrapf(err, "failed to truncate state file %q", path)
+				}
+			}
+			break
+		} else if err != nil {
+			file.Close()
+			return nil, errors.Wrapf(err, "failed to read state file %q", path)
+		}
+		offset += int64(len(b))
+
+		var record Record
+		if err := json.Unmarshal(b, &record); err != nil {
+			file.Close()
+			return nil, errors.Wrapf(err, "malformed record in state file %q at line %d", path, line)
+		}
+		store.memory.add(record)
+	}
+	return store, nil
+}
+
+// Add records the application of an operation.
+func (f *FileStore) Add(record Record) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	f.memory.lock.Lock()
+	defer f.memory.lock.Unlock()
+	if _, err := f.file.Write(append(b, '\n')); err != nil {
+		return errors.Wrap(err, "failed to write state file")
+	}
+	f.memory.add(record)
+	return nil
+}
+
+// Find returns all records for the specified item and operation, ordered by ascending time.
+func (f *FileStore) Find(repository string, number int, operation string) ([]Record, error) {
+	return f.memory.Find(repository, number, operation)
+}
+
+// Close releases any resource held by the store.
+func (f *FileStore) Close() error {
+	return f.file.Close()
+}
@@ -0,0 +1,55 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// MemoryStore is an in-memory Store, typically used for tests and one-shot command line
+// invocations.
+type MemoryStore struct {
+	lock    sync.RWMutex
+	records map[string][]Record
+}
+
+// NewMemoryStore returns a new empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records: make(map[string][]Record),
+	}
+}
+
+// Add records the application of an operation.
+func (m *MemoryStore) Add(record Record) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.add(record)
+	return nil
+}
+
+// Find returns all records for the specified item and operation, ordered by ascending time.
+func (m *MemoryStore) Find(repository string, number int, operation string) ([]Record, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	records := m.records[recordKey(repository, number, operation)]
+	return append([]Record(nil), records...), nil
+}
+
+// Close releases any resource held by the store.
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+func (m *MemoryStore) add(record Record) {
+	key := recordKey(record.Repository, record.Number, record.Operation)
+	records := append(m.records[key], record)
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time.Before(records[j].Time)
+	})
+	m.records[key] = records
+}
+
+func recordKey(repository string, number int, operation string) string {
+	return fmt.Sprintf("%s#%d:%s", repository, number, operation)
+}
@@ -0,0 +1,68 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Record describes the application of an operation to a GitHub item.
+type Record struct {
+	// Repository is the full name of the GitHub repository (e.g., "moby/moby").
+	Repository string `json:"repository"`
+
+	// Number is the number of the GitHub item.
+	Number int `json:"number"`
+
+	// Operation is the name of the applied operation.
+	Operation string `json:"operation"`
+
+	// Digest is a digest of the operation specific user data at the time of application.
+	Digest string `json:"digest"`
+
+	// Time is the time of application.
+	Time time.Time `json:"time"`
+}
+
+// Store records the history of applied operations. Implementations must be safe for concurrent
+// use.
+type Store interface {
+	// Add records the application of an operation.
+	Add(record Record) error
+
+	// Find returns all records for the specified item and operation, ordered by ascending time.
+	Find(repository string, number int, operation string) ([]Record, error)
+
+	// Close releases any resource held by the store.
+	Close() error
+}
+
+// Open returns the store for the specified path: an empty path results in an in-memory store.
+func Open(path string) (Store, error) {
+	if path == "" {
+		return NewMemoryStore(), nil
+	}
+	return OpenFileStore(path)
+}
+
+// Digest returns a digest of the specified operation user data.
+func Digest(userData interface{}) string {
+	b, err := json.Marshal(userData)
+	if err != nil {
+		b = []byte(fmt.Sprintf("%#v", userData))
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// Last returns the most recent record for the specified item and operation, or nil if the
+// operation was never applied.
+func Last(s Store, repository string, number int, operation string) (*Record, error) {
+	records, err := s.Find(repository, number, operation)
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	return &records[len(records)-1], nil
+}
@@ -0,0 +1,101 @@
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "poule-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	// Record operations out of order.
+	now := time.Now().UTC().Truncate(time.Second)
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open returned unexpected error %v", err)
+	}
+	for _, record := range []Record{
+		{Repository: "icecrime/poule", Number: 1, Operation: "prune:ping", Digest: Digest(2), Time: now},
+		{Repository: "icecrime/poule", Number: 1, Operation: "prune:ping", Digest: Digest(1), Time: now.Add(-time.Hour)},
+		{Repository: "icecrime/poule", Number: 2, Operation: "prune:ping", Digest: Digest(3), Time: now},
+	} {
+		if err := store.Add(record); err != nil {
+			t.Fatalf("Add returned unexpected error %v", err)
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close returned unexpected error %v", err)
+	}
+
+	// Reopen the store and verify the history was persisted.
+	store, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open returned unexpected error %v", err)
+	}
+	defer store.Close()
+
+	records, err := store.Find("icecrime/poule", 1, "prune:ping")
+	if err != nil {
+		t.Fatalf("Find returned unexpected error %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	if last, _ := Last(store, "icecrime/poule", 1, "prune:ping"); last == nil || !last.Time.Equal(now) || last.Digest != Digest(2) {
+		t.Fatalf("Last returned unexpected record %v", last)
+	}
+	if last, _ := Last(store, "icecrime/poule", 1, "prune:warn"); last != nil {
+		t.Fatalf("Last returned unexpected record %v", last)
+	}
+}
+
+func TestFileStoreIncompleteRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "poule-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	// A record interrupted while being written is discarded, while the previous ones are kept.
+	complete := `{"repository":"icecrime/poule","number":1,"operation":"prune:ping","time":"2017-01-01T00:00:00Z"}` + "\n"
+	if err := ioutil.WriteFile(path, []byte(complete+`{"repository":"icecrime/poule","num`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open returned unexpected error %v", err)
+	}
+	if records, _ := store.Find("icecrime/poule", 1, "prune:ping"); len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+	if err := store.Add(Record{Repository: "icecrime/poule", Number: 2, Operation: "prune:ping"}); err != nil {
+		t.Fatalf("Add returned unexpected error %v", err)
+	}
+	store.Close()
+
+	store, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open returned unexpected error %v after discarding an incomplete record", err)
+	}
+	defer store.Close()
+	if records, _ := store.Find("icecrime/poule", 2, "prune:ping"); len(records) != 1 {
+		t.Fatalf("Expected record added after an incomplete one to be persisted, got %d", len(records))
+	}
+
+	// Malformed records which aren't the last one are still refused.
+	if err := ioutil.WriteFile(path, []byte("{\n"+complete), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); err == nil {
+		t.Fatalf("Expected Open to fail on a malformed record")
+	}
+}
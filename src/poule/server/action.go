@@ -3,12 +3,14 @@ package server
 import (
 	"poule/configuration"
 	"poule/gh"
+	"poule/operations"
 	"poule/runner"
+	"poule/runner/state"
 
 	"github.com/Sirupsen/logrus"
 )
 
-func executeAction(config *configuration.Config, action configuration.Action, item gh.Item) error {
+func executeAction(config *configuration.Config, store state.Store, permissions *gh.PermissionCache, action configuration.Action, item gh.Item, event *operations.Event) error {
 	for _, opConfig := range action.Operations {
 		logrus.WithFields(logrus.Fields{
 			"operation":  opConfig.Type,
@@ -20,6 +22,9 @@ func executeAction(config *configuration.Config, action configuration.Action, it
 		if err != nil {
 			return err
 		}
+		opRunner.State = store
+		opRunner.Permissions = permissions
+		opRunner.Event = event
 		if err := opRunner.Handle(item); err != nil {
 			return err
 		}
@@ -27,7 +32,10 @@ func executeAction(config *configuration.Config, action configuration.Action, it
 	return nil
 }
 
-func executeActionOnAllItems(config *configuration.Config, action configuration.Action) error {
+func executeActionOnAllItems(config *configuration.Config, store state.Store, permissions *gh.PermissionCache, action configuration.Action) error {
+	if err := gh.ResolveCredentials(config); err != nil {
+		return err
+	}
 	for _, opConfig := range action.Operations {
 		logrus.WithFields(logrus.Fields{
 			"operation": opConfig.Type,
@@ -37,6 +45,8 @@ func executeActionOnAllItems(config *configuration.Config, action configuration.
 		if err != nil {
 			return err
 		}
+		opRunner.State = store
+		opRunner.Permissions = permissions
 		if err := opRunner.HandleStock(); err != nil {
 			return err
 		}
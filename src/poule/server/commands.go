@@ -0,0 +1,341 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"poule/configuration"
+	"poule/gh"
+	"poule/operations"
+	"poule/operations/catalog"
+	"poule/operations/settings"
+	"poule/runner"
+	"poule/runner/report"
+	"poule/runner/state"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+const (
+	// commandPrefix starts the lines of issue comments which are commands.
+	commandPrefix = "/poule"
+
+	// defaultCommandsPermission is the default minimum permission level required to run commands.
+	defaultCommandsPermission = "write"
+)
+
+// command is an operation requested in an issue comment (e.g., "/poule rebuild janky").
+type command struct {
+	// Line is the comment line the command was parsed from.
+	Line string
+
+	// Name is the name of the operation.
+	Name string
+
+	// Args are the command-line arguments of the operation.
+	Args []string
+}
+
+// parseCommands returns the commands of an issue comment, each of which is a line starting with the
+// command prefix. Lines inside fenced code blocks are ignored, so that commands can be quoted.
+func parseCommands(body string) ([]command, error) {
+	commands := []command{}
+	inCodeBlock := false
+	for _, line := range strings.Split(strings.Replace(body, "\r", "", -1), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+		if inCodeBlock || !strings.HasPrefix(line, commandPrefix) {
+			continue
+		}
+		// The prefix may be part of another word (e.g., "/poule-updater").
+		if rest := strings.TrimPrefix(line, commandPrefix); rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+			continue
+		}
+		args, err := splitArguments(strings.TrimPrefix(line, commandPrefix))
+		switch {
+		case err != nil:
+			return nil, errors.Wrapf(err, "invalid command %q", line)
+		case len(args) == 0:
+			return nil, errors.Errorf("invalid command %q: expected an operation", line)
+		}
+		commands = append(commands, command{Line: line, Name: args[0], Args: args[1:]})
+	}
+	return commands, nil
+}
+
+// splitArguments splits a command line into arguments separated by spaces, which can be enclosed in
+// single or double quotes.
+func splitArguments(line string) ([]string, error) {
+	args := []string{}
+	var current []rune
+	var quote rune
+	inArgument := false
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current = append(current, r)
+		case r == '"' || r == '\'':
+			quote, inArgument = r, true
+		case r == ' ' || r == '\t':
+			if inArgument {
+				args = append(args, string(current))
+				current, inArgument = nil, false
+			}
+		default:
+			current, inArgument = append(current, r), true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.Errorf("unterminated quote %c", quote)
+	}
+	if inArgument {
+		args = append(args, string(current))
+	}
+	return args, nil
+}
+
+// handleCommands runs the commands of a newly created issue comment, if commands are enabled.
+func (s *Server) handleCommands(snapshot *snapshot, event *operations.Event, item gh.Item) error {
+	if len(snapshot.Server.Commands) == 0 || event.Name != "issue_comment" || event.Action != "created" {
+		return nil
+	}
+	config := makeExecutionConfig(snapshot.Server, item.Repository())
+	return newCommandRunner(gh.MakeClient(config), config, s.state, s.permissions, snapshot.Server, snapshot.Actions(item.Repository())).Execute(event, item)
+}
+
+// commandRunner runs the commands of an issue comment on behalf of its author.
+type commandRunner struct {
+	client gh.Client
+	config *configuration.Config
+	store  state.Store
+
+	// allowed lists the operations which can be run as commands.
+	allowed configuration.StringSlice
+
+	// permission is the minimum permission level of the comment author on the repository.
+	permission string
+
+	// actions are the actions of the repository: the author of a command must also be allowed to
+	// trigger every restricted action which defines the operation.
+	actions []configuration.Action
+
+	// permissions caches the permission levels of comment authors, and is shared with operations.
+	permissions *gh.PermissionCache
+
+	// run applies the operation to the item, overridable for testing purposes.
+	run func(r *runner.OperationRunner, item gh.Item) error
+}
+
+func newCommandRunner(client gh.Client, config *configuration.Config, store state.Store, permissions *gh.PermissionCache, serverConfig *configuration.Server, actions []configuration.Action) *commandRunner {
+	permission := serverConfig.CommandsPermission
+	if permission == "" {
+		permission = defaultCommandsPermission
+	}
+	return &commandRunner{
+		client:      client,
+		config:      config,
+		store:       store,
+		allowed:     serverConfig.Commands,
+		permission:  permission,
+		actions:     actions,
+		permissions: permissions,
+		run: func(r *runner.OperationRunner, item gh.Item) error {
+			return r.Handle(item)
+		},
+	}
+}
+
+// Execute runs the commands of the comment of the event on the item, and reports the outcome by
+// reacting to the comment, and by replying when any of the commands didn't succeed. Only failing to
+// check the permission of the author is returned as an error: other failures are reported to the
+// author, as retrying the event could run some of the commands twice.
+func (r *commandRunner) Execute(event *operations.Event, item gh.Item) error {
+	if event.Comment == nil || event.Comment.Body == nil || event.Comment.ID == nil {
+		return nil
+	}
+	commands, err := parseCommands(*event.Comment.Body)
+	switch {
+	case err != nil:
+		r.respond(event, item, "confused", []string{err.Error()})
+		return nil
+	case len(commands) == 0:
+		return nil
+	}
+
+	// Commands are restricted to users with a sufficient permission on the repository.
+	login := commentAuthor(event)
+	if login == "" {
+		return nil
+	}
+	owner, repo := r.config.SplitRepository()
+	level, role, err := r.permissions.PermissionLevel(r.client, owner, repo, login)
+	if err != nil {
+		return err
+	}
+	if !configuration.HasPermission(configuration.RolePermission(level, role), r.permission) {
+		logrus.WithFields(logrus.Fields{
+			"number":     item.Number(),
+			"repository": r.config.Repository,
+			"sender":     login,
+		}).Warn("rejecting commands from user without permission")
+		r.respond(event, item, "-1", []string{fmt.Sprintf("commands require the %q permission on the repository", r.permission)})
+		return nil
+	}
+
+	// Operations on pull requests expect the pull request object rather than its issue.
+	if item.IsIssue() && item.Issue.PullRequestLinks != nil {
+		pr, _, err := r.client.PullRequests().Get(owner, repo, item.Number())
+		if err != nil {
+			r.respond(event, item, "confused", []string{fmt.Sprintf("failed to retrieve pull request #%d: %v", item.Number(), err)})
+			return nil
+		}
+		issue := item.Issue
+		item = gh.MakePullRequestItem(pr)
+		item.Issue = issue
+	}
+
+	var problems []string
+	for _, cmd := range commands {
+		if problem := r.executeCommand(event, item, cmd); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+	if len(problems) != 0 {
+		r.respond(event, item, "confused", problems)
+	} else {
+		r.respond(event, item, "+1", nil)
+	}
+	return nil
+}
+
+// executeCommand runs a single command, and returns a description of the problem if it didn't
+// succeed.
+func (r *commandRunner) executeCommand(event *operations.Event, item gh.Item, cmd command) string {
+	logrus.WithFields(logrus.Fields{
+		"command":    cmd.Line,
+		"number":     item.Number(),
+		"repository": r.config.Repository,
+		"sender":     commentAuthor(event),
+	}).Info("running command")
+
+	descriptor, ok := catalog.ByNameIndex[cmd.Name]
+	if !ok || !r.allowed.Contains(cmd.Name) {
+		return fmt.Sprintf("`%s`: unknown command (available commands: %s)", cmd.Line, strings.Join(r.allowed, ", "))
+	}
+	if problem := r.checkRestrictions(event, cmd); problem != "" {
+		return problem
+	}
+	opContext, err := catalog.ParseOperationArguments(nil, descriptor, cmd.Args)
+	if err != nil {
+		return fmt.Sprintf("`%s`: invalid arguments: %v", cmd.Line, err)
+	}
+	op, err := descriptor.OperationFromCli(opContext)
+	if err != nil {
+		return fmt.Sprintf("`%s`: %v", cmd.Line, err)
+	}
+	filters, err := settings.ParseCliFilters(opContext)
+	if err != nil {
+		return fmt.Sprintf("`%s`: %v", cmd.Line, err)
+	}
+	switch {
+	case item.IsPullRequest() && op.Accepts()&operations.PullRequests == 0:
+		return fmt.Sprintf("`%s`: the operation doesn't apply to pull requests", cmd.Line)
+	case item.IsIssue() && op.Accepts()&operations.Issues == 0:
+		return fmt.Sprintf("`%s`: the operation doesn't apply to issues", cmd.Line)
+	}
+
+	opRunner := runner.NewOperationRunner(r.config, op)
+	opRunner.Event = event
+	opRunner.GlobalFilters = filters
+	opRunner.OperationName = cmd.Name
+	opRunner.Permissions = r.permissions
+	opRunner.Report = report.New()
+	opRunner.State = r.store
+	if err := r.run(opRunner, item); err != nil {
+		return fmt.Sprintf("`%s` failed: %v", cmd.Line, err)
+	}
+	for _, entry := range opRunner.Report.Entries() {
+		if entry.Outcome == report.Rejected || entry.Outcome == report.Terminal {
+			return fmt.Sprintf("`%s`: the operation doesn't apply to this %s", cmd.Line, strings.Replace(entry.ItemType, "_", " ", -1))
+		}
+	}
+	return ""
+}
+
+// checkRestrictions verifies that the author of the comment is allowed to trigger the restricted
+// actions which define the operation of the command, so that commands can't be used to bypass
+// their allowed_senders, allowed_teams, and min_permission settings. It returns a description of
+// the problem if the author isn't allowed.
+func (r *commandRunner) checkRestrictions(event *operations.Event, cmd command) string {
+	login := commentAuthor(event)
+	for _, action := range r.actions {
+		if !action.IsRestricted() || !definesOperation(action, cmd.Name) {
+			continue
+		}
+		allowed, err := isUserAllowed(r.client, r.permissions, action, login, r.config.Repository)
+		switch {
+		case err != nil:
+			return fmt.Sprintf("`%s`: failed to check permissions: %v", cmd.Line, err)
+		case !allowed:
+			logrus.WithFields(logrus.Fields{
+				"command":    cmd.Line,
+				"repository": r.config.Repository,
+				"sender":     login,
+			}).Warn("rejecting restricted command from unauthorized user")
+			return fmt.Sprintf("`%s`: the operation is restricted to some users", cmd.Line)
+		}
+	}
+	return ""
+}
+
+// definesOperation returns whether the action has an operation of the specified type.
+func definesOperation(action configuration.Action, name string) bool {
+	for _, opConfig := range action.Operations {
+		if opConfig.Type == name {
+			return true
+		}
+	}
+	return false
+}
+
+// respond reacts to the comment of the event, and replies with the problems if any. Failures are
+// logged, and nothing is posted in dry run mode.
+func (r *commandRunner) respond(event *operations.Event, item gh.Item, reaction string, problems []string) {
+	owner, repo := r.config.SplitRepository()
+	fields := logrus.Fields{
+		"number":     item.Number(),
+		"reaction":   reaction,
+		"repository": r.config.Repository,
+	}
+	if r.config.DryRun {
+		logrus.WithFields(fields).Infof("not responding to commands in dry run mode: %s", strings.Join(problems, "; "))
+		return
+	}
+	if _, _, err := r.client.Reactions().CreateIssueCommentReaction(owner, repo, *event.Comment.ID, reaction); err != nil {
+		logrus.WithFields(fields).Errorf("failed to react to comment: %v", err)
+	}
+	if len(problems) == 0 {
+		return
+	}
+	body := fmt.Sprintf("@%s:\n\n- %s", commentAuthor(event), strings.Join(problems, "\n- "))
+	if _, _, err := r.client.Issues().CreateComment(owner, repo, item.Number(), &github.IssueComment{Body: &body}); err != nil {
+		logrus.WithFields(fields).Errorf("failed to reply to comment: %v", err)
+	}
+}
+
+// commentAuthor returns the login of the author of the comment of the event.
+func commentAuthor(event *operations.Event) string {
+	switch {
+	case event.Comment != nil && event.Comment.User != nil && event.Comment.User.Login != nil:
+		return *event.Comment.User.Login
+	default:
+		return event.SenderLogin()
+	}
+}
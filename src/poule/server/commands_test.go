@@ -0,0 +1,164 @@
+package server
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"poule/configuration"
+	"poule/gh"
+	"poule/operations"
+	"poule/runner"
+	"poule/runner/state"
+	"poule/test"
+
+	"github.com/google/go-github/github"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestParseCommands(t *testing.T) {
+	for body, expected := range map[string][]command{
+		"LGTM":                            {},
+		"/poule-updater is great":         {},
+		"/poule rebuild janky":            {{Line: "/poule rebuild janky", Name: "rebuild", Args: []string{"janky"}}},
+		"Hi!\r\n  /poule   rebuild  \r\n": {{Line: "/poule   rebuild", Name: "rebuild", Args: []string{}}},
+		`/poule label "status/needs info:^Needs info"`:          {{Line: `/poule label "status/needs info:^Needs info"`, Name: "label", Args: []string{"status/needs info:^Needs info"}}},
+		"```\n/poule rebuild\n```\n/poule prune --action close": {{Line: "/poule prune --action close", Name: "prune", Args: []string{"--action", "close"}}},
+	} {
+		commands, err := parseCommands(body)
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %v", body, err)
+		}
+		if !reflect.DeepEqual(commands, expected) {
+			t.Fatalf("Expected commands %#v for %q, got %#v", expected, body, commands)
+		}
+	}
+
+	for body, expected := range map[string]string{
+		"/poule":                 "expected an operation",
+		"/poule label 'unquoted": "unterminated quote",
+	} {
+		if _, err := parseCommands(body); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("Expected error containing %q for %q, got %v", expected, body, err)
+		}
+	}
+}
+
+func makeCommandEvent(body string) *operations.Event {
+	return &operations.Event{
+		Name:   "issue_comment",
+		Action: "created",
+		Comment: &github.IssueComment{
+			ID:   github.Int(42),
+			Body: github.String(body),
+			User: &github.User{Login: github.String("icecrime")},
+		},
+	}
+}
+
+func makeCommandRunner(clt *test.Client, ran *[]string) *commandRunner {
+	r := newCommandRunner(clt, &configuration.Config{Repository: test.Username + "/" + test.Repository}, state.NewMemoryStore(), gh.NewPermissionCache(0), &configuration.Server{
+		Commands: []string{"label", "rebuild"},
+	}, nil)
+	r.run = func(opRunner *runner.OperationRunner, item gh.Item) error {
+		*ran = append(*ran, opRunner.OperationName)
+		return nil
+	}
+	return r
+}
+
+func TestCommandRunner(t *testing.T) {
+	clt := &test.Client{}
+	clt.MockRepositories.On("GetPermissionLevel", test.Username, test.Repository, "icecrime").Return(&gh.RepositoryPermissionLevel{
+		RepositoryPermissionLevel: github.RepositoryPermissionLevel{Permission: github.String("write")},
+	}, nil, nil)
+	clt.MockReactions.On("CreateIssueCommentReaction", test.Username, test.Repository, 42, "+1").Return(nil, nil, nil).Once()
+
+	var ran []string
+	item := test.NewIssueBuilder(test.IssueNumber).Item()
+	if err := makeCommandRunner(clt, &ran).Execute(makeCommandEvent("/poule label bug:crash"), item); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(ran, []string{"label"}) {
+		t.Fatalf("Expected label operation to run, got %v", ran)
+	}
+
+	// Problems are reported in a reply to the author.
+	clt.MockReactions.On("CreateIssueCommentReaction", test.Username, test.Repository, 42, "confused").Return(nil, nil, nil).Once()
+	clt.MockIssues.On("CreateComment", test.Username, test.Repository, test.IssueNumber, mock.MatchedBy(func(comment *github.IssueComment) bool {
+		return strings.HasPrefix(*comment.Body, "@icecrime:") &&
+			strings.Contains(*comment.Body, "`/poule prune`: unknown command (available commands: label, rebuild)") &&
+			strings.Contains(*comment.Body, "`/poule rebuild`: the operation doesn't apply to issues")
+	})).Return(nil, nil, nil).Once()
+
+	ran = nil
+	if err := makeCommandRunner(clt, &ran).Execute(makeCommandEvent("/poule prune\n/poule rebuild\n/poule label bug:crash"), item); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(ran, []string{"label"}) {
+		t.Fatalf("Expected label operation to run, got %v", ran)
+	}
+	test.AssertExpectations(clt, t)
+}
+
+func TestCommandRunnerPermission(t *testing.T) {
+	clt := &test.Client{}
+	clt.MockRepositories.On("GetPermissionLevel", test.Username, test.Repository, "icecrime").Return(&gh.RepositoryPermissionLevel{
+		RepositoryPermissionLevel: github.RepositoryPermissionLevel{Permission: github.String("read")},
+	}, nil, nil)
+	clt.MockReactions.On("CreateIssueCommentReaction", test.Username, test.Repository, 42, "-1").Return(nil, nil, nil)
+	clt.MockIssues.On("CreateComment", test.Username, test.Repository, test.IssueNumber, mock.Anything).Return(nil, nil, nil)
+
+	var ran []string
+	item := test.NewIssueBuilder(test.IssueNumber).Item()
+	if err := makeCommandRunner(clt, &ran).Execute(makeCommandEvent("/poule label bug:crash"), item); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(ran) != 0 {
+		t.Fatalf("Expected no operation to run, got %v", ran)
+	}
+	test.AssertExpectations(clt, t)
+}
+
+func TestCommandRunnerRestrictedAction(t *testing.T) {
+	clt := &test.Client{}
+	clt.MockRepositories.On("GetPermissionLevel", test.Username, test.Repository, "icecrime").Return(&gh.RepositoryPermissionLevel{
+		RepositoryPermissionLevel: github.RepositoryPermissionLevel{Permission: github.String("write")},
+	}, nil, nil)
+	clt.MockReactions.On("CreateIssueCommentReaction", test.Username, test.Repository, 42, "confused").Return(nil, nil, nil).Once()
+	clt.MockIssues.On("CreateComment", test.Username, test.Repository, test.IssueNumber, mock.MatchedBy(func(comment *github.IssueComment) bool {
+		return strings.Contains(*comment.Body, "`/poule label bug:crash`: the operation is restricted to some users")
+	})).Return(nil, nil, nil).Once()
+
+	// Commands can't bypass the restrictions of the actions which define the operation.
+	var ran []string
+	r := makeCommandRunner(clt, &ran)
+	r.actions = []configuration.Action{
+		{
+			Operations:     []configuration.OperationConfiguration{{Type: "rebuild"}},
+			AllowedSenders: []string{"icecrime"},
+		},
+		{
+			Operations:     []configuration.OperationConfiguration{{Type: "label"}},
+			AllowedSenders: []string{"maintainer"},
+		},
+	}
+	item := test.NewIssueBuilder(test.IssueNumber).Item()
+	if err := r.Execute(makeCommandEvent("/poule label bug:crash"), item); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(ran) != 0 {
+		t.Fatalf("Expected no operation to run, got %v", ran)
+	}
+
+	// Allowed senders can run the command.
+	clt.MockReactions.On("CreateIssueCommentReaction", test.Username, test.Repository, 42, "+1").Return(nil, nil, nil).Once()
+	r.actions[1].AllowedSenders = []string{"IceCrime"}
+	if err := r.Execute(makeCommandEvent("/poule label bug:crash"), item); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(ran, []string{"label"}) {
+		t.Fatalf("Expected label operation to run, got %v", ran)
+	}
+	test.AssertExpectations(clt, t)
+}
@@ -1,11 +1,10 @@
 package server
 
 import (
-	"fmt"
-	"io/ioutil"
-	"net/http"
+	"time"
 
 	"poule/configuration"
+	"poule/gh"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/pkg/errors"
@@ -13,16 +12,11 @@ import (
 	yaml "gopkg.in/yaml.v2"
 )
 
-const (
-	// GitHubRawURLPrefix is the URL prefix for GitHub content retrieval.
-	GitHubRawURLPrefix = "https://raw.githubusercontent.com"
-)
-
 // FetchRepositoriesConfigs retrieves the repository specific configurations from GitHub.
 func (s *Server) FetchRepositoriesConfigs() error {
-	for repository := range s.config.Repositories {
+	for repository := range s.Config().Repositories {
 		if err := s.refreshRepositoryConfiguration(repository); err != nil {
-			logrus.Warnf(err.Error())
+			logrus.Warn(err)
 			continue
 		}
 	}
@@ -30,7 +24,7 @@ func (s *Server) FetchRepositoriesConfigs() error {
 }
 
 func (s *Server) refreshRepositoryConfiguration(repository string) error {
-	repoConfigFile, err := pouleConfigurationFromGitHub(repository)
+	repoConfigFile, err := s.pouleConfigurationFromGitHub(s.Config(), repository)
 	if err != nil {
 		return errors.Wrapf(err, "failed to get configuration for repository %q", repository)
 	}
@@ -40,15 +34,51 @@ func (s *Server) refreshRepositoryConfiguration(repository string) error {
 	return s.updateRepositoryConfiguration(repository, repoConfigFile)
 }
 
+// fetchRepositoriesConfigs retrieves and parses the specific configurations of all repositories
+// of the server configuration, without installing them. Repositories without a configuration file
+// are omitted from the result.
+func (s *Server) fetchRepositoriesConfigs(config *configuration.Server) (map[string]repositoryConfig, error) {
+	repositories := map[string]repositoryConfig{}
+	for repository := range config.Repositories {
+		repoConfigFile, err := s.pouleConfigurationFromGitHub(config, repository)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get configuration for repository %q", repository)
+		}
+		if len(repoConfigFile) == 0 {
+			continue
+		}
+		repoConfig, err := s.makeRepositoryConfiguration(repository, repoConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		repositories[repository] = repoConfig
+	}
+	return repositories, nil
+}
+
 func (s *Server) updateRepositoryConfiguration(repository string, configFile []byte) error {
-	var actions []configuration.Action
-	if err := yaml.Unmarshal([]byte(configFile), &actions); err != nil {
-		return errors.Wrapf(err, "failed to read configuration file for repository %q", repository)
+	repoConfig, err := s.makeRepositoryConfiguration(repository, configFile)
+	if err != nil {
+		return err
 	}
 
-	// Stop any existing cron job for the repository.
-	if c, ok := s.repositoriesConfig[repository]; ok && c.Cron != nil {
-		c.Cron.Stop()
+	// Store the repository specific configuration, which replaces any existing cron job for the
+	// repository.
+	snapshot := s.registry.SetRepository(repository, repoConfig)
+	if snapshot == nil {
+		logrus.Warnf("ignoring configuration for unconfigured repository %q", repository)
+		return nil
+	}
+	logrus.Infof("updated configuration for repository %q (version %d)", repository, snapshot.Version)
+	return nil
+}
+
+// makeRepositoryConfiguration parses the specific configuration file of a repository. Its cron job
+// is only started once installed in the registry.
+func (s *Server) makeRepositoryConfiguration(repository string, configFile []byte) (repositoryConfig, error) {
+	var actions []configuration.Action
+	if err := yaml.Unmarshal([]byte(configFile), &actions); err != nil {
+		return repositoryConfig{}, errors.Wrapf(err, "failed to read configuration file for repository %q", repository)
 	}
 
 	// Initialize a new cron schedule.
@@ -57,7 +87,10 @@ func (s *Server) updateRepositoryConfiguration(repository string, configFile []b
 		if actionConfig.Schedule != "" {
 			logrus.Debugf("registering schedule %q for repository %q", actionConfig.Schedule, repository)
 			repositoryCron.AddFunc(actionConfig.Schedule, func() {
-				if err := executeActionOnAllItems(s.makeExecutionConfig(repository), actionConfig); err != nil {
+				// Scheduled tasks run with the server configuration current at the time.
+				config := s.Config()
+				logRateLimitBudget(config, repository)
+				if err := executeActionOnAllItems(makeExecutionConfig(config, repository), s.state, s.permissions, actionConfig); err != nil {
 					logrus.WithFields(logrus.Fields{
 						"repository": repository,
 					}).Errorf("error executing scheduled task: %v", err)
@@ -65,40 +98,68 @@ func (s *Server) updateRepositoryConfiguration(repository string, configFile []b
 			})
 		}
 	}
-	repositoryCron.Start()
 
-	// Store the repository specific configuration.
-	s.repositoriesConfig[repository] = repositoryConfig{
+	return repositoryConfig{
 		Actions: actions,
 		Cron:    repositoryCron,
-	}
-	logrus.Infof("updated configuration for repository %q", repository)
-	return nil
+	}, nil
 }
 
-func (s *Server) makeExecutionConfig(repository string) *configuration.Config {
+func makeExecutionConfig(config *configuration.Server, repository string) *configuration.Config {
 	return &configuration.Config{
-		RunDelay:   s.config.RunDelay,
-		DryRun:     s.config.DryRun,
-		Token:      s.config.Token,
-		TokenFile:  s.config.TokenFile,
-		Repository: repository,
+		AppID:             config.AppID,
+		AppInstallationID: config.AppInstallationID,
+		AppPrivateKeyFile: config.AppPrivateKeyFile,
+		BaseURL:           config.BaseURL,
+		UploadURL:         config.UploadURL,
+		Concurrency:       config.Concurrency,
+		RunDelay:          config.RunDelay,
+		DisableSearch:     config.DisableSearch,
+		DryRun:            config.DryRun,
+		RateLimitReserve:  config.RateLimitReserve,
+		Token:             config.Token,
+		TokenFile:         config.TokenFile,
+		Repository:        repository,
+		StateFile:         config.StateFile,
 	}
 }
 
-func pouleConfigurationFromGitHub(repository string) ([]byte, error) {
-	// Fetch a repository specific configuration from GitHub.
-	configURL := fmt.Sprintf("%s/%s/master/%s", GitHubRawURLPrefix, repository, configuration.PouleConfigurationFile)
-	resp, err := http.Get(configURL)
+func logRateLimitBudget(config *configuration.Server, repository string) {
+	budget, known := gh.RateLimiterForConfig(makeExecutionConfig(config, repository)).Budget()
+	if !known {
+		return
+	}
+	logrus.WithFields(logrus.Fields{
+		"limit":      budget.Limit,
+		"remaining":  budget.Remaining,
+		"repository": repository,
+		"reserve":    config.RateLimitReserve,
+		"reset":      budget.Reset.Format(time.RFC3339),
+	}).Info("running scheduled task")
+}
+
+func (s *Server) pouleConfigurationFromGitHub(serverConfig *configuration.Server, repository string) ([]byte, error) {
+	// Fetch a repository specific configuration from the default branch through the API, so that
+	// private repositories and GitHub Enterprise instances are supported. Both requests are cached
+	// using ETags, so that unchanged files aren't downloaded again.
+	config := makeExecutionConfig(serverConfig, repository)
+	owner, name := config.SplitRepository()
+	if err := gh.ResolveCredentials(config); err != nil {
+		return nil, err
+	}
+	client := gh.MakeClient(config)
+	branch, err := gh.GetDefaultBranch(client, owner, name)
+	if err != nil {
+		return nil, err
+	}
+	content, err := gh.GetFileContent(client, owner, name, configuration.PouleConfigurationFile, branch)
 	if err != nil {
-		return []byte{}, err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
 	// If the file is not found, this is not an error.
-	if resp.StatusCode == http.StatusNotFound {
+	if content == nil {
 		logrus.Debugf("configuration file missing for repository %q", repository)
-		return []byte{}, nil
 	}
-	return ioutil.ReadAll(resp.Body)
+	return content, nil
 }
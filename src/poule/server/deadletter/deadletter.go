@@ -0,0 +1,137 @@
+package deadletter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Entry describes a GitHub event which failed to be processed.
+type Entry struct {
+	// Event is the GitHub event type (e.g., "pull_request").
+	Event string `json:"event"`
+
+	// Delivery is the unique ID of the GitHub delivery, which may be empty.
+	Delivery string `json:"delivery,omitempty"`
+
+	// Body is the event payload.
+	Body json.RawMessage `json:"body"`
+
+	// Error is the error returned by the last attempt.
+	Error string `json:"error,omitempty"`
+
+	// Attempts is the number of times processing of the event was attempted.
+	Attempts int `json:"attempts,omitempty"`
+
+	// Time is the time of the last attempt.
+	Time time.Time `json:"time"`
+}
+
+// Store persists failed events as individual JSON files in a directory.
+type Store struct {
+	dir string
+}
+
+// Open returns the store persisted in the specified directory, creating it if necessary.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrapf(err, "failed to create dead-letter directory %q", dir)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Add persists the entry, and returns the path of the corresponding file.
+func (s *Store) Add(entry Entry) (string, error) {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+
+	// The file is written under a temporary name and renamed, so that readers never observe a
+	// partially written entry.
+	id := entry.Delivery
+	if id == "" {
+		id = fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	name := fmt.Sprintf("%s-%s-%s.json", entry.Time.UTC().Format("20060102T150405Z"), entry.Event, filepath.Base(id))
+	path := filepath.Join(s.dir, name)
+	tmp, err := ioutil.TempFile(s.dir, ".tmp-")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to write dead-letter entry")
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", errors.Wrap(err, "failed to write dead-letter entry")
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", errors.Wrap(err, "failed to write dead-letter entry")
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return "", errors.Wrap(err, "failed to write dead-letter entry")
+	}
+	return path, nil
+}
+
+// List returns the paths of the JSON files in the directory, sorted by name (and thus by time for
+// dead-letter entries).
+func List(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list directory %q", dir)
+	}
+	paths := []string{}
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasSuffix(info.Name(), ".json") && !strings.HasPrefix(info.Name(), ".") {
+			paths = append(paths, filepath.Join(dir, info.Name()))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Read returns the entry persisted in the specified file. The file may also be a GitHub webhook
+// payload captured as JSON, either raw or as relayed through NSQ (with an "X-GitHub-Event" key), in
+// which case the event type is taken from the payload or otherwise left empty.
+func Read(path string) (*Entry, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %q", path)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, errors.Wrapf(err, "malformed JSON file %q", path)
+	}
+
+	// Dead-letter entries have both an event type and a body.
+	if _, ok := fields["body"]; ok {
+		if _, ok := fields["event"]; ok {
+			var entry Entry
+			if err := json.Unmarshal(b, &entry); err != nil {
+				return nil, errors.Wrapf(err, "malformed dead-letter entry %q", path)
+			}
+			return &entry, nil
+		}
+	}
+
+	// Captured webhook payloads.
+	var m struct {
+		GitHubEvent    string `json:"X-GitHub-Event"`
+		GitHubDelivery string `json:"X-GitHub-Delivery"`
+	}
+	json.Unmarshal(b, &m)
+	return &Entry{
+		Event:    m.GitHubEvent,
+		Delivery: m.GitHubDelivery,
+		Body:     json.RawMessage(b),
+	}, nil
+}
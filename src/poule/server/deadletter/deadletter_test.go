@@ -0,0 +1,79 @@
+package deadletter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "poule-deadletter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := Open(filepath.Join(dir, "failed"))
+	if err != nil {
+		t.Fatalf("Open returned unexpected error %v", err)
+	}
+	now := time.Date(2017, time.July, 1, 12, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Event: "issues", Delivery: "b", Body: []byte(`{"action":"opened"}`), Error: "failure", Attempts: 4, Time: now.Add(time.Minute)},
+		{Event: "pull_request", Delivery: "a", Body: []byte(`{"action":"closed"}`), Error: "failure", Attempts: 4, Time: now},
+	}
+	for _, entry := range entries {
+		if _, err := store.Add(entry); err != nil {
+			t.Fatalf("Add returned unexpected error %v", err)
+		}
+	}
+
+	// Entries are listed by time.
+	paths, err := List(filepath.Join(dir, "failed"))
+	if err != nil {
+		t.Fatalf("List returned unexpected error %v", err)
+	}
+	if len(paths) != 2 {
+		t.Fatalf("Expected 2 entries, got %v", paths)
+	}
+	for i, path := range paths {
+		entry, err := Read(path)
+		if err != nil {
+			t.Fatalf("Read returned unexpected error %v", err)
+		}
+		expected := entries[1-i]
+		if entry.Event != expected.Event || entry.Delivery != expected.Delivery || string(entry.Body) != string(expected.Body) || entry.Attempts != 4 || !entry.Time.Equal(expected.Time) {
+			t.Fatalf("Expected entry %#v, got %#v", expected, entry)
+		}
+	}
+}
+
+func TestReadCapturedPayload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "poule-deadletter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tc := range []struct {
+		payload       string
+		expectedEvent string
+	}{
+		{`{"action": "opened", "issue": {"number": 1}}`, ""},
+		{`{"X-GitHub-Event": "issues", "X-GitHub-Delivery": "1", "action": "opened", "issue": {"number": 1}}`, "issues"},
+	} {
+		path := filepath.Join(dir, "payload.json")
+		if err := ioutil.WriteFile(path, []byte(tc.payload), 0600); err != nil {
+			t.Fatal(err)
+		}
+		entry, err := Read(path)
+		if err != nil {
+			t.Fatalf("Read returned unexpected error %v", err)
+		}
+		if entry.Event != tc.expectedEvent || string(entry.Body) != tc.payload {
+			t.Fatalf("Unexpected entry %#v for payload %s", entry, tc.payload)
+		}
+	}
+}
@@ -6,15 +6,45 @@ import (
 
 	"poule/configuration"
 	"poule/gh"
+	"poule/operations"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
 )
 
 // HandleMessage handles a GitHub event.
 func (s *Server) HandleMessage(event string, body []byte) error {
+	// The event is processed against the configuration current at the time of reception.
+	snapshot := s.registry.Snapshot()
+
+	// Events which don't relate to a repository can't trigger any action.
+	var m struct {
+		Action     string `json:"action"`
+		Repository *struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(body, &m); err != nil {
+		return err
+	}
+	if m.Repository == nil || m.Repository.FullName == "" {
+		return nil
+	}
+
+	// Label events relate to all the items carrying the label: they are only looked up when an
+	// action of the repository explicitly opted in by being triggered by such events.
+	if event == "label" && !isTriggered(snapshot.Actions(m.Repository.FullName), event, m.Action) {
+		return nil
+	}
+
 	// Parse into GitHub items in order to extract the repository information.
-	items, err := makeGitHubItems(&s.config.Config, event, body)
+	config := makeExecutionConfig(snapshot.Server, m.Repository.FullName)
+	if err := gh.ResolveCredentials(config); err != nil {
+		return err
+	}
+	client := gh.MakeClient(config)
+	items, err := makeGitHubItems(client, event, body)
 	switch {
 	case err != nil:
 		return err
@@ -24,65 +54,94 @@ func (s *Server) HandleMessage(event string, body []byte) error {
 
 	// Handle the event for every GitHub item related to this event.
 	for _, item := range items {
-		if err := s.handleMessageForItem(event, body, item); err != nil {
+		if err := s.handleMessageForItem(snapshot, event, body, item); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (s *Server) handleMessageForItem(event string, body []byte, item gh.Item) error {
-	// Unserialize the body in order to extract the action.
-	var m struct {
-		Action string `json:"action"`
-	}
-	if err := json.Unmarshal(body, &m); err != nil {
+func (s *Server) handleMessageForItem(snapshot *snapshot, event string, body []byte, item gh.Item) error {
+	// Unserialize the body in order to extract the action, and to provide the event to operations.
+	evt, err := operations.NewEvent(event, body)
+	if err != nil {
 		return err
 	}
 
 	logrus.WithFields(logrus.Fields{
-		"action":     m.Action,
+		"action":     evt.Action,
 		"event":      event,
 		"number":     item.Number(),
 		"repository": item.Repository(),
+		"version":    snapshot.Version,
 	}).Debugf("received GitHub event")
 
 	// Gather the list of potential actions for that repository.
-	actions := s.config.CommonActions
-	if repoConfig, ok := s.repositoriesConfig[item.Repository()]; ok {
-		actions = append(actions, repoConfig.Actions...)
-	}
+	actions := snapshot.Actions(item.Repository())
 
 	// Go through the configurations that match this (event, action) couple. In the `Triggers` map,
 	// keys are GitHub event types, and values are associated actions.
 outer_loop:
 	for _, actionConfig := range actions {
-		if actionConfig.Triggers.Contains(event, m.Action) {
-			if err := executeAction(s.makeExecutionConfig(item.Repository()), actionConfig, item); err != nil {
+		if actionConfig.Triggers.Contains(event, evt.Action) {
+			config := makeExecutionConfig(snapshot.Server, item.Repository())
+			if actionConfig.IsRestricted() {
+				allowed, err := isSenderAllowed(gh.MakeClient(config), s.permissions, actionConfig, evt, item.Repository())
+				if err != nil {
+					return err
+				}
+				if !allowed {
+					logrus.WithFields(logrus.Fields{
+						"event":      event,
+						"number":     item.Number(),
+						"repository": item.Repository(),
+						"sender":     evt.SenderLogin(),
+					}).Info("ignoring action triggered by unauthorized sender")
+					continue outer_loop
+				}
+			}
+			if err := executeAction(config, s.state, s.permissions, actionConfig, item, evt); err != nil {
 				return err
 			}
 			continue outer_loop
 		}
 	}
-	return nil
+
+	// Comments may also hold commands to run on the item.
+	return s.handleCommands(snapshot, evt, item)
+}
+
+// isTriggered returns whether any of the actions is triggered by the (event, action) couple.
+func isTriggered(actions []configuration.Action, event, action string) bool {
+	for _, actionConfig := range actions {
+		if actionConfig.Triggers.Contains(event, action) {
+			return true
+		}
+	}
+	return false
 }
 
-func makeGitHubItems(c *configuration.Config, event string, data []byte) ([]gh.Item, error) {
+// makeGitHubItems returns the GitHub items an event relates to. Events which don't directly
+// embed an issue or a pull request (such as check suites, pushes, or labels) are mapped to items
+// through the GitHub API.
+func makeGitHubItems(client gh.Client, event string, data []byte) ([]gh.Item, error) {
 	switch event {
 	case "issues", "issue_comment":
-		return makeItemsFromIssueEvent(c, data)
+		return makeItemsFromIssueEvent(data)
 	case "pull_request", "pull_request_review", "pull_request_review_comment":
-		return makeItemsFromPullRequestEvent(c, data)
-	// Handling of the "status" event is temporarily disabled: we don't have a use case for it yet
-	// and it's extremely consuming in terms of API limits.
-	//case "status":
-	//	return makeItemsFromStatusEvent(c, data)
+		return makeItemsFromPullRequestEvent(data)
+	case "check_run", "check_suite":
+		return makeItemsFromCheckEvent(client, event, data)
+	case "label":
+		return makeItemsFromLabelEvent(client, data)
+	case "push":
+		return makeItemsFromPushEvent(client, data)
 	default:
 		return nil, nil
 	}
 }
 
-func makeItemsFromIssueEvent(c *configuration.Config, data []byte) ([]gh.Item, error) {
+func makeItemsFromIssueEvent(data []byte) ([]gh.Item, error) {
 	var evt *github.IssuesEvent
 	if err := json.Unmarshal(data, &evt); err != nil {
 		return []gh.Item{}, err
@@ -95,7 +154,7 @@ func makeItemsFromIssueEvent(c *configuration.Config, data []byte) ([]gh.Item, e
 	return []gh.Item{item}, nil
 }
 
-func makeItemsFromPullRequestEvent(c *configuration.Config, data []byte) ([]gh.Item, error) {
+func makeItemsFromPullRequestEvent(data []byte) ([]gh.Item, error) {
 	var evt *github.PullRequestEvent
 	if err := json.Unmarshal(data, &evt); err != nil {
 		return []gh.Item{}, err
@@ -105,36 +164,153 @@ func makeItemsFromPullRequestEvent(c *configuration.Config, data []byte) ([]gh.I
 	return []gh.Item{item}, nil
 }
 
-func makeItemsFromStatusEvent(c *configuration.Config, data []byte) ([]gh.Item, error) {
-	var evt *github.StatusEvent
+// checkEvent is the subset of the "check_run" and "check_suite" events payload we rely on, as
+// those events are unknown to our version of the GitHub client library.
+type checkEvent struct {
+	CheckRun *struct {
+		HeadSHA string `json:"head_sha"`
+	} `json:"check_run"`
+	CheckSuite *struct {
+		HeadSHA string `json:"head_sha"`
+	} `json:"check_suite"`
+	Repo *github.Repository `json:"repository"`
+}
+
+func makeItemsFromCheckEvent(client gh.Client, event string, data []byte) ([]gh.Item, error) {
+	var evt checkEvent
 	if err := json.Unmarshal(data, &evt); err != nil {
 		return []gh.Item{}, err
 	}
 
-	// Search for all pull request that match this commit SHA. Note that it's perfectly fine for a
-	// single commit to belong to multiple pull requests (example: when a patch was cherry-picked in
-	// multiple places).
-	client := gh.MakeClient(c)
-	result, _, err := client.Search().Issues(*evt.SHA, nil)
-	if err != nil {
+	var sha string
+	switch {
+	case event == "check_run" && evt.CheckRun != nil:
+		sha = evt.CheckRun.HeadSHA
+	case event == "check_suite" && evt.CheckSuite != nil:
+		sha = evt.CheckSuite.HeadSHA
+	}
+	if sha == "" || evt.Repo == nil {
+		return []gh.Item{}, nil
+	}
+	return listPullRequestsForHead(client, evt.Repo, sha)
+}
+
+func makeItemsFromLabelEvent(client gh.Client, data []byte) ([]gh.Item, error) {
+	var evt *github.LabelEvent
+	if err := json.Unmarshal(data, &evt); err != nil {
 		return []gh.Item{}, err
 	}
-	logrus.Debugf("found %d matching items for SHA %s", *result.Total, *evt.SHA)
-
-	// TODO Retrieve the commit list for the pull request, and verify that the SHA is indeed part of
-	// the pull requests commits. This avoids matching pull request that contain the specified SHA
-	// as part of their title or body.
-	pulls := []gh.Item{}
-	for _, issue := range result.Issues {
-		// The issue object has an empty repository information, so we need to extract if from the
-		// issue's HTML URL... <insert crying emoji here>
-		if strings.HasPrefix(*issue.HTMLURL, "https://github.com/"+*evt.Repo.FullName) {
-			pull, _, err := client.PullRequests().Get(*evt.Repo.Owner.Login, *evt.Repo.Name, *issue.Number)
-			if err != nil {
-				return []gh.Item{}, err
+	if evt.Label == nil || evt.Label.Name == nil || evt.Repo == nil || evt.Repo.Owner == nil || evt.Repo.Owner.Login == nil || evt.Repo.Name == nil {
+		return []gh.Item{}, nil
+	}
+
+	// Only edited labels may be carried by items: created labels aren't yet, and deleted ones no
+	// longer are.
+	if evt.Action == nil || *evt.Action != "edited" {
+		return []gh.Item{}, nil
+	}
+
+	// The event concerns the repository label itself: we apply it to all open issues and pull
+	// requests which carry it. Pull requests are listed as issues, and are only retrieved as such
+	// once they pass the global filters of an operation.
+	items := []gh.Item{}
+	options := &github.IssueListByRepoOptions{
+		State:       "open",
+		Labels:      []string{*evt.Label.Name},
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for options.Page = 1; options.Page != 0; {
+		issues, resp, err := client.Issues().ListByRepo(*evt.Repo.Owner.Login, *evt.Repo.Name, options)
+		if err != nil {
+			return []gh.Item{}, errors.Wrapf(err, "failed to list issues labeled %q", *evt.Label.Name)
+		}
+		for _, issue := range issues {
+			issue.Repository = evt.Repo
+			if issue.PullRequestLinks == nil {
+				items = append(items, gh.MakeIssueItem(issue))
+			} else {
+				items = append(items, gh.MakePartialPullRequestItem(issue))
 			}
-			pulls = append(pulls, gh.MakePullRequestItem(pull))
 		}
+		if resp == nil {
+			break
+		}
+		options.Page = resp.NextPage
+	}
+	logrus.Debugf("found %d matching items for label %q", len(items), *evt.Label.Name)
+	return items, nil
+}
+
+func makeItemsFromPushEvent(client gh.Client, data []byte) ([]gh.Item, error) {
+	var evt *github.PushEvent
+	if err := json.Unmarshal(data, &evt); err != nil {
+		return []gh.Item{}, err
+	}
+
+	// Only pushes to branches can update the base of pull requests, and deleting a branch closes
+	// the pull requests based on it.
+	if evt.Ref == nil || !strings.HasPrefix(*evt.Ref, "refs/heads/") || evt.Repo == nil || (evt.Deleted != nil && *evt.Deleted) {
+		return []gh.Item{}, nil
+	}
+	branch := strings.TrimPrefix(*evt.Ref, "refs/heads/")
+
+	// The repository object of push events has a different layout than other events, so we rely
+	// on its full name.
+	if evt.Repo.FullName == nil || !strings.Contains(*evt.Repo.FullName, "/") {
+		return []gh.Item{}, nil
+	}
+	repo := strings.SplitN(*evt.Repo.FullName, "/", 2)
+
+	items := []gh.Item{}
+	options := &github.PullRequestListOptions{
+		State:       "open",
+		Base:        branch,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for options.Page = 1; options.Page != 0; {
+		pulls, resp, err := client.PullRequests().List(repo[0], repo[1], options)
+		if err != nil {
+			return []gh.Item{}, errors.Wrapf(err, "failed to list pull requests based on %q", branch)
+		}
+		for _, pull := range pulls {
+			items = append(items, gh.MakePullRequestItem(pull))
+		}
+		if resp == nil {
+			break
+		}
+		options.Page = resp.NextPage
+	}
+	logrus.Debugf("found %d matching items for base branch %q", len(items), branch)
+	return items, nil
+}
+
+// listPullRequestsForHead returns the open pull requests of the repository whose head is the
+// specified commit. Note that it's perfectly fine for a single commit to belong to multiple pull
+// requests (example: when a patch was cherry-picked in multiple places).
+func listPullRequestsForHead(client gh.Client, repo *github.Repository, sha string) ([]gh.Item, error) {
+	items := []gh.Item{}
+	if repo == nil || repo.Owner == nil || repo.Owner.Login == nil || repo.Name == nil {
+		return items, nil
+	}
+	options := &github.ListOptions{PerPage: 100}
+	for options.Page = 1; options.Page != 0; {
+		pulls, resp, err := client.Repositories().ListPullRequestsWithCommit(*repo.Owner.Login, *repo.Name, sha, options)
+		if err != nil {
+			return []gh.Item{}, errors.Wrapf(err, "failed to list pull requests for commit %s", sha)
+		}
+		for _, pull := range pulls {
+			// The commit may belong to a pull request without being its head, in which case the
+			// event doesn't reflect the state of the pull request.
+			if pull.State == nil || *pull.State != "open" || pull.Head == nil || pull.Head.SHA == nil || *pull.Head.SHA != sha {
+				continue
+			}
+			items = append(items, gh.MakePullRequestItem(pull))
+		}
+		if resp == nil {
+			break
+		}
+		options.Page = resp.NextPage
 	}
-	return pulls, nil
+	logrus.Debugf("found %d matching items for SHA %s", len(items), sha)
+	return items, nil
 }
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"testing"
+
+	"poule/gh"
+	"poule/test"
+
+	"github.com/google/go-github/github"
+	"github.com/stretchr/testify/mock"
+)
+
+func itemNumbers(items []gh.Item) []int {
+	numbers := []int{}
+	for _, item := range items {
+		numbers = append(numbers, item.Number())
+	}
+	return numbers
+}
+
+func assertItemNumbers(t *testing.T, items []gh.Item, expected ...int) {
+	numbers := itemNumbers(items)
+	if len(numbers) != len(expected) {
+		t.Fatalf("Expected items %v, got %v", expected, numbers)
+	}
+	for i := range expected {
+		if numbers[i] != expected[i] {
+			t.Fatalf("Expected items %v, got %v", expected, numbers)
+		}
+	}
+}
+
+func TestCheckSuiteEventItems(t *testing.T) {
+	clt := &test.Client{}
+	clt.MockRepositories.On("ListPullRequestsWithCommit", "icecrime", "poule", "abc", mock.Anything).Return([]*github.PullRequest{
+		test.NewPullRequestBuilder(1).State("open").HeadBranch("icecrime", "poule", "a", "abc").Value,
+		test.NewPullRequestBuilder(2).State("closed").HeadBranch("icecrime", "poule", "b", "abc").Value,
+		test.NewPullRequestBuilder(3).State("open").HeadBranch("icecrime", "poule", "c", "def").Value,
+	}, &github.Response{}, nil)
+
+	for _, event := range []string{"check_suite", "check_run"} {
+		body := []byte(`{"action": "completed", "` + event + `": {"head_sha": "abc"}, "repository": {"name": "poule", "full_name": "icecrime/poule", "owner": {"login": "icecrime"}}}`)
+		items, err := makeGitHubItems(clt, event, body)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		assertItemNumbers(t, items, 1)
+	}
+	test.AssertExpectations(clt, t)
+}
+
+func TestPushEventItems(t *testing.T) {
+	clt := &test.Client{}
+	clt.MockPullRequests.On("List", "icecrime", "poule", &github.PullRequestListOptions{
+		State:       "open",
+		Base:        "master",
+		ListOptions: github.ListOptions{Page: 1, PerPage: 100},
+	}).Return([]*github.PullRequest{
+		test.NewPullRequestBuilder(1).Value,
+		test.NewPullRequestBuilder(2).Value,
+	}, &github.Response{}, nil)
+
+	body := []byte(`{"ref": "refs/heads/master", "repository": {"name": "poule", "full_name": "icecrime/poule", "owner": {"name": "icecrime"}}}`)
+	items, err := makeGitHubItems(clt, "push", body)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	assertItemNumbers(t, items, 1, 2)
+
+	// Pushes to tags and branch deletions don't relate to any item.
+	for _, body := range []string{
+		`{"ref": "refs/tags/v1.0", "repository": {"name": "poule", "full_name": "icecrime/poule"}}`,
+		`{"ref": "refs/heads/master", "deleted": true, "repository": {"name": "poule", "full_name": "icecrime/poule"}}`,
+	} {
+		if items, err := makeGitHubItems(clt, "push", []byte(body)); err != nil || len(items) != 0 {
+			t.Fatalf("Expected no items, got %v (error %v)", itemNumbers(items), err)
+		}
+	}
+	test.AssertExpectations(clt, t)
+}
+
+func TestLabelEventItems(t *testing.T) {
+	clt := &test.Client{}
+	clt.MockIssues.On("ListByRepo", "icecrime", "poule", &github.IssueListByRepoOptions{
+		State:       "open",
+		Labels:      []string{"bug"},
+		ListOptions: github.ListOptions{Page: 1, PerPage: 100},
+	}).Return([]*github.Issue{
+		test.NewIssueBuilder(3).Value,
+		{Number: github.Int(4), PullRequestLinks: &github.PullRequestLinks{}},
+	}, &github.Response{}, nil).Once()
+
+	// Pull requests carrying the label are returned as partial items, and only retrieved once they
+	// pass the global filters of an operation.
+	body := []byte(`{"action": "edited", "label": {"name": "bug"}, "repository": {"name": "poule", "full_name": "icecrime/poule", "owner": {"login": "icecrime"}}}`)
+	items, err := makeGitHubItems(clt, "label", body)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	assertItemNumbers(t, items, 3, 4)
+	if repository := items[0].Repository(); repository != "icecrime/poule" {
+		t.Fatalf("Expected item of repository %q, got %q", "icecrime/poule", repository)
+	}
+	if !items[0].IsIssue() || !items[1].IsPullRequest() || !items[1].IsPartial() {
+		t.Fatalf("Expected an issue and a partial pull request")
+	}
+	if repository := items[1].Repository(); repository != "icecrime/poule" {
+		t.Fatalf("Expected item of repository %q, got %q", "icecrime/poule", repository)
+	}
+
+	// Created and deleted labels aren't carried by any item.
+	for _, action := range []string{"created", "deleted"} {
+		body = []byte(`{"action": "` + action + `", "label": {"name": "bug"}, "repository": {"name": "poule", "full_name": "icecrime/poule", "owner": {"login": "icecrime"}}}`)
+		if items, err := makeGitHubItems(clt, "label", body); err != nil || len(items) != 0 {
+			t.Fatalf("Expected no items for a %s label, got %v (error %v)", action, itemNumbers(items), err)
+		}
+	}
+
+	// Repositories without an owner login don't relate to any item.
+	body = []byte(`{"action": "edited", "label": {"name": "bug"}, "repository": {"name": "poule", "full_name": "icecrime/poule", "owner": {}}}`)
+	if items, err := makeGitHubItems(clt, "label", body); err != nil || len(items) != 0 {
+		t.Fatalf("Expected no items, got %v (error %v)", itemNumbers(items), err)
+	}
+	test.AssertExpectations(clt, t)
+}
+
+func TestPullRequestReviewEventItems(t *testing.T) {
+	clt := &test.Client{}
+	body := []byte(`{"action": "submitted", "review": {"state": "approved"}, "pull_request": {"number": 4}, "repository": {"name": "poule", "full_name": "icecrime/poule"}}`)
+	items, err := makeGitHubItems(clt, "pull_request_review", body)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	assertItemNumbers(t, items, 4)
+	test.AssertExpectations(clt, t)
+}
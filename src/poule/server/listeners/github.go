@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"sync"
 
 	"poule/configuration"
 
@@ -16,6 +17,7 @@ import (
 
 // GitHubListener listens for GitHub events directly from webhooks.
 type GitHubListener struct {
+	sync.RWMutex
 	config *configuration.Server
 }
 
@@ -26,23 +28,62 @@ func NewGitHubListener(config *configuration.Server) *GitHubListener {
 	}
 }
 
-// Start starts an HTTP server to receive GitHub WebHooks.
+// Start starts an HTTP server to receive GitHub WebHooks. Events are acknowledged as soon as they
+// are queued, and processed asynchronously.
 func (l *GitHubListener) Start(handler Handler) error {
+	config := l.currentConfig()
+	deadLetters, err := openDeadLetters(config)
+	if err != nil {
+		return err
+	}
+	queue := NewWorkQueue(handler, WorkQueueOptions{
+		Workers:     config.HTTPWorkers,
+		Size:        config.HTTPQueueSize,
+		Retries:     config.HTTPRetries,
+		DeadLetters: deadLetters,
+	})
+	defer queue.Close()
+
 	r := mux.NewRouter()
-	r.Handle("/{user:.*}/{name:.*}", newWebHookHandler(handler, l.config.HTTPSecret)).Methods("POST")
-	logrus.Infof("listening on %q", l.config.HTTPListen)
-	return http.ListenAndServe(l.config.HTTPListen, r)
+	r.Handle("/{user:.*}/{name:.*}", newWebHookHandler(queue, l.secret)).Methods("POST")
+	logrus.Infof("listening on %q", config.HTTPListen)
+	return http.ListenAndServe(config.HTTPListen, r)
+}
+
+// Reload applies the new server configuration. Changes to the listening address and to the
+// processing of events require a restart.
+func (l *GitHubListener) Reload(config *configuration.Server) error {
+	l.Lock()
+	defer l.Unlock()
+	if config.HTTPListen != l.config.HTTPListen {
+		logrus.Warnf("listening address change to %q requires a restart", config.HTTPListen)
+	}
+	if config.HTTPWorkers != l.config.HTTPWorkers || config.HTTPQueueSize != l.config.HTTPQueueSize || config.HTTPRetries != l.config.HTTPRetries {
+		logrus.Warn("changes to http_workers, http_queue_size, and http_retries require a restart")
+	}
+	l.config = config
+	return nil
+}
+
+func (l *GitHubListener) currentConfig() *configuration.Server {
+	l.RLock()
+	defer l.RUnlock()
+	return l.config
+}
+
+func (l *GitHubListener) secret() string {
+	return l.currentConfig().HTTPSecret
 }
 
 type webHookHandler struct {
-	handler Handler
-	secret  string
+	queue  *WorkQueue
+	secret func() string
 }
 
-func newWebHookHandler(handler Handler, secret string) *webHookHandler {
+func newWebHookHandler(queue *WorkQueue, secret func() string) *webHookHandler {
 	return &webHookHandler{
-		handler: handler,
-		secret:  secret,
+		queue:  queue,
+		secret: secret,
 	}
 }
 
@@ -56,15 +97,22 @@ func (h *webHookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !validateSignature(r, h.secret, data) {
+	if !validateSignature(r, h.secret(), data) {
 		logrus.Warn("signature verification failed")
 		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
 		return
 	}
 
-	if err := h.handler.HandleMessage(r.Header.Get("X-Github-Event"), data); err != nil {
-		logrus.WithField("error", err).Error("processing event")
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	event := NewEvent(r.Header.Get("X-Github-Event"), r.Header.Get("X-Github-Delivery"), data)
+	switch err := h.queue.Enqueue(event); err {
+	case nil:
+		w.WriteHeader(http.StatusAccepted)
+	case ErrDuplicateDelivery:
+		logrus.WithField("delivery", event.Delivery).Info("ignoring duplicate delivery")
+		w.WriteHeader(http.StatusOK)
+	default:
+		logrus.WithField("error", err).Error("queueing event")
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
 	}
 }
 
@@ -1,5 +1,10 @@
 package listeners
 
+import (
+	"poule/configuration"
+	"poule/server/deadletter"
+)
+
 // Handler handles GitHub events.
 type Handler interface {
 	// HandleMessage handles a GitHub event.
@@ -11,3 +16,17 @@ type Listener interface {
 	// Start starts listening for GitHub events, calling the Handler for each event received.
 	Start(handler Handler) error
 }
+
+// Reloader is implemented by listeners which support changes of the server configuration.
+type Reloader interface {
+	// Reload applies the new server configuration.
+	Reload(config *configuration.Server) error
+}
+
+// openDeadLetters returns the dead-letter store of the configuration, or nil if none is configured.
+func openDeadLetters(config *configuration.Server) (*deadletter.Store, error) {
+	if config.DeadLetterDir == "" {
+		return nil, nil
+	}
+	return deadletter.Open(config.DeadLetterDir)
+}
@@ -5,62 +5,133 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 
 	"poule/configuration"
+	"poule/server/deadletter"
 
 	"github.com/Sirupsen/logrus"
 	nsq "github.com/bitly/go-nsq"
+	"github.com/pkg/errors"
 )
 
 // NSQListener listens for GitHub events from an NSQ message queue.
 type NSQListener struct {
-	config *configuration.Server
+	sync.Mutex
+	config  *configuration.Server
+	handler nsq.Handler
+	queues  map[string]*Queue
 }
 
 // NewNSQListener returns a new NSQListener instance.
 func NewNSQListener(config *configuration.Server) *NSQListener {
 	return &NSQListener{
 		config: config,
+		queues: make(map[string]*Queue),
 	}
 }
 
-// Start starts an HTTP server to receive GitHub WebHooks.
+// Start starts monitoring the queues of all configured repositories, and only returns once stopped
+// by SIGTERM or SIGINT.
 func (l *NSQListener) Start(handler Handler) error {
 	// Create and start monitoring queues.
-	queues := createQueues(l.config, newNSQHandler(handler))
-	stopChan := monitorQueues(queues)
+	deadLetters, err := openDeadLetters(l.config)
+	if err != nil {
+		return err
+	}
+
+	l.Lock()
+	l.handler = newNSQHandler(handler, l.config.NSQMaxAttempts, deadLetters)
+	for _, topic := range l.config.Repositories {
+		queue, err := NewQueue(topic, l.config.Channel, l.config.LookupdAddr, l.handler)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		l.queues[topic] = queue
+	}
+	l.Unlock()
 
 	// Graceful stop on SIGTERM and SIGINT.
 	sigChan := make(chan os.Signal, 64)
 	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
+	sig := <-sigChan
+	logrus.WithField("signal", sig).Debug("received signal")
+
+	l.Lock()
+	queues := make([]*Queue, 0, len(l.queues))
+	for topic, q := range l.queues {
+		q.Consumer.Stop()
+		queues = append(queues, q)
+		delete(l.queues, topic)
+	}
+	l.Unlock()
+	<-monitorQueues(queues)
+	logrus.Debug("All queues exited")
+	return nil
+}
+
+// Reload applies the new server configuration: queues are created for new repositories, and stopped
+// for removed ones. All queues are recreated when the NSQ channel or lookupd address changes.
+func (l *NSQListener) Reload(config *configuration.Server) error {
+	l.Lock()
+	defer l.Unlock()
+	recreate := config.Channel != l.config.Channel || config.LookupdAddr != l.config.LookupdAddr
+	l.config = config
+	if l.handler == nil {
+		// Queues are created when the listener starts.
+		return nil
+	}
+
+	topics := map[string]bool{}
+	for _, topic := range config.Repositories {
+		topics[topic] = true
+	}
+	for topic, q := range l.queues {
+		if recreate || !topics[topic] {
+			logrus.Infof("stopping queue for topic %q", topic)
+			q.Consumer.Stop()
+			delete(l.queues, topic)
+		}
+	}
 
-	for {
-		select {
-		case _, ok := <-stopChan:
-			if !ok {
-				return nil
-			}
-			logrus.Debug("All queues exited")
-			break
-		case sig := <-sigChan:
-			logrus.WithField("signal", sig).Debug("received signal")
-			for _, q := range queues {
-				q.Consumer.Stop()
-			}
-			break
+	var errs []string
+	for topic := range topics {
+		if _, ok := l.queues[topic]; ok {
+			continue
+		}
+		logrus.Infof("starting queue for topic %q", topic)
+		queue, err := NewQueue(topic, config.Channel, config.LookupdAddr, l.handler)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
 		}
+		l.queues[topic] = queue
+	}
+	if len(errs) != 0 {
+		return errors.Errorf("failed to create queues: %s", strings.Join(errs, ", "))
 	}
+	return nil
 }
 
+// defaultNSQMaxAttempts is the default number of times processing of an NSQ message is attempted.
+const defaultNSQMaxAttempts = 5
+
 type nsqHandler struct {
-	handler Handler
+	handler     Handler
+	maxAttempts uint16
+	deadLetters *deadletter.Store
 }
 
-func newNSQHandler(handler Handler) *nsqHandler {
+func newNSQHandler(handler Handler, maxAttempts int, deadLetters *deadletter.Store) *nsqHandler {
+	if maxAttempts <= 0 {
+		maxAttempts = defaultNSQMaxAttempts
+	}
 	return &nsqHandler{
-		handler: handler,
+		handler:     handler,
+		maxAttempts: uint16(maxAttempts),
+		deadLetters: deadLetters,
 	}
 }
 
@@ -71,7 +142,21 @@ func (h *nsqHandler) HandleMessage(message *nsq.Message) error {
 	if err := json.Unmarshal(message.Body, &m); err != nil {
 		return err
 	}
-	return h.handler.HandleMessage(m.GitHubEvent, message.Body)
+	err := h.handler.HandleMessage(m.GitHubEvent, message.Body)
+	if err == nil || message.Attempts < h.maxAttempts {
+		// NSQ requeues the message on failure.
+		return err
+	}
+
+	// Give up on the message, which is finished rather than requeued forever.
+	logrus.WithFields(logrus.Fields{
+		"attempt":  message.Attempts,
+		"delivery": m.GitHubDelivery,
+		"error":    err,
+		"event":    m.GitHubEvent,
+	}).Error("processing event failed")
+	storeDeadLetter(h.deadLetters, NewEvent(m.GitHubEvent, m.GitHubDelivery, message.Body), err, int(message.Attempts))
+	return nil
 }
 
 type partialMessage struct {
@@ -103,19 +188,6 @@ func NewQueue(topic, channel, lookupd string, handler nsq.Handler) (*Queue, erro
 	return &Queue{Consumer: consumer}, nil
 }
 
-func createQueues(c *configuration.Server, handler nsq.Handler) []*Queue {
-	// Subscribe to the message queues for each repository.
-	queues := make([]*Queue, 0, len(c.Repositories))
-	for _, topic := range c.Repositories {
-		queue, err := NewQueue(topic, c.Channel, c.LookupdAddr, handler)
-		if err != nil {
-			logrus.Fatal(err)
-		}
-		queues = append(queues, queue)
-	}
-	return queues
-}
-
 func monitorQueues(queues []*Queue) <-chan struct{} {
 	// Start one goroutine per queue and monitor the StopChan event.
 	wg := sync.WaitGroup{}
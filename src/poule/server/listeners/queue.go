@@ -0,0 +1,278 @@
+package listeners
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"poule/server/deadletter"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultWorkers is the default number of events processed concurrently.
+	defaultWorkers = 4
+
+	// defaultQueueSize is the default maximum number of events waiting to be processed.
+	defaultQueueSize = 1000
+
+	// retryBackoff is the delay before the first retry, which doubles for each subsequent one.
+	retryBackoff = time.Second
+
+	// deliveryHistorySize is the number of delivery IDs remembered for deduplication.
+	deliveryHistorySize = 4096
+)
+
+var (
+	// ErrQueueFull is returned when enqueuing an event to a full queue.
+	ErrQueueFull = errors.New("work queue is full")
+
+	// ErrDuplicateDelivery is returned when enqueuing an event which was already received.
+	ErrDuplicateDelivery = errors.New("duplicate delivery")
+
+	// ErrQueueClosed is returned when enqueuing an event to a closed queue.
+	ErrQueueClosed = errors.New("work queue is closed")
+)
+
+// Event is a GitHub event waiting to be processed.
+type Event struct {
+	// Type is the GitHub event type (e.g., "pull_request").
+	Type string
+
+	// Delivery is the unique ID of the GitHub delivery, which may be empty.
+	Delivery string
+
+	// Repository is the full name of the repository the event relates to, which may be empty.
+	Repository string
+
+	// Body is the event payload.
+	Body []byte
+}
+
+// NewEvent returns an event for the specified payload, extracting the repository from the body.
+func NewEvent(eventType, delivery string, body []byte) Event {
+	var m struct {
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+	}
+	json.Unmarshal(body, &m)
+	return Event{
+		Type:       eventType,
+		Delivery:   delivery,
+		Repository: m.Repository.FullName,
+		Body:       body,
+	}
+}
+
+// WorkQueueOptions configures a WorkQueue. Zero values are replaced by defaults, except for
+// Retries where zero disables retries.
+type WorkQueueOptions struct {
+	// Workers is the number of events processed concurrently.
+	Workers int
+
+	// Size is the maximum number of events waiting to be processed.
+	Size int
+
+	// Retries is the number of times the processing of an event is retried on failure. Retrying
+	// runs all operations for the event again, including the ones which were already applied, so
+	// it should only be enabled when these operations can safely be applied twice.
+	Retries int
+
+	// DeadLetters persists the events which failed to be processed when not nil.
+	DeadLetters *deadletter.Store
+}
+
+// WorkQueue processes events asynchronously using a fixed number of workers. Events of a given
+// repository are processed in order of reception, one at a time, while events of different
+// repositories are processed concurrently.
+type WorkQueue struct {
+	handler Handler
+	options WorkQueueOptions
+
+	// sleep waits between retries, overridable for testing purposes.
+	sleep func(time.Duration)
+
+	// ready receives the repositories which have pending events and which aren't being processed.
+	ready   chan string
+	pending sync.WaitGroup
+	workers sync.WaitGroup
+
+	lock       sync.Mutex
+	closed     bool
+	count      int
+	queues     map[string][]Event
+	deliveries map[string]bool
+	history    []string
+}
+
+// NewWorkQueue returns a new WorkQueue calling the handler for each event. Workers are started
+// immediately.
+func NewWorkQueue(handler Handler, options WorkQueueOptions) *WorkQueue {
+	if options.Workers <= 0 {
+		options.Workers = defaultWorkers
+	}
+	if options.Size <= 0 {
+		options.Size = defaultQueueSize
+	}
+	q := &WorkQueue{
+		handler:    handler,
+		options:    options,
+		sleep:      time.Sleep,
+		ready:      make(chan string, options.Size),
+		queues:     make(map[string][]Event),
+		deliveries: make(map[string]bool),
+	}
+	for i := 0; i < options.Workers; i++ {
+		q.workers.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+// Enqueue adds an event to the queue. It fails when the queue is full, when the event is pending or
+// was successfully processed already, or when the queue is closed.
+func (q *WorkQueue) Enqueue(event Event) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	switch {
+	case q.closed:
+		return ErrQueueClosed
+	case event.Delivery != "" && q.deliveries[event.Delivery]:
+		return ErrDuplicateDelivery
+	case q.count >= q.options.Size:
+		return ErrQueueFull
+	}
+	q.remember(event.Delivery)
+
+	// The repository is scheduled unless it already has pending events, in which case it is either
+	// scheduled or being processed. The number of scheduled repositories never exceeds the number
+	// of pending events, which guarantees that sending to the channel doesn't block.
+	q.count++
+	q.pending.Add(1)
+	q.queues[event.Repository] = append(q.queues[event.Repository], event)
+	if len(q.queues[event.Repository]) == 1 {
+		q.ready <- event.Repository
+	}
+	return nil
+}
+
+// Close stops accepting events, and waits for pending events to be processed.
+func (q *WorkQueue) Close() {
+	q.lock.Lock()
+	if q.closed {
+		q.lock.Unlock()
+		return
+	}
+	q.closed = true
+	q.lock.Unlock()
+
+	q.pending.Wait()
+	close(q.ready)
+	q.workers.Wait()
+}
+
+// remember records the delivery ID, forgetting the oldest one once the history is full. Deliveries
+// are remembered from reception so that they are ignored while pending, and forgotten when their
+// processing fails so that they can be redelivered.
+func (q *WorkQueue) remember(delivery string) {
+	if delivery == "" {
+		return
+	}
+	if len(q.history) >= deliveryHistorySize {
+		delete(q.deliveries, q.history[0])
+		q.history = q.history[1:]
+	}
+	q.deliveries[delivery] = true
+	q.history = append(q.history, delivery)
+}
+
+// forget removes the delivery ID from the history.
+func (q *WorkQueue) forget(delivery string) {
+	if delivery == "" || !q.deliveries[delivery] {
+		return
+	}
+	delete(q.deliveries, delivery)
+	for i, d := range q.history {
+		if d == delivery {
+			q.history = append(q.history[:i], q.history[i+1:]...)
+			break
+		}
+	}
+}
+
+func (q *WorkQueue) work() {
+	defer q.workers.Done()
+	for repository := range q.ready {
+		// The event stays at the head of the repository queue while being processed, so that
+		// subsequent events of the repository don't schedule it again.
+		q.lock.Lock()
+		event := q.queues[repository][0]
+		q.lock.Unlock()
+
+		err := q.process(event)
+
+		q.lock.Lock()
+		if err != nil {
+			q.forget(event.Delivery)
+		}
+		q.count--
+		if remaining := q.queues[repository][1:]; len(remaining) == 0 {
+			delete(q.queues, repository)
+		} else {
+			q.queues[repository] = remaining
+			q.ready <- repository
+		}
+		q.lock.Unlock()
+		q.pending.Done()
+	}
+}
+
+// process calls the handler for the event, retrying with an exponential backoff on failure, and
+// returns the error of the last attempt.
+func (q *WorkQueue) process(event Event) error {
+	backoff := retryBackoff
+	for attempt := 1; ; attempt++ {
+		err := q.handler.HandleMessage(event.Type, event.Body)
+		if err == nil {
+			return nil
+		}
+		fields := logrus.Fields{
+			"attempt":    attempt,
+			"delivery":   event.Delivery,
+			"error":      err,
+			"event":      event.Type,
+			"repository": event.Repository,
+		}
+		if attempt > q.options.Retries {
+			logrus.WithFields(fields).Error("processing event failed")
+			storeDeadLetter(q.options.DeadLetters, event, err, attempt)
+			return err
+		}
+		logrus.WithFields(fields).Warnf("processing event failed: retrying in %s", backoff)
+		q.sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// storeDeadLetter persists an event which failed to be processed to the store, if any.
+func storeDeadLetter(store *deadletter.Store, event Event, err error, attempts int) {
+	if store == nil {
+		return
+	}
+	path, storeErr := store.Add(deadletter.Entry{
+		Event:    event.Type,
+		Delivery: event.Delivery,
+		Body:     event.Body,
+		Error:    err.Error(),
+		Attempts: attempts,
+		Time:     time.Now(),
+	})
+	if storeErr != nil {
+		logrus.WithField("delivery", event.Delivery).Errorf("failed to store dead-letter event: %v", storeErr)
+		return
+	}
+	logrus.WithField("delivery", event.Delivery).Infof("stored failed event to %q", path)
+}
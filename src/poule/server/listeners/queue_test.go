@@ -0,0 +1,209 @@
+package listeners
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"poule/server/deadletter"
+
+	"github.com/pkg/errors"
+)
+
+// recordingHandler records the events it handles, and fails the first attempts of the events
+// listed in failures.
+type recordingHandler struct {
+	sync.Mutex
+	failures map[string]int
+	handled  map[string][]string
+	block    chan struct{}
+}
+
+func newRecordingHandler() *recordingHandler {
+	return &recordingHandler{
+		failures: map[string]int{},
+		handled:  map[string][]string{},
+	}
+}
+
+func (h *recordingHandler) HandleMessage(event string, body []byte) error {
+	if h.block != nil {
+		<-h.block
+	}
+	e := NewEvent(event, "", body)
+	h.Lock()
+	defer h.Unlock()
+	h.handled[e.Repository] = append(h.handled[e.Repository], string(body))
+	if h.failures[string(body)] > 0 {
+		h.failures[string(body)]--
+		return errors.New("failure")
+	}
+	return nil
+}
+
+func makeEventBody(repository string, i int) []byte {
+	return []byte(fmt.Sprintf(`{"repository": {"full_name": %q}, "number": %d}`, repository, i))
+}
+
+func TestWorkQueueOrdering(t *testing.T) {
+	h := newRecordingHandler()
+	q := NewWorkQueue(h, WorkQueueOptions{Workers: 4, Size: 100})
+
+	expected := map[string][]string{}
+	for i := 0; i < 20; i++ {
+		for _, repository := range []string{"icecrime/poule", "moby/moby", "docker/docker"} {
+			body := makeEventBody(repository, i)
+			expected[repository] = append(expected[repository], string(body))
+			if err := q.Enqueue(NewEvent("issues", fmt.Sprintf("%s-%d", repository, i), body)); err != nil {
+				t.Fatalf("Enqueue returned unexpected error %v", err)
+			}
+		}
+	}
+	q.Close()
+
+	// Events of each repository are processed in order.
+	if !reflect.DeepEqual(h.handled, expected) {
+		t.Fatalf("Expected handled events %v, got %v", expected, h.handled)
+	}
+	if err := q.Enqueue(NewEvent("issues", "", nil)); err != ErrQueueClosed {
+		t.Fatalf("Expected error %v after close, got %v", ErrQueueClosed, err)
+	}
+}
+
+func TestWorkQueueRetries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "poule-queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	deadLetters, err := deadletter.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := newRecordingHandler()
+	q := NewWorkQueue(h, WorkQueueOptions{Workers: 1, Retries: 2, DeadLetters: deadLetters})
+	var delays []time.Duration
+	q.sleep = func(d time.Duration) { delays = append(delays, d) }
+
+	// The first event succeeds on the last retry, while the second one always fails: processing
+	// of the repository carries on in order.
+	first, second, third := makeEventBody("moby/moby", 1), makeEventBody("moby/moby", 2), makeEventBody("moby/moby", 3)
+	h.failures[string(first)] = 2
+	h.failures[string(second)] = 10
+	for _, body := range [][]byte{first, second, third} {
+		if err := q.Enqueue(NewEvent("issues", "", body)); err != nil {
+			t.Fatalf("Enqueue returned unexpected error %v", err)
+		}
+	}
+	q.Close()
+
+	expected := []string{string(first), string(first), string(first), string(second), string(second), string(second), string(third)}
+	if !reflect.DeepEqual(h.handled["moby/moby"], expected) {
+		t.Fatalf("Expected handled events %v, got %v", expected, h.handled["moby/moby"])
+	}
+	expectedDelays := []time.Duration{retryBackoff, 2 * retryBackoff, retryBackoff, 2 * retryBackoff}
+	if !reflect.DeepEqual(delays, expectedDelays) {
+		t.Fatalf("Expected delays %v, got %v", expectedDelays, delays)
+	}
+
+	// The event which failed every attempt is persisted.
+	paths, err := deadletter.List(dir)
+	if err != nil || len(paths) != 1 {
+		t.Fatalf("Expected a single dead-letter entry, got %v (error %v)", paths, err)
+	}
+	entry, err := deadletter.Read(paths[0])
+	if err != nil {
+		t.Fatalf("Read returned unexpected error %v", err)
+	}
+	var body bytes.Buffer
+	json.Compact(&body, second)
+	if entry.Event != "issues" || string(entry.Body) != body.String() || entry.Attempts != 3 {
+		t.Fatalf("Unexpected dead-letter entry %#v", entry)
+	}
+}
+
+func TestWorkQueueDeliveries(t *testing.T) {
+	h := newRecordingHandler()
+	h.block = make(chan struct{})
+	q := NewWorkQueue(h, WorkQueueOptions{Workers: 1})
+
+	// Deliveries are ignored while pending and once processed, but failed ones can be redelivered.
+	succeeded, failed := makeEventBody("moby/moby", 1), makeEventBody("moby/moby", 2)
+	h.failures[string(failed)] = 1
+	for _, event := range []Event{NewEvent("issues", "succeeded", succeeded), NewEvent("issues", "failed", failed)} {
+		if err := q.Enqueue(event); err != nil {
+			t.Fatalf("Enqueue returned unexpected error %v", err)
+		}
+	}
+	if err := q.Enqueue(NewEvent("issues", "succeeded", succeeded)); err != ErrDuplicateDelivery {
+		t.Fatalf("Expected error %v for a pending delivery, got %v", ErrDuplicateDelivery, err)
+	}
+	close(h.block)
+	q.pending.Wait()
+
+	if err := q.Enqueue(NewEvent("issues", "succeeded", succeeded)); err != ErrDuplicateDelivery {
+		t.Fatalf("Expected error %v for a processed delivery, got %v", ErrDuplicateDelivery, err)
+	}
+	if err := q.Enqueue(NewEvent("issues", "failed", failed)); err != nil {
+		t.Fatalf("Expected failed delivery to be accepted again, got %v", err)
+	}
+	q.Close()
+
+	expected := []string{string(succeeded), string(failed), string(failed)}
+	if !reflect.DeepEqual(h.handled["moby/moby"], expected) {
+		t.Fatalf("Expected handled events %v, got %v", expected, h.handled["moby/moby"])
+	}
+}
+
+func TestWebHookHandler(t *testing.T) {
+	h := newRecordingHandler()
+	h.block = make(chan struct{})
+	q := NewWorkQueue(h, WorkQueueOptions{Workers: 1, Size: 2})
+	server := httptest.NewServer(newWebHookHandler(q, func() string { return "" }))
+	defer server.Close()
+
+	post := func(delivery string) int {
+		req, err := http.NewRequest("POST", server.URL, bytes.NewReader(makeEventBody("moby/moby", 1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("X-GitHub-Event", "issues")
+		req.Header.Set("X-GitHub-Delivery", delivery)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Events are acknowledged before being processed, duplicates are ignored, and events are
+	// refused once the queue is full.
+	for _, tc := range []struct {
+		delivery string
+		expected int
+	}{
+		{"1", http.StatusAccepted},
+		{"1", http.StatusOK},
+		{"2", http.StatusAccepted},
+		{"3", http.StatusServiceUnavailable},
+	} {
+		if status := post(tc.delivery); status != tc.expected {
+			t.Fatalf("Expected status %d for delivery %q, got %d", tc.expected, tc.delivery, status)
+		}
+	}
+	close(h.block)
+	q.Close()
+	if len(h.handled["moby/moby"]) != 2 {
+		t.Fatalf("Expected 2 handled events, got %v", h.handled)
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"strings"
+
+	"poule/configuration"
+	"poule/gh"
+	"poule/operations"
+)
+
+// isSenderAllowed returns whether the sender of the event is allowed to trigger the action on the
+// repository. Events without a sender never trigger restricted actions.
+func isSenderAllowed(client gh.Client, cache *gh.PermissionCache, action configuration.Action, event *operations.Event, repository string) (bool, error) {
+	return isUserAllowed(client, cache, action, event.SenderLogin(), repository)
+}
+
+// isUserAllowed returns whether the user is allowed to trigger the action on the repository.
+func isUserAllowed(client gh.Client, cache *gh.PermissionCache, action configuration.Action, login, repository string) (bool, error) {
+	if !action.IsRestricted() {
+		return true, nil
+	}
+	if login == "" {
+		return false, nil
+	}
+	for _, sender := range action.AllowedSenders {
+		if strings.EqualFold(sender, login) {
+			return true, nil
+		}
+	}
+	for _, team := range action.AllowedTeams {
+		s := strings.SplitN(team, "/", 2)
+		isMember, err := cache.IsTeamMember(client, s[0], s[1], login)
+		if err != nil {
+			return false, err
+		}
+		if isMember {
+			return true, nil
+		}
+	}
+	if action.MinPermission == "" {
+		return false, nil
+	}
+	s := strings.SplitN(repository, "/", 2)
+	level, role, err := cache.PermissionLevel(client, s[0], s[1], login)
+	if err != nil {
+		return false, err
+	}
+	return configuration.HasPermission(configuration.RolePermission(level, role), action.MinPermission), nil
+}
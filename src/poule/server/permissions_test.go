@@ -0,0 +1,91 @@
+package server
+
+import (
+	"testing"
+
+	"poule/configuration"
+	"poule/gh"
+	"poule/operations"
+	"poule/test"
+
+	"github.com/google/go-github/github"
+)
+
+func makeSenderEvent(login string) *operations.Event {
+	return &operations.Event{Sender: &github.User{Login: github.String(login)}}
+}
+
+func TestIsSenderAllowed(t *testing.T) {
+	clt := &test.Client{}
+	repository := test.Username + "/" + test.Repository
+
+	// Lookups are cached, and only performed once per user.
+	clt.MockOrganizations.On("ListTeams", "docker", &github.ListOptions{Page: 1, PerPage: 100}).Return([]*github.Team{
+		{ID: github.Int(42), Slug: github.String("maintainers")},
+	}, &github.Response{}, nil).Once()
+	clt.MockOrganizations.On("IsTeamMember", 42, "maintainer").Return(true, nil, nil).Once()
+	clt.MockOrganizations.On("IsTeamMember", 42, "collaborator").Return(false, nil, nil).Once()
+	clt.MockOrganizations.On("IsTeamMember", 42, "contributor").Return(false, nil, nil).Once()
+	// GitHub reports the "triage" role as the "read" permission.
+	clt.MockRepositories.On("GetPermissionLevel", test.Username, test.Repository, "collaborator").Return(&gh.RepositoryPermissionLevel{
+		RepositoryPermissionLevel: github.RepositoryPermissionLevel{Permission: github.String("read")},
+		RoleName:                  github.String("triage"),
+	}, nil, nil).Once()
+	clt.MockRepositories.On("GetPermissionLevel", test.Username, test.Repository, "contributor").Return(&gh.RepositoryPermissionLevel{
+		RepositoryPermissionLevel: github.RepositoryPermissionLevel{Permission: github.String("read")},
+		RoleName:                  github.String("custom-role"),
+	}, nil, nil).Once()
+
+	cache := gh.NewPermissionCache(0)
+	action := configuration.Action{
+		AllowedSenders: []string{"Icecrime"},
+		AllowedTeams:   []string{"docker/maintainers"},
+		MinPermission:  "triage",
+	}
+	for i := 0; i < 2; i++ {
+		for login, expected := range map[string]bool{
+			"icecrime":     true,
+			"maintainer":   true,
+			"collaborator": true,
+			"contributor":  false,
+		} {
+			allowed, err := isSenderAllowed(clt, cache, action, makeSenderEvent(login), repository)
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if allowed != expected {
+				t.Fatalf("Expected sender %q to be allowed=%t", login, expected)
+			}
+		}
+	}
+
+	// Unrestricted actions don't perform any lookup, and restricted actions are never triggered by
+	// events without a sender.
+	if allowed, err := isSenderAllowed(clt, cache, configuration.Action{}, &operations.Event{}, repository); err != nil || !allowed {
+		t.Fatalf("Expected unrestricted action to be allowed (error %v)", err)
+	}
+	if allowed, err := isSenderAllowed(clt, cache, action, &operations.Event{}, repository); err != nil || allowed {
+		t.Fatalf("Expected event without sender not to be allowed (error %v)", err)
+	}
+	test.AssertExpectations(clt, t)
+}
+
+func TestActionRestrictionsValidation(t *testing.T) {
+	for _, action := range []configuration.Action{
+		{AllowedTeams: []string{"maintainers"}},
+		{AllowedTeams: []string{"docker/"}},
+		{MinPermission: "none"},
+		{MinPermission: "owner"},
+	} {
+		if err := action.Validate(acceptingValidator{}); err == nil {
+			t.Fatalf("Expected validation error for action %#v", action)
+		}
+	}
+}
+
+// acceptingValidator accepts any operation configuration.
+type acceptingValidator struct{}
+
+func (acceptingValidator) Validate(*configuration.OperationConfiguration) error {
+	return nil
+}
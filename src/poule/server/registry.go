@@ -0,0 +1,137 @@
+package server
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"poule/configuration"
+
+	cron "gopkg.in/robfig/cron.v2"
+)
+
+type repositoryConfig struct {
+	Cron    *cron.Cron
+	Actions []configuration.Action
+}
+
+// snapshot is an immutable version of the server configuration along with the repository specific
+// configurations. Events are processed against a single snapshot, so that concurrent updates don't
+// affect an event being processed.
+type snapshot struct {
+	// Version is incremented for each update of the registry.
+	Version uint64
+
+	// Server is the server configuration.
+	Server *configuration.Server
+
+	// Repositories maps repositories full names to their specific configuration.
+	Repositories map[string]repositoryConfig
+}
+
+// Actions returns the actions which apply to the repository, starting with the common actions.
+func (s *snapshot) Actions(repository string) []configuration.Action {
+	actions := append([]configuration.Action{}, s.Server.CommonActions...)
+	if repoConfig, ok := s.Repositories[repository]; ok {
+		actions = append(actions, repoConfig.Actions...)
+	}
+	return actions
+}
+
+// registry holds the current configuration snapshot. Readers never block, while updates are
+// serialized and copy the snapshot they replace.
+type registry struct {
+	// lock serializes updates, including starting and stopping cron jobs, so that exactly one cron
+	// job runs for each repository of the current snapshot.
+	lock    sync.Mutex
+	current atomic.Value
+}
+
+func newRegistry(config *configuration.Server) *registry {
+	r := &registry{}
+	r.current.Store(&snapshot{
+		Version:      1,
+		Server:       config,
+		Repositories: map[string]repositoryConfig{},
+	})
+	return r
+}
+
+// Snapshot returns the current configuration snapshot, which must not be modified.
+func (r *registry) Snapshot() *snapshot {
+	return r.current.Load().(*snapshot)
+}
+
+// update stores a new snapshot derived from a copy of the current one, and returns it. The caller
+// must hold the lock.
+func (r *registry) update(fn func(next *snapshot)) *snapshot {
+	current := r.Snapshot()
+	next := &snapshot{
+		Version:      current.Version + 1,
+		Server:       current.Server,
+		Repositories: make(map[string]repositoryConfig, len(current.Repositories)),
+	}
+	for repository, repoConfig := range current.Repositories {
+		next.Repositories[repository] = repoConfig
+	}
+	fn(next)
+	r.current.Store(next)
+	return next
+}
+
+// Replace installs a new server configuration along with the specific configuration of its
+// repositories, starting their cron jobs in place of the previous ones. Configurations of
+// repositories which aren't part of the server configuration are ignored. It returns the new
+// snapshot along with the repositories which are no longer configured.
+func (r *registry) Replace(config *configuration.Server, repositories map[string]repositoryConfig) (*snapshot, []string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	removed := []string{}
+	next := r.update(func(next *snapshot) {
+		next.Server = config
+		for repository, repoConfig := range next.Repositories {
+			stopCron(repoConfig.Cron)
+			delete(next.Repositories, repository)
+			if _, ok := config.Repositories[repository]; !ok {
+				removed = append(removed, repository)
+			}
+		}
+		for repository, repoConfig := range repositories {
+			if _, ok := config.Repositories[repository]; !ok {
+				continue
+			}
+			if repoConfig.Cron != nil {
+				repoConfig.Cron.Start()
+			}
+			next.Repositories[repository] = repoConfig
+		}
+	})
+	return next, removed
+}
+
+// SetRepository replaces the specific configuration of the repository, and starts its cron job in
+// place of the previous one. The configuration is ignored if the repository isn't part of the
+// server configuration (e.g., when it was removed while its configuration was being fetched), in
+// which case nil is returned.
+func (r *registry) SetRepository(repository string, repoConfig repositoryConfig) *snapshot {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.Snapshot().Server.Repositories[repository]; !ok {
+		return nil
+	}
+	return r.update(func(next *snapshot) {
+		if previous, ok := next.Repositories[repository]; ok {
+			stopCron(previous.Cron)
+		}
+		if repoConfig.Cron != nil {
+			repoConfig.Cron.Start()
+		}
+		next.Repositories[repository] = repoConfig
+	})
+}
+
+// stopCron stops a cron job started by the registry.
+func stopCron(c *cron.Cron) {
+	if c != nil {
+		c.Stop()
+	}
+}
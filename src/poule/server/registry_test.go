@@ -0,0 +1,183 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"poule/configuration"
+	"poule/server/listeners"
+
+	cron "gopkg.in/robfig/cron.v2"
+)
+
+func makeServerConfig(repositories ...string) *configuration.Server {
+	config := &configuration.Server{
+		Repositories:  map[string]string{},
+		CommonActions: make([]configuration.Action, 1, 2),
+	}
+	for _, repository := range repositories {
+		config.Repositories[repository] = ""
+	}
+	return config
+}
+
+func TestRegistryUpdates(t *testing.T) {
+	r := newRegistry(makeServerConfig("icecrime/poule", "moby/moby"))
+	initial := r.Snapshot()
+
+	r.SetRepository("icecrime/poule", repositoryConfig{Actions: make([]configuration.Action, 2), Cron: cron.New()})
+	r.SetRepository("moby/moby", repositoryConfig{Actions: make([]configuration.Action, 3), Cron: cron.New()})
+	next, removed := r.Replace(makeServerConfig("moby/moby"), map[string]repositoryConfig{
+		"icecrime/poule": {Actions: make([]configuration.Action, 2), Cron: cron.New()},
+		"moby/moby":      {Actions: make([]configuration.Action, 3), Cron: cron.New()},
+	})
+
+	// Unconfigured repositories are ignored.
+	if r.SetRepository("icecrime/poule", repositoryConfig{}) != nil {
+		t.Fatalf("Unexpected configuration of an unconfigured repository")
+	}
+
+	// Previous snapshots are left untouched.
+	if len(initial.Repositories) != 0 || initial.Version != 1 {
+		t.Fatalf("Unexpected modification of the initial snapshot %#v", initial)
+	}
+	if next.Version != 4 || r.Snapshot() != next {
+		t.Fatalf("Unexpected current snapshot %#v", r.Snapshot())
+	}
+	if len(removed) != 1 || removed[0] != "icecrime/poule" {
+		t.Fatalf("Unexpected removed repositories %v", removed)
+	}
+	if len(next.Repositories) != 1 {
+		t.Fatalf("Unexpected repositories %v", next.Repositories)
+	}
+
+	// Actions are the common actions followed by the repository specific ones, and never share
+	// storage with the snapshot.
+	if actions := next.Actions("moby/moby"); len(actions) != 4 {
+		t.Fatalf("Expected 4 actions, got %d", len(actions))
+	}
+	if actions := next.Actions("icecrime/poule"); len(actions) != 1 {
+		t.Fatalf("Expected 1 action, got %d", len(actions))
+	}
+	actions := next.Actions("unknown/unknown")
+	actions = append(actions, configuration.Action{Schedule: "@daily"})
+	if extended := next.Server.CommonActions[:2]; extended[1].Schedule != "" {
+		t.Fatalf("Actions shares storage with the common actions")
+	}
+}
+
+// TestRegistryConcurrency must be run with the race detector.
+func TestRegistryConcurrency(t *testing.T) {
+	repositories := []string{"icecrime/poule", "moby/moby", "docker/docker"}
+	r := newRegistry(makeServerConfig(repositories...))
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	errs := make(chan error, 16)
+
+	// Readers verify that each snapshot is consistent: every repository specific configuration
+	// belongs to a configured repository.
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				s := r.Snapshot()
+				for repository := range s.Repositories {
+					if _, ok := s.Server.Repositories[repository]; !ok {
+						errs <- fmt.Errorf("snapshot %d has configuration for unconfigured repository %q", s.Version, repository)
+						return
+					}
+					s.Actions(repository)
+				}
+			}
+		}()
+	}
+
+	// Writers concurrently update repository configurations and the server configuration.
+	var writers sync.WaitGroup
+	for _, repository := range repositories {
+		writers.Add(1)
+		go func(repository string) {
+			defer writers.Done()
+			for i := 0; i < 50; i++ {
+				r.SetRepository(repository, repositoryConfig{Cron: cron.New()})
+			}
+		}(repository)
+	}
+	writers.Add(1)
+	go func() {
+		defer writers.Done()
+		for i := 0; i < 50; i++ {
+			r.Replace(makeServerConfig(repositories[:1+i%len(repositories)]...), map[string]repositoryConfig{
+				repositories[0]: {Cron: cron.New()},
+			})
+		}
+	}()
+	writers.Wait()
+	close(stop)
+	wg.Wait()
+
+	select {
+	case err := <-errs:
+		t.Fatal(err)
+	default:
+	}
+
+	// Exactly one cron job is left running for each configured repository: stop them.
+	s := r.Snapshot()
+	for _, repoConfig := range s.Repositories {
+		repoConfig.Cron.Stop()
+	}
+}
+
+// failingListener is a listener which fails to apply configurations with a secret.
+type failingListener struct {
+	reloads []*configuration.Server
+}
+
+func (l *failingListener) Start(handler listeners.Handler) error {
+	return nil
+}
+
+func (l *failingListener) Reload(config *configuration.Server) error {
+	l.reloads = append(l.reloads, config)
+	if config.HTTPSecret != "" {
+		return fmt.Errorf("failed to apply configuration")
+	}
+	return nil
+}
+
+func TestServerReloadFailure(t *testing.T) {
+	current := &configuration.Server{}
+	listener := &failingListener{}
+	s := &Server{
+		registry: newRegistry(current),
+		listener: listener,
+	}
+
+	// The current configuration is kept, and the listener restored, when the listener fails.
+	if err := s.Reload(&configuration.Server{HTTPSecret: "secret"}); err == nil {
+		t.Fatalf("Expected Reload to fail")
+	}
+	if s.Config() != current || s.registry.Snapshot().Version != 1 {
+		t.Fatalf("Expected the current configuration to be kept, got %#v", s.Config())
+	}
+	if len(listener.reloads) != 2 || listener.reloads[1] != current {
+		t.Fatalf("Expected the listener to be restored, got reloads %v", listener.reloads)
+	}
+
+	next := &configuration.Server{}
+	if err := s.Reload(next); err != nil {
+		t.Fatalf("Reload returned unexpected error %v", err)
+	}
+	if s.Config() != next {
+		t.Fatalf("Expected the new configuration to be installed")
+	}
+}
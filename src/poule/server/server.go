@@ -1,30 +1,44 @@
 package server
 
 import (
+	"strings"
+	"sync"
+
 	"poule/configuration"
+	"poule/gh"
 	"poule/operations/catalog"
+	"poule/runner/state"
 
 	"poule/server/listeners"
 
-	cron "gopkg.in/robfig/cron.v2"
+	"github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
 )
 
-type repositoryConfig struct {
-	Cron    *cron.Cron
-	Actions []configuration.Action
-}
-
 // Server provides operation trigger on GitHub events through a long-running job.
 type Server struct {
-	config             *configuration.Server
-	repositoriesConfig map[string]repositoryConfig
+	registry *registry
+	state    state.Store
+
+	// permissions caches the lookups of senders permissions for restricted actions and commands, and
+	// of users memberships for operations filters.
+	permissions *gh.PermissionCache
+
+	// listenerLock protects the listener, which is created when the server runs.
+	listenerLock sync.Mutex
+	listener     listeners.Listener
 }
 
 // NewServer returns a new server instance.
 func NewServer(config *configuration.Server) (*Server, error) {
+	store, err := state.Open(config.StateFile)
+	if err != nil {
+		return nil, err
+	}
 	server := &Server{
-		config:             config,
-		repositoriesConfig: make(map[string]repositoryConfig),
+		registry:    newRegistry(config),
+		state:       store,
+		permissions: gh.NewPermissionCache(0),
 	}
 
 	// We initialize the special poule-updater operation which need to be given a callback into the
@@ -33,16 +47,69 @@ func NewServer(config *configuration.Server) (*Server, error) {
 	return server, nil
 }
 
+// Config returns the current server configuration, which must not be modified.
+func (s *Server) Config() *configuration.Server {
+	return s.registry.Snapshot().Server
+}
+
+// Reload replaces the server configuration. The configuration of all repositories (including
+// their cron schedules) is fetched again from GitHub, and the listener is notified of the change so
+// that it can adjust to the new set of repositories. The new configuration is only installed once
+// all of these succeeded: the current one is kept otherwise.
+func (s *Server) Reload(config *configuration.Server) error {
+	if errs := config.Validate(catalog.OperationValidator{}); len(errs) != 0 {
+		var strErrors []string
+		for _, err := range errs {
+			strErrors = append(strErrors, err.Error())
+		}
+		return errors.Errorf("invalid server configuration:\n%s", strings.Join(strErrors, "\n"))
+	}
+
+	// GitHub App credentials are resolved again, in case the App was reinstalled or its private key
+	// rotated.
+	gh.ResetAppCredentials()
+	repositories, err := s.fetchRepositoriesConfigs(config)
+	if err != nil {
+		return err
+	}
+
+	s.listenerLock.Lock()
+	listener := s.listener
+	s.listenerLock.Unlock()
+
+	if r, ok := listener.(listeners.Reloader); ok {
+		if err := r.Reload(config); err != nil {
+			// Restore the listener to the current configuration, which is kept.
+			if rollbackErr := r.Reload(s.Config()); rollbackErr != nil {
+				logrus.Errorf("failed to restore listener configuration: %v", rollbackErr)
+			}
+			return err
+		}
+	}
+
+	// Swap the configuration, which replaces the cron jobs of all repositories.
+	snapshot, removed := s.registry.Replace(config, repositories)
+	for _, repository := range removed {
+		logrus.Infof("removed configuration for repository %q", repository)
+	}
+	logrus.Infof("reloaded server configuration (version %d)", snapshot.Version)
+	return nil
+}
+
 // Run starts the event loop, and only returns when completed.
 func (s *Server) Run() error {
 	// We either run in "NSQ-mode" or in direct "GitHub WebHook" mode depending on the presence of
 	// the `nsq_channel` configuration key.
+	config := s.Config()
 	var l listeners.Listener
-	if s.config.HTTPListen != "" {
-		l = listeners.NewGitHubListener(s.config)
+	if config.HTTPListen != "" {
+		l = listeners.NewGitHubListener(config)
 	} else {
-		l = listeners.NewNSQListener(s.config)
+		l = listeners.NewNSQListener(config)
 	}
+	s.listenerLock.Lock()
+	s.listener = l
+	s.listenerLock.Unlock()
 
 	// Start the listener
 	return l.Start(s)
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"poule/configuration"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// configurationPollInterval is the interval at which the server configuration file is checked for
+// modifications.
+const configurationPollInterval = 5 * time.Second
+
+// WatchConfiguration reloads the server configuration when the configuration file is modified, or
+// when the process receives SIGHUP. The load function reads and validates the configuration file:
+// the current configuration is kept when it fails.
+func (s *Server) WatchConfiguration(path string, load func() (*configuration.Server, error)) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGHUP)
+	go watchFile(path, configurationPollInterval, sigChan, nil, func() {
+		config, err := load()
+		if err == nil {
+			err = s.Reload(config)
+		}
+		if err != nil {
+			logrus.Errorf("keeping current server configuration: %v", err)
+		}
+	})
+}
+
+// watchFile calls the reload function whenever the modification time or the size of the file
+// changes, and whenever a value is received on the trigger channel, until the stop channel is
+// closed.
+func watchFile(path string, interval time.Duration, trigger <-chan os.Signal, stop <-chan struct{}, reload func()) {
+	var modTime time.Time
+	var size int64
+	if fi, err := os.Stat(path); err == nil {
+		modTime, size = fi.ModTime(), fi.Size()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case sig := <-trigger:
+			logrus.WithField("signal", sig).Info("reloading server configuration")
+			reload()
+		case <-ticker.C:
+			fi, err := os.Stat(path)
+			if err != nil {
+				logrus.Warnf("failed to check server configuration file: %v", err)
+				continue
+			}
+			if fi.ModTime().Equal(modTime) && fi.Size() == size {
+				continue
+			}
+			modTime, size = fi.ModTime(), fi.Size()
+			logrus.Infof("server configuration file %q changed: reloading", path)
+			reload()
+		}
+	}
+}
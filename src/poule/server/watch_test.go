@@ -0,0 +1,55 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWatchFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "poule-server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "poule-server.yml")
+	if err := ioutil.WriteFile(path, []byte("http_listen: :8080\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	reloads := make(chan struct{}, 16)
+	trigger := make(chan os.Signal, 1)
+	stop := make(chan struct{})
+	defer close(stop)
+	go watchFile(path, 10*time.Millisecond, trigger, stop, func() {
+		reloads <- struct{}{}
+	})
+
+	expectReload := func(reason string) {
+		select {
+		case <-reloads:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected a reload after %s", reason)
+		}
+	}
+
+	// An unmodified file doesn't trigger a reload.
+	select {
+	case <-reloads:
+		t.Fatalf("Unexpected reload of an unmodified file")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Modifying the file triggers a reload.
+	if err := ioutil.WriteFile(path, []byte("http_listen: :8081\nhttp_secret: secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	expectReload("modifying the file")
+
+	// A signal triggers a reload.
+	trigger <- syscall.SIGHUP
+	expectReload("receiving SIGHUP")
+}
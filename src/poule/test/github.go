@@ -10,10 +10,12 @@ import (
 
 // Client is a mocked implementation of a GitHub client.
 type Client struct {
-	MockIssues       mocks.IssuesService
-	MockPullRequests mocks.PullRequestsService
-	MockRepositories mocks.RepositoriesService
-	MockSearch       mocks.SearchService
+	MockIssues        mocks.IssuesService
+	MockOrganizations mocks.OrganizationsService
+	MockPullRequests  mocks.PullRequestsService
+	MockReactions     mocks.ReactionsService
+	MockRepositories  mocks.RepositoriesService
+	MockSearch        mocks.SearchService
 }
 
 // Issues returns the issue service instance.
@@ -21,11 +23,21 @@ func (t *Client) Issues() gh.IssuesService {
 	return &t.MockIssues
 }
 
+// Organizations returns the organization service instance.
+func (t *Client) Organizations() gh.OrganizationsService {
+	return &t.MockOrganizations
+}
+
 // PullRequests returns the pull request service instance.
 func (t *Client) PullRequests() gh.PullRequestsService {
 	return &t.MockPullRequests
 }
 
+// Reactions returns the reactions service instance.
+func (t *Client) Reactions() gh.ReactionsService {
+	return &t.MockReactions
+}
+
 // Repositories returns the repository service instance.
 func (t *Client) Repositories() gh.RepositoriesService {
 	return &t.MockRepositories
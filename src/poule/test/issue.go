@@ -1,6 +1,8 @@
 package test
 
 import (
+	"time"
+
 	"poule/gh"
 
 	"github.com/google/go-github/github"
@@ -51,6 +53,12 @@ func (p *IssueBuilder) Title(title string) *IssueBuilder {
 	return p
 }
 
+// UpdatedAt sets the last update time of the issue.
+func (p *IssueBuilder) UpdatedAt(updatedAt time.Time) *IssueBuilder {
+	p.Value.UpdatedAt = &updatedAt
+	return p
+}
+
 // UserLogin sets the user of the pull request.
 func (p *IssueBuilder) UserLogin(title string) *IssueBuilder {
 	p.Value.User = &github.User{
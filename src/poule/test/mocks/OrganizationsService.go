@@ -0,0 +1,104 @@
+package mocks
+
+import gh "poule/gh"
+import github "github.com/google/go-github/github"
+import mock "github.com/stretchr/testify/mock"
+
+// OrganizationsService is an autogenerated mock type for the OrganizationsService type
+type OrganizationsService struct {
+	mock.Mock
+}
+
+// IsMember provides a mock function with given fields: org, user
+func (_m *OrganizationsService) IsMember(org string, user string) (bool, *github.Response, error) {
+	ret := _m.Called(org, user)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string, string) bool); ok {
+		r0 = rf(org, user)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 *github.Response
+	if rf, ok := ret.Get(1).(func(string, string) *github.Response); ok {
+		r1 = rf(org, user)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*github.Response)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, string) error); ok {
+		r2 = rf(org, user)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// IsTeamMember provides a mock function with given fields: team, user
+func (_m *OrganizationsService) IsTeamMember(team int, user string) (bool, *github.Response, error) {
+	ret := _m.Called(team, user)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(int, string) bool); ok {
+		r0 = rf(team, user)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 *github.Response
+	if rf, ok := ret.Get(1).(func(int, string) *github.Response); ok {
+		r1 = rf(team, user)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*github.Response)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(int, string) error); ok {
+		r2 = rf(team, user)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ListTeams provides a mock function with given fields: org, opt
+func (_m *OrganizationsService) ListTeams(org string, opt *github.ListOptions) ([]*github.Team, *github.Response, error) {
+	ret := _m.Called(org, opt)
+
+	var r0 []*github.Team
+	if rf, ok := ret.Get(0).(func(string, *github.ListOptions) []*github.Team); ok {
+		r0 = rf(org, opt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*github.Team)
+		}
+	}
+
+	var r1 *github.Response
+	if rf, ok := ret.Get(1).(func(string, *github.ListOptions) *github.Response); ok {
+		r1 = rf(org, opt)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*github.Response)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, *github.ListOptions) error); ok {
+		r2 = rf(org, opt)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+var _ gh.OrganizationsService = (*OrganizationsService)(nil)
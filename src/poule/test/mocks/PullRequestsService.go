@@ -41,6 +41,36 @@ func (_m *PullRequestsService) Get(owner string, repo string, number int) (*gith
 	return r0, r1, r2
 }
 
+// IsDraft provides a mock function with given fields: owner, repo, number
+func (_m *PullRequestsService) IsDraft(owner string, repo string, number int) (bool, *github.Response, error) {
+	ret := _m.Called(owner, repo, number)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string, string, int) bool); ok {
+		r0 = rf(owner, repo, number)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 *github.Response
+	if rf, ok := ret.Get(1).(func(string, string, int) *github.Response); ok {
+		r1 = rf(owner, repo, number)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*github.Response)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, string, int) error); ok {
+		r2 = rf(owner, repo, number)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // List provides a mock function with given fields: owner, repo, opt
 func (_m *PullRequestsService) List(owner string, repo string, opt *github.PullRequestListOptions) ([]*github.PullRequest, *github.Response, error) {
 	ret := _m.Called(owner, repo, opt)
@@ -137,4 +167,100 @@ func (_m *PullRequestsService) ListFiles(owner string, repo string, number int,
 	return r0, r1, r2
 }
 
+// ListReviewers provides a mock function with given fields: owner, repo, number
+func (_m *PullRequestsService) ListReviewers(owner string, repo string, number int) (*gh.Reviewers, *github.Response, error) {
+	ret := _m.Called(owner, repo, number)
+
+	var r0 *gh.Reviewers
+	if rf, ok := ret.Get(0).(func(string, string, int) *gh.Reviewers); ok {
+		r0 = rf(owner, repo, number)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*gh.Reviewers)
+		}
+	}
+
+	var r1 *github.Response
+	if rf, ok := ret.Get(1).(func(string, string, int) *github.Response); ok {
+		r1 = rf(owner, repo, number)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*github.Response)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, string, int) error); ok {
+		r2 = rf(owner, repo, number)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ListReviews provides a mock function with given fields: owner, repo, number
+func (_m *PullRequestsService) ListReviews(owner string, repo string, number int) ([]*github.PullRequestReview, *github.Response, error) {
+	ret := _m.Called(owner, repo, number)
+
+	var r0 []*github.PullRequestReview
+	if rf, ok := ret.Get(0).(func(string, string, int) []*github.PullRequestReview); ok {
+		r0 = rf(owner, repo, number)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*github.PullRequestReview)
+		}
+	}
+
+	var r1 *github.Response
+	if rf, ok := ret.Get(1).(func(string, string, int) *github.Response); ok {
+		r1 = rf(owner, repo, number)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*github.Response)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, string, int) error); ok {
+		r2 = rf(owner, repo, number)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// RequestReviewers provides a mock function with given fields: owner, repo, number, reviewers
+func (_m *PullRequestsService) RequestReviewers(owner string, repo string, number int, reviewers gh.ReviewersRequest) (*github.PullRequest, *github.Response, error) {
+	ret := _m.Called(owner, repo, number, reviewers)
+
+	var r0 *github.PullRequest
+	if rf, ok := ret.Get(0).(func(string, string, int, gh.ReviewersRequest) *github.PullRequest); ok {
+		r0 = rf(owner, repo, number, reviewers)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*github.PullRequest)
+		}
+	}
+
+	var r1 *github.Response
+	if rf, ok := ret.Get(1).(func(string, string, int, gh.ReviewersRequest) *github.Response); ok {
+		r1 = rf(owner, repo, number, reviewers)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*github.Response)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, string, int, gh.ReviewersRequest) error); ok {
+		r2 = rf(owner, repo, number, reviewers)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 var _ gh.PullRequestsService = (*PullRequestsService)(nil)
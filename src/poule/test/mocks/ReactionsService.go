@@ -0,0 +1,44 @@
+package mocks
+
+import gh "poule/gh"
+import github "github.com/google/go-github/github"
+import mock "github.com/stretchr/testify/mock"
+
+// ReactionsService is an autogenerated mock type for the ReactionsService type
+type ReactionsService struct {
+	mock.Mock
+}
+
+// CreateIssueCommentReaction provides a mock function with given fields: owner, repo, id, content
+func (_m *ReactionsService) CreateIssueCommentReaction(owner string, repo string, id int, content string) (*github.Reaction, *github.Response, error) {
+	ret := _m.Called(owner, repo, id, content)
+
+	var r0 *github.Reaction
+	if rf, ok := ret.Get(0).(func(string, string, int, string) *github.Reaction); ok {
+		r0 = rf(owner, repo, id, content)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*github.Reaction)
+		}
+	}
+
+	var r1 *github.Response
+	if rf, ok := ret.Get(1).(func(string, string, int, string) *github.Response); ok {
+		r1 = rf(owner, repo, id, content)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*github.Response)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, string, int, string) error); ok {
+		r2 = rf(owner, repo, id, content)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+var _ gh.ReactionsService = (*ReactionsService)(nil)
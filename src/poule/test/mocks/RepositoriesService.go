@@ -41,6 +41,207 @@ func (_m *RepositoriesService) CreateStatus(owner string, repo string, ref strin
 	return r0, r1, r2
 }
 
+// Get provides a mock function with given fields: owner, repo
+func (_m *RepositoriesService) Get(owner string, repo string) (*github.Repository, *github.Response, error) {
+	ret := _m.Called(owner, repo)
+
+	var r0 *github.Repository
+	if rf, ok := ret.Get(0).(func(string, string) *github.Repository); ok {
+		r0 = rf(owner, repo)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*github.Repository)
+		}
+	}
+
+	var r1 *github.Response
+	if rf, ok := ret.Get(1).(func(string, string) *github.Response); ok {
+		r1 = rf(owner, repo)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*github.Response)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, string) error); ok {
+		r2 = rf(owner, repo)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetPermissionLevel provides a mock function with given fields: owner, repo, user
+func (_m *RepositoriesService) GetPermissionLevel(owner string, repo string, user string) (*gh.RepositoryPermissionLevel, *github.Response, error) {
+	ret := _m.Called(owner, repo, user)
+
+	var r0 *gh.RepositoryPermissionLevel
+	if rf, ok := ret.Get(0).(func(string, string, string) *gh.RepositoryPermissionLevel); ok {
+		r0 = rf(owner, repo, user)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*gh.RepositoryPermissionLevel)
+		}
+	}
+
+	var r1 *github.Response
+	if rf, ok := ret.Get(1).(func(string, string, string) *github.Response); ok {
+		r1 = rf(owner, repo, user)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*github.Response)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, string, string) error); ok {
+		r2 = rf(owner, repo, user)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetContents provides a mock function with given fields: owner, repo, path, opt
+func (_m *RepositoriesService) GetContents(owner string, repo string, path string, opt *github.RepositoryContentGetOptions) (*github.RepositoryContent, []*github.RepositoryContent, *github.Response, error) {
+	ret := _m.Called(owner, repo, path, opt)
+
+	var r0 *github.RepositoryContent
+	if rf, ok := ret.Get(0).(func(string, string, string, *github.RepositoryContentGetOptions) *github.RepositoryContent); ok {
+		r0 = rf(owner, repo, path, opt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*github.RepositoryContent)
+		}
+	}
+
+	var r1 []*github.RepositoryContent
+	if rf, ok := ret.Get(1).(func(string, string, string, *github.RepositoryContentGetOptions) []*github.RepositoryContent); ok {
+		r1 = rf(owner, repo, path, opt)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]*github.RepositoryContent)
+		}
+	}
+
+	var r2 *github.Response
+	if rf, ok := ret.Get(2).(func(string, string, string, *github.RepositoryContentGetOptions) *github.Response); ok {
+		r2 = rf(owner, repo, path, opt)
+	} else {
+		if ret.Get(2) != nil {
+			r2 = ret.Get(2).(*github.Response)
+		}
+	}
+
+	var r3 error
+	if rf, ok := ret.Get(3).(func(string, string, string, *github.RepositoryContentGetOptions) error); ok {
+		r3 = rf(owner, repo, path, opt)
+	} else {
+		r3 = ret.Error(3)
+	}
+
+	return r0, r1, r2, r3
+}
+
+// List provides a mock function with given fields: user, opt
+func (_m *RepositoriesService) List(user string, opt *github.ListOptions) ([]*gh.Repository, *github.Response, error) {
+	ret := _m.Called(user, opt)
+
+	var r0 []*gh.Repository
+	if rf, ok := ret.Get(0).(func(string, *github.ListOptions) []*gh.Repository); ok {
+		r0 = rf(user, opt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*gh.Repository)
+		}
+	}
+
+	var r1 *github.Response
+	if rf, ok := ret.Get(1).(func(string, *github.ListOptions) *github.Response); ok {
+		r1 = rf(user, opt)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*github.Response)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, *github.ListOptions) error); ok {
+		r2 = rf(user, opt)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ListByOrg provides a mock function with given fields: org, opt
+func (_m *RepositoriesService) ListByOrg(org string, opt *github.ListOptions) ([]*gh.Repository, *github.Response, error) {
+	ret := _m.Called(org, opt)
+
+	var r0 []*gh.Repository
+	if rf, ok := ret.Get(0).(func(string, *github.ListOptions) []*gh.Repository); ok {
+		r0 = rf(org, opt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*gh.Repository)
+		}
+	}
+
+	var r1 *github.Response
+	if rf, ok := ret.Get(1).(func(string, *github.ListOptions) *github.Response); ok {
+		r1 = rf(org, opt)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*github.Response)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, *github.ListOptions) error); ok {
+		r2 = rf(org, opt)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ListPullRequestsWithCommit provides a mock function with given fields: owner, repo, sha, opt
+func (_m *RepositoriesService) ListPullRequestsWithCommit(owner string, repo string, sha string, opt *github.ListOptions) ([]*github.PullRequest, *github.Response, error) {
+	ret := _m.Called(owner, repo, sha, opt)
+
+	var r0 []*github.PullRequest
+	if rf, ok := ret.Get(0).(func(string, string, string, *github.ListOptions) []*github.PullRequest); ok {
+		r0 = rf(owner, repo, sha, opt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*github.PullRequest)
+		}
+	}
+
+	var r1 *github.Response
+	if rf, ok := ret.Get(1).(func(string, string, string, *github.ListOptions) *github.Response); ok {
+		r1 = rf(owner, repo, sha, opt)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*github.Response)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, string, string, *github.ListOptions) error); ok {
+		r2 = rf(owner, repo, sha, opt)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // ListStatuses provides a mock function with given fields: owner, repo, ref, opt
 func (_m *RepositoriesService) ListStatuses(owner string, repo string, ref string, opt *github.ListOptions) ([]*github.RepoStatus, *github.Response, error) {
 	ret := _m.Called(owner, repo, ref, opt)
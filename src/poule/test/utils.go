@@ -5,6 +5,8 @@ import "testing"
 // AssertExpectations asserts mock expectations for all different GitHub services.
 func AssertExpectations(clt *Client, t *testing.T) {
 	clt.MockIssues.AssertExpectations(t)
+	clt.MockOrganizations.AssertExpectations(t)
 	clt.MockPullRequests.AssertExpectations(t)
+	clt.MockReactions.AssertExpectations(t)
 	clt.MockRepositories.AssertExpectations(t)
 }